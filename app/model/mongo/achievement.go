@@ -30,10 +30,26 @@ type Achievement struct {
 	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
 }
 
-// Attachment represents a single file stored externally (e.g. Cloud Storage / server folder).
+// Attachment represents a single file kept in the FileStore (local disk or S3-compatible bucket).
 type Attachment struct {
-	FileName string `bson:"fileName" json:"fileName"`
-	URL      string `bson:"url" json:"url"`
-	MimeType string `bson:"mimeType" json:"mimeType"`
-	Size     int64  `bson:"size" json:"size"` // bytes
+	ID         string `bson:"id,omitempty" json:"id,omitempty"` // stable id used in download URLs
+	FileName   string `bson:"fileName" json:"fileName"`
+	StorageKey string `bson:"storageKey,omitempty" json:"storageKey,omitempty"` // FileStore key
+	URL        string `bson:"url,omitempty" json:"url,omitempty"`               // legacy "/uploads/..." path of older attachments
+	MimeType   string `bson:"mimeType" json:"mimeType"`
+	Size       int64  `bson:"size" json:"size"`                         // bytes
+	SHA256     string `bson:"sha256,omitempty" json:"sha256,omitempty"` // hex checksum of the stored file
+
+	// Malware scan; attachments older than scanning have no status
+	ScanStatus    string     `bson:"scanStatus,omitempty" json:"scanStatus,omitempty"`       // pending, clean, infected, missing
+	ScanSignature string     `bson:"scanSignature,omitempty" json:"scanSignature,omitempty"` // malware name when infected
+	ScannedAt     *time.Time `bson:"scannedAt,omitempty" json:"scannedAt,omitempty"`
 }
+
+// Attachment scan statuses
+const (
+	ScanPending  = "pending"
+	ScanClean    = "clean"
+	ScanInfected = "infected"
+	ScanMissing  = "missing" // the stored file disappeared before it could be scanned
+)
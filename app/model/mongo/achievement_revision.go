@@ -0,0 +1,28 @@
+package mongo
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AchievementRevision is an immutable snapshot of an Achievement taken each time it is submitted.
+// Stored in the "achievement_revisions" collection; documents are never updated.
+type AchievementRevision struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReferenceID   string             `bson:"referenceId" json:"referenceId"` // achievement_references.id
+	AchievementID primitive.ObjectID `bson:"achievementId" json:"achievementId"`
+	Number        int                `bson:"number" json:"number"` // 1 = first submission
+	Snapshot      Achievement        `bson:"snapshot" json:"snapshot"`
+	SubmittedBy   string             `bson:"submittedBy" json:"submittedBy"` // users.id
+	SubmittedAt   time.Time          `bson:"submittedAt" json:"submittedAt"`
+
+	// Set on resubmissions: the rejection of the previous revision this one answers.
+	AnswersRejection *RevisionRejection `bson:"answersRejection,omitempty" json:"answersRejection,omitempty"`
+}
+
+// RevisionRejection links a revision to the rejection it responds to.
+type RevisionRejection struct {
+	RevisionNumber int    `bson:"revisionNumber" json:"revisionNumber"`
+	Note           string `bson:"note" json:"note"`
+}
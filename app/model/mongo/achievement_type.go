@@ -0,0 +1,29 @@
+package mongo
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AchievementTypeSchema describes which Achievement.Details fields a given type expects.
+// Stored in the "achievement_types" collection; built-in defaults are used when no document exists.
+type AchievementTypeSchema struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Type        string             `bson:"type" json:"type"` // competition, publication, organization, ...
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description" json:"description"`
+	Fields      []DetailField      `bson:"fields" json:"fields"`
+	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// DetailField is a single entry of Achievement.Details.
+type DetailField struct {
+	Name             string   `bson:"name" json:"name"`
+	Label            string   `bson:"label" json:"label"`
+	Kind             string   `bson:"kind" json:"kind"`                           // string, number, boolean, date, string_array, enum
+	Options          []string `bson:"options,omitempty" json:"options,omitempty"` // allowed values when kind=enum
+	Pattern          string   `bson:"pattern,omitempty" json:"pattern,omitempty"` // optional regexp for string fields
+	RequiredOnDraft  bool     `bson:"requiredOnDraft" json:"requiredOnDraft"`
+	RequiredOnSubmit bool     `bson:"requiredOnSubmit" json:"requiredOnSubmit"`
+}
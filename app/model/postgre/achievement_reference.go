@@ -6,7 +6,7 @@ type AchievementReference struct {
 	ID                 string     `db:"id" json:"id"`                                     // uuid
 	StudentID          string     `db:"student_id" json:"student_id"`                     // FK -> students.id
 	MongoAchievementID string     `db:"mongo_achievement_id" json:"mongo_achievement_id"` // ObjectId.Hex()
-	Status             string     `db:"status" json:"status"`                             // draft, submitted, verified, rejected
+	Status             string     `db:"status" json:"status"`                             // draft, submitted, verified, rejected, revision
 	SubmittedAt        *time.Time `db:"submitted_at" json:"submitted_at"`
 	VerifiedAt         *time.Time `db:"verified_at" json:"verified_at"`
 	VerifiedBy         *string    `db:"verified_by" json:"verified_by"` // FK -> users.id (verifier)
@@ -0,0 +1,12 @@
+package postgres
+
+import "time"
+
+// LoginThrottle counts consecutive failed logins of one username. The row is deleted by a
+// successful login or an admin unlock.
+type LoginThrottle struct {
+	Username     string     `db:"username" json:"username"` // lower-cased
+	FailedCount  int        `db:"failed_count" json:"failed_count"`
+	LastFailedAt *time.Time `db:"last_failed_at" json:"last_failed_at"`
+	LockedUntil  *time.Time `db:"locked_until" json:"locked_until"`
+}
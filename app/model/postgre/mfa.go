@@ -0,0 +1,21 @@
+package postgres
+
+import "time"
+
+// UserMFA is the TOTP enrollment of a user. It is pending until the first code is confirmed.
+type UserMFA struct {
+	UserID       string     `db:"user_id" json:"user_id"` // FK -> users.id
+	Secret       string     `db:"secret" json:"-"`        // base32 TOTP secret
+	EnabledAt    *time.Time `db:"enabled_at" json:"enabled_at"`
+	LastUsedStep int64      `db:"last_used_step" json:"-"` // replay protection
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+}
+
+// MFARecoveryCode is a one-time code that replaces a TOTP code. Only its SHA-256 hash is stored.
+type MFARecoveryCode struct {
+	ID        string     `db:"id" json:"id"`
+	UserID    string     `db:"user_id" json:"user_id"`
+	CodeHash  string     `db:"code_hash" json:"-"`
+	UsedAt    *time.Time `db:"used_at" json:"used_at"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
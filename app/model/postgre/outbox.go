@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Outbox entry statuses
+const (
+	OutboxPending = "pending"
+	OutboxDone    = "done"
+	OutboxFailed  = "failed"
+)
+
+// OutboxEntry is a MongoDB operation committed in the same Postgres transaction as the
+// reference row change it belongs to. Entries of one aggregate are applied in Seq order.
+type OutboxEntry struct {
+	ID            string          `db:"id" json:"id"`                           // uuid
+	Seq           int64           `db:"seq" json:"seq"`                         // bigserial, apply order
+	AggregateID   string          `db:"aggregate_id" json:"aggregate_id"`       // achievement_references.id
+	Operation     string          `db:"operation" json:"operation"`             // create_document, patch_document, ...
+	Payload       json.RawMessage `db:"payload" json:"payload"`                 // jsonb
+	Status        string          `db:"status" json:"status"`                   // pending, done, failed
+	Attempts      int             `db:"attempts" json:"attempts"`               // failed apply attempts so far
+	LastError     *string         `db:"last_error" json:"last_error"`           // error of the last attempt
+	NextAttemptAt time.Time       `db:"next_attempt_at" json:"next_attempt_at"` // not picked up before this time
+	CreatedAt     time.Time       `db:"created_at" json:"created_at"`
+	ProcessedAt   *time.Time      `db:"processed_at" json:"processed_at"`
+}
+
+// OutboxStats summarizes the outbox for monitoring.
+type OutboxStats struct {
+	Pending          int        `json:"pending"`
+	Failed           int        `json:"failed"`
+	Retrying         int        `json:"retrying"` // pending entries that already failed at least once
+	OldestPendingAt  *time.Time `json:"oldest_pending_at"`
+	OldestPendingAge float64    `json:"oldest_pending_age_seconds"`
+}
@@ -0,0 +1,11 @@
+package postgres
+
+import "time"
+
+// PasswordHistory is a password hash the user had before, kept to refuse reusing it.
+type PasswordHistory struct {
+	ID           string    `db:"id" json:"id"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	PasswordHash string    `db:"password_hash" json:"-"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"` // when it was replaced
+}
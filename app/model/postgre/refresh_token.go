@@ -0,0 +1,19 @@
+package postgres
+
+import "time"
+
+// RefreshToken is one opaque refresh token. Only its SHA-256 hash is stored. Every refresh
+// marks the token used and issues a child in the same family; presenting a used token again
+// revokes the whole family.
+type RefreshToken struct {
+	ID            string     `db:"id" json:"id"`               // uuid
+	FamilyID      string     `db:"family_id" json:"family_id"` // shared by every rotation of one login
+	ParentID      *string    `db:"parent_id" json:"parent_id"` // token this one was rotated from
+	UserID        string     `db:"user_id" json:"user_id"`     // FK -> users.id
+	TokenHash     string     `db:"token_hash" json:"-"`        // hex sha256 of the token
+	ExpiresAt     time.Time  `db:"expires_at" json:"expires_at"`
+	UsedAt        *time.Time `db:"used_at" json:"used_at"` // set when rotated
+	RevokedAt     *time.Time `db:"revoked_at" json:"revoked_at"`
+	RevokedReason *string    `db:"revoked_reason" json:"revoked_reason"` // logout, reuse_detected, ...
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+}
@@ -6,5 +6,6 @@ type Role struct {
 	ID          string    `db:"id" json:"id"`
 	Name        string    `db:"name" json:"name"`
 	Description string    `db:"description" json:"description"`
+	MFARequired bool      `db:"mfa_required" json:"mfa_required"` // members must log in with TOTP
 	CreatedAt   time.Time `db:"created_at" json:"created_at"`
 }
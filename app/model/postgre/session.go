@@ -0,0 +1,26 @@
+package postgres
+
+import "time"
+
+// Session is one login of a user on a device. Its id is also the family id of the
+// refresh tokens issued for that login and the "sid" claim of its access tokens.
+type Session struct {
+	ID            string     `db:"id" json:"id"`           // uuid
+	UserID        string     `db:"user_id" json:"user_id"` // FK -> users.id
+	Device        string     `db:"device" json:"device"`   // client supplied name, or derived from the user agent
+	IP            string     `db:"ip" json:"ip"`
+	UserAgent     string     `db:"user_agent" json:"user_agent"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	LastSeenAt    time.Time  `db:"last_seen_at" json:"last_seen_at"`
+	RevokedAt     *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	RevokedReason *string    `db:"revoked_reason" json:"revoked_reason,omitempty"` // logout, revoked, role_changed, ...
+
+	Current bool `db:"-" json:"current"` // set when listing: the session of the caller's token
+}
+
+// SessionMeta describes the client at login time.
+type SessionMeta struct {
+	Device    string
+	IP        string
+	UserAgent string
+}
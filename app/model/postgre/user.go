@@ -3,18 +3,39 @@ package postgres
 import "time"
 
 type User struct {
-	ID           string    `db:"id" json:"id"` // uuid string
-	Username     string    `db:"username" json:"username"`
-	Email        string    `db:"email" json:"email"`
-	PasswordHash string    `db:"password_hash" json:"password_hash"`
-	FullName     string    `db:"full_name" json:"full_name"`
-	RoleID       string    `db:"role_id" json:"role_id"` // FK -> roles.id
-	IsActive     bool      `db:"is_active" json:"is_active"`
-	CreatedAt    time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+	ID              string     `db:"id" json:"id"` // uuid string
+	Username        string     `db:"username" json:"username"`
+	Email           string     `db:"email" json:"email"`
+	PasswordHash    string     `db:"password_hash" json:"-"`
+	FullName        string     `db:"full_name" json:"full_name"`
+	RoleID          string     `db:"role_id" json:"role_id"` // FK -> roles.id
+	IsActive        bool       `db:"is_active" json:"is_active"`
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at"` // cleared when the email changes
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// CreateUserRequest is the body of POST /users.
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	FullName string `json:"full_name"`
+	RoleID   string `json:"role_id"`
+}
+
+// UpdateUserRequest is the body of PUT /users/:id; omitted fields keep their stored value.
+type UpdateUserRequest struct {
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+	FullName *string `json:"full_name"`
+	RoleID   *string `json:"role_id"`
+	IsActive *bool   `json:"is_active"`
+	Password *string `json:"password"` // new password in plain text
 }
 
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Device   string `json:"device"` // optional device name shown in the session list
 }
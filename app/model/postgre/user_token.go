@@ -0,0 +1,21 @@
+package postgres
+
+import "time"
+
+// Purposes of user tokens
+const (
+	TokenPurposePasswordReset = "password_reset"
+	TokenPurposeEmailVerify   = "email_verify"
+)
+
+// UserToken is a single-use token mailed to the user. Only its SHA-256 hash is stored.
+type UserToken struct {
+	ID        string     `db:"id" json:"id"`
+	UserID    string     `db:"user_id" json:"user_id"`
+	Purpose   string     `db:"purpose" json:"purpose"`
+	TokenHash string     `db:"token_hash" json:"-"`
+	Email     string     `db:"email" json:"email"` // address the token was sent to
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time `db:"used_at" json:"used_at"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
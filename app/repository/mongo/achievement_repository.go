@@ -16,11 +16,37 @@ import (
 // AchievementRepository defines the operations for achievements collection.
 type AchievementRepository interface {
 	Create(ctx context.Context, a *mongomodel.Achievement) (primitive.ObjectID, error)
+	CreateIfAbsent(ctx context.Context, a *mongomodel.Achievement) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*mongomodel.Achievement, error)
 	Update(ctx context.Context, id primitive.ObjectID, updates map[string]interface{}) error
 	SoftDelete(ctx context.Context, id primitive.ObjectID) error
 	ListByStudent(ctx context.Context, studentID string, limit, offset int64) ([]*mongomodel.Achievement, error)
 	AddAttachment(ctx context.Context, id primitive.ObjectID, attachment mongomodel.Attachment) error
+	RemoveAttachment(ctx context.Context, id primitive.ObjectID, attachmentID string) error
+	ReplaceAttachment(ctx context.Context, id primitive.ObjectID, attachment mongomodel.Attachment) error
+	SetAttachmentScan(ctx context.Context, id primitive.ObjectID, attachmentID, storageKey string, status, signature string, scannedAt time.Time) error
+	ListPendingScans(ctx context.Context, after primitive.ObjectID, limit int64) ([]*mongomodel.Achievement, error)
+	ApplyPatch(ctx context.Context, id primitive.ObjectID, set map[string]interface{}, unset map[string]interface{}) (*mongomodel.Achievement, error)
+	FindIDs(ctx context.Context, f AchievementFilter, limit int64) ([]primitive.ObjectID, error)
+	Iterate(ctx context.Context, fn func(a *mongomodel.Achievement) error) error
+	GetIncludingDeleted(ctx context.Context, id primitive.ObjectID) (*mongomodel.Achievement, error)
+	Restore(ctx context.Context, id primitive.ObjectID) error
+}
+
+// AchievementFilter selects non-deleted documents by their content; empty fields are ignored.
+type AchievementFilter struct {
+	Type     string
+	Level    string
+	Category string
+	Tag      string
+	Text     string // full-text search over title, category, tags and details
+
+	StudentIDs []string // narrows the content filters to these students (not a content filter itself)
+}
+
+// IsEmpty reports whether the filter has no constraint at all
+func (f AchievementFilter) IsEmpty() bool {
+	return f.Type == "" && f.Level == "" && f.Category == "" && f.Tag == "" && f.Text == ""
 }
 
 // --------------------------
@@ -33,43 +59,95 @@ type achievementRepo struct {
 func (r *achievementRepo) AddAttachment(ctx context.Context, id primitive.ObjectID, attachment mongomodel.Attachment) error {
     filter := bson.M{"_id": id}
     update := bson.M{
-        "$push": bson.M{"attachments": attachment},
+        "$addToSet": bson.M{"attachments": attachment},
         "$set":  bson.M{"updatedAt": time.Now()},
     }
     _, err := r.col.UpdateOne(ctx, filter, update)
     return err
 }
 
-// NewAchievementRepository creates repository and ensures indexes
-func NewAchievementRepository(db *driver.Database, collectionName string) AchievementRepository {
-	col := db.Collection(collectionName)
-	r := &achievementRepo{col: col}
+// RemoveAttachment pulls the attachment with attachmentID; removing a missing one is not an error.
+func (r *achievementRepo) RemoveAttachment(ctx context.Context, id primitive.ObjectID, attachmentID string) error {
+	update := bson.M{
+		"$pull": bson.M{"attachments": bson.M{"id": attachmentID}},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
 
-	// ensure indexes (best-effort)
-	_ = r.ensureIndexes(context.Background())
-	return r
+// ReplaceAttachment overwrites the attachment with the same id in place (order is kept).
+func (r *achievementRepo) ReplaceAttachment(ctx context.Context, id primitive.ObjectID, attachment mongomodel.Attachment) error {
+	update := bson.M{
+		"$set": bson.M{"attachments.$": attachment, "updatedAt": time.Now()},
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "attachments.id": attachment.ID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return driver.ErrNoDocuments
+	}
+	return nil
 }
 
-func (r *achievementRepo) ensureIndexes(ctx context.Context) error {
-	// index on studentId and createdAt
-	indexes := []driver.IndexModel{
-		{
-			Keys:    bson.D{{Key: "studentId", Value: 1}},
-			Options: options.Index().SetBackground(true),
-		},
-		{
-			Keys:    bson.D{{Key: "createdAt", Value: -1}},
-			Options: options.Index().SetBackground(true),
-		},
-		{
-			Keys:    bson.D{{Key: "tags", Value: 1}},
-			Options: options.Index().SetBackground(true),
-		},
+// SetAttachmentScan records the scan verdict of one attachment file; an attachment that was removed
+// or got another file (storageKey) meanwhile is left alone.
+func (r *achievementRepo) SetAttachmentScan(ctx context.Context, id primitive.ObjectID, attachmentID, storageKey string, status, signature string, scannedAt time.Time) error {
+	set := bson.M{
+		"attachments.$.scanStatus": status,
+		"attachments.$.scannedAt":  scannedAt,
+	}
+	update := bson.M{"$set": set}
+	if signature != "" {
+		set["attachments.$.scanSignature"] = signature
+	} else {
+		update["$unset"] = bson.M{"attachments.$.scanSignature": ""}
 	}
-	_, err := r.col.Indexes().CreateMany(ctx, indexes)
+	filter := bson.M{
+		"_id":         id,
+		"attachments": bson.M{"$elemMatch": bson.M{"id": attachmentID, "storageKey": storageKey}},
+	}
+	_, err := r.col.UpdateOne(ctx, filter, update)
 	return err
 }
 
+// ListPendingScans returns up to limit non-deleted documents (id and attachments only) with an
+// attachment still waiting for its malware scan, in _id order starting after after (the last id
+// of the previous page, or primitive.NilObjectID for the first one).
+func (r *achievementRepo) ListPendingScans(ctx context.Context, after primitive.ObjectID, limit int64) ([]*mongomodel.Achievement, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"_id": 1, "attachments": 1})
+	filter := bson.M{
+		"_id":                    bson.M{"$gt": after},
+		"attachments.scanStatus": mongomodel.ScanPending,
+		"deletedAt":              bson.M{"$exists": false},
+	}
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*mongomodel.Achievement
+	for cur.Next(ctx) {
+		var a mongomodel.Achievement
+		if err := cur.Decode(&a); err != nil {
+			return nil, err
+		}
+		out = append(out, &a)
+	}
+	return out, cur.Err()
+}
+
+// NewAchievementRepository creates the repository. Indexes are managed by the
+// versioned migrations in database/migrations.
+func NewAchievementRepository(db *driver.Database, collectionName string) AchievementRepository {
+	return &achievementRepo{col: db.Collection(collectionName)}
+}
+
 // Create inserts a new achievement document and returns its ObjectID
 func (r *achievementRepo) Create(ctx context.Context, a *mongomodel.Achievement) (primitive.ObjectID, error) {
 	now := time.Now()
@@ -89,6 +167,34 @@ func (r *achievementRepo) Create(ctx context.Context, a *mongomodel.Achievement)
 	return oid, nil
 }
 
+// CreateIfAbsent inserts a with its preset ID unless a document with that ID already exists,
+// so replaying the insert is harmless.
+func (r *achievementRepo) CreateIfAbsent(ctx context.Context, a *mongomodel.Achievement) error {
+	if a.ID.IsZero() {
+		return errors.New("achievement id must be set")
+	}
+	now := time.Now()
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = now
+	}
+	if a.UpdatedAt.IsZero() {
+		a.UpdatedAt = now
+	}
+	raw, err := bson.Marshal(a)
+	if err != nil {
+		return err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	delete(doc, "_id") // taken from the filter on insert
+
+	opts := options.Update().SetUpsert(true)
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": a.ID}, bson.M{"$setOnInsert": doc}, opts)
+	return err
+}
+
 // GetByID fetches an achievement by ObjectID
 func (r *achievementRepo) GetByID(ctx context.Context, id primitive.ObjectID) (*mongomodel.Achievement, error) {
 	var out mongomodel.Achievement
@@ -118,6 +224,78 @@ func (r *achievementRepo) Update(ctx context.Context, id primitive.ObjectID, upd
 	return nil
 }
 
+// ApplyPatch runs $set / $unset on a non-deleted document and returns the document after the update
+func (r *achievementRepo) ApplyPatch(ctx context.Context, id primitive.ObjectID, set map[string]interface{}, unset map[string]interface{}) (*mongomodel.Achievement, error) {
+	setDoc := bson.M{"updatedAt": time.Now()}
+	for k, v := range set {
+		setDoc[k] = v
+	}
+	update := bson.M{"$set": setDoc}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	filter := bson.M{"_id": id, "deletedAt": bson.M{"$exists": false}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var out mongomodel.Achievement
+	if err := r.col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&out); err != nil {
+		if err == driver.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FindIDs returns the ObjectIDs of at most limit non-deleted documents matching f (limit <= 0: no limit)
+func (r *achievementRepo) FindIDs(ctx context.Context, f AchievementFilter, limit int64) ([]primitive.ObjectID, error) {
+	filter := bson.M{"deletedAt": bson.M{"$exists": false}}
+	if f.Type != "" {
+		filter["type"] = f.Type
+	}
+	if f.Level != "" {
+		filter["level"] = f.Level
+	}
+	if f.Category != "" {
+		filter["category"] = f.Category
+	}
+	if f.Tag != "" {
+		filter["tags"] = f.Tag
+	}
+	if f.Text != "" {
+		filter["$text"] = bson.M{"$search": f.Text}
+	}
+	if len(f.StudentIDs) > 0 {
+		filter["studentId"] = bson.M{"$in": f.StudentIDs}
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := []primitive.ObjectID{}
+	for cur.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		out = append(out, doc.ID)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SoftDelete sets deletedAt timestamp instead of physically deleting
 func (r *achievementRepo) SoftDelete(ctx context.Context, id primitive.ObjectID) error {
 	now := time.Now()
@@ -137,6 +315,61 @@ func (r *achievementRepo) SoftDelete(ctx context.Context, id primitive.ObjectID)
 	return nil
 }
 
+// Iterate calls fn for every document, soft-deleted ones included, in _id order.
+// Only the fields needed to cross-check references are loaded.
+func (r *achievementRepo) Iterate(ctx context.Context, fn func(a *mongomodel.Achievement) error) error {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"_id": 1, "studentId": 1, "deletedAt": 1, "createdAt": 1})
+	cur, err := r.col.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var a mongomodel.Achievement
+		if err := cur.Decode(&a); err != nil {
+			return err
+		}
+		if err := fn(&a); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// GetIncludingDeleted fetches the fields Iterate loads for one document, soft-deleted or not
+// (nil when there is no document at all).
+func (r *achievementRepo) GetIncludingDeleted(ctx context.Context, id primitive.ObjectID) (*mongomodel.Achievement, error) {
+	var out mongomodel.Achievement
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1, "studentId": 1, "deletedAt": 1, "createdAt": 1})
+	err := r.col.FindOne(ctx, bson.M{"_id": id}, opts).Decode(&out)
+	if err != nil {
+		if err == driver.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Restore clears deletedAt on a soft-deleted document
+func (r *achievementRepo) Restore(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{
+		"$unset": bson.M{"deletedAt": ""},
+		"$set":   bson.M{"updatedAt": time.Now()},
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return driver.ErrNoDocuments
+	}
+	return nil
+}
+
 // ListByStudent returns achievements for a given student with pagination
 func (r *achievementRepo) ListByStudent(ctx context.Context, studentID string, limit, offset int64) ([]*mongomodel.Achievement, error) {
 	if limit <= 0 {
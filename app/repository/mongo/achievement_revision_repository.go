@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"context"
+
+	mongomodel "UAS_BACKEND/app/model/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	driver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AchievementRevisionRepository stores submission snapshots. It is insert-only on purpose.
+type AchievementRevisionRepository interface {
+	Create(ctx context.Context, rev *mongomodel.AchievementRevision) (primitive.ObjectID, error)
+	ListByReference(ctx context.Context, referenceID string) ([]*mongomodel.AchievementRevision, error)
+	GetByNumber(ctx context.Context, referenceID string, number int) (*mongomodel.AchievementRevision, error)
+	GetLatest(ctx context.Context, referenceID string) (*mongomodel.AchievementRevision, error)
+}
+
+// --------------------------
+// Implementation
+// --------------------------
+type achievementRevisionRepo struct {
+	col *driver.Collection
+}
+
+// NewAchievementRevisionRepository creates repository (the unique (referenceId, number) index is a migration)
+func NewAchievementRevisionRepository(db *driver.Database, collectionName string) AchievementRevisionRepository {
+	col := db.Collection(collectionName)
+	return &achievementRevisionRepo{col: col}
+}
+
+func (r *achievementRevisionRepo) Create(ctx context.Context, rev *mongomodel.AchievementRevision) (primitive.ObjectID, error) {
+	res, err := r.col.InsertOne(ctx, rev)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	oid, _ := res.InsertedID.(primitive.ObjectID)
+	rev.ID = oid
+	return oid, nil
+}
+
+func (r *achievementRevisionRepo) ListByReference(ctx context.Context, referenceID string) ([]*mongomodel.AchievementRevision, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "number", Value: 1}})
+	cur, err := r.col.Find(ctx, bson.M{"referenceId": referenceID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := []*mongomodel.AchievementRevision{}
+	for cur.Next(ctx) {
+		var rev mongomodel.AchievementRevision
+		if err := cur.Decode(&rev); err != nil {
+			return nil, err
+		}
+		out = append(out, &rev)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *achievementRevisionRepo) GetByNumber(ctx context.Context, referenceID string, number int) (*mongomodel.AchievementRevision, error) {
+	var out mongomodel.AchievementRevision
+	err := r.col.FindOne(ctx, bson.M{"referenceId": referenceID, "number": number}).Decode(&out)
+	if err != nil {
+		if err == driver.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *achievementRevisionRepo) GetLatest(ctx context.Context, referenceID string) (*mongomodel.AchievementRevision, error) {
+	var out mongomodel.AchievementRevision
+	opts := options.FindOne().SetSort(bson.D{{Key: "number", Value: -1}})
+	err := r.col.FindOne(ctx, bson.M{"referenceId": referenceID}, opts).Decode(&out)
+	if err != nil {
+		if err == driver.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &out, nil
+}
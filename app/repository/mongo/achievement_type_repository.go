@@ -0,0 +1,92 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	mongomodel "UAS_BACKEND/app/model/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	driver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AchievementTypeRepository stores the admin-managed details schema per achievement type.
+type AchievementTypeRepository interface {
+	ListAll(ctx context.Context) ([]*mongomodel.AchievementTypeSchema, error)
+	GetByType(ctx context.Context, typ string) (*mongomodel.AchievementTypeSchema, error)
+	Upsert(ctx context.Context, schema *mongomodel.AchievementTypeSchema) error
+	Delete(ctx context.Context, typ string) error
+}
+
+// --------------------------
+// Implementation
+// --------------------------
+type achievementTypeRepo struct {
+	col *driver.Collection
+}
+
+// NewAchievementTypeRepository creates repository (the unique index on type is a migration)
+func NewAchievementTypeRepository(db *driver.Database, collectionName string) AchievementTypeRepository {
+	col := db.Collection(collectionName)
+	return &achievementTypeRepo{col: col}
+}
+
+func (r *achievementTypeRepo) ListAll(ctx context.Context) ([]*mongomodel.AchievementTypeSchema, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "type", Value: 1}})
+	cur, err := r.col.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*mongomodel.AchievementTypeSchema
+	for cur.Next(ctx) {
+		var s mongomodel.AchievementTypeSchema
+		if err := cur.Decode(&s); err != nil {
+			return nil, err
+		}
+		out = append(out, &s)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *achievementTypeRepo) GetByType(ctx context.Context, typ string) (*mongomodel.AchievementTypeSchema, error) {
+	var out mongomodel.AchievementTypeSchema
+	err := r.col.FindOne(ctx, bson.M{"type": typ}).Decode(&out)
+	if err != nil {
+		if err == driver.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Upsert replaces the schema document for schema.Type (insert if missing)
+func (r *achievementTypeRepo) Upsert(ctx context.Context, schema *mongomodel.AchievementTypeSchema) error {
+	schema.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{
+		"type":        schema.Type,
+		"name":        schema.Name,
+		"description": schema.Description,
+		"fields":      schema.Fields,
+		"updatedAt":   schema.UpdatedAt,
+	}}
+	_, err := r.col.UpdateOne(ctx, bson.M{"type": schema.Type}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *achievementTypeRepo) Delete(ctx context.Context, typ string) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"type": typ})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return driver.ErrNoDocuments
+	}
+	return nil
+}
@@ -3,9 +3,13 @@ package postgre
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
 	pgmodel "UAS_BACKEND/app/model/postgre"
+
+	"github.com/lib/pq"
 )
 
 // AchievementRefRepository handles achievement_references table.
@@ -13,22 +17,69 @@ type AchievementRefRepository interface {
 	Create(ctx context.Context, ref *pgmodel.AchievementReference) error
 	UpdateStatus(ctx context.Context, id string, status string, verifierID *string) error
 	GetByID(ctx context.Context, id string) (*pgmodel.AchievementReference, error)
+	GetByIDForUpdate(ctx context.Context, id string) (*pgmodel.AchievementReference, error)
+	// GetByMongoID returns the reference pointing at a Mongo document, or nil when there is none.
+	GetByMongoID(ctx context.Context, mongoID string) (*pgmodel.AchievementReference, error)
 	ListByStudent(ctx context.Context, studentID string) ([]*pgmodel.AchievementReference, error)
+	ListByStudents(ctx context.Context, studentIDs []string) ([]*pgmodel.AchievementReference, error)
 	UpdateRejectionNote(ctx context.Context, id string, note string) error
+	// NextRevisionNumber bumps revision_count to at least atLeast+1 and returns it; run it on the
+	// locked row (see GetByIDForUpdate) so two submits never get the same number.
+	NextRevisionNumber(ctx context.Context, id string, atLeast int) (int, error)
 	ListAll(ctx context.Context) ([]*pgmodel.AchievementReference, error)
 	Update(ctx context.Context, ref *pgmodel.AchievementReference) error
 	Delete(ctx context.Context, id string) error
+	Search(ctx context.Context, f AchievementRefFilter) ([]*pgmodel.AchievementReference, int, error)
+	WithTx(tx *sql.Tx) AchievementRefRepository
+}
+
+// AchievementRefFilter describes a filtered, sorted and paginated query on achievement_references.
+// MongoIDs == nil means "no constraint"; an empty non-nil slice matches nothing.
+type AchievementRefFilter struct {
+	Statuses      []string
+	StudentIDs    []string
+	AdvisorID     string // lecturers.id, matched through students.advisor_id
+	MongoIDs      []string
+	SubmittedFrom *time.Time
+	SubmittedTo   *time.Time
+	VerifiedFrom  *time.Time
+	VerifiedTo    *time.Time
+
+	SortField string // created_at, updated_at, submitted_at, verified_at, status
+	SortDesc  bool
+	Limit     int
+	Offset    int
+	// Keyset pagination (only for created_at / updated_at): rows strictly after (AfterValue, AfterID)
+	AfterValue *time.Time
+	AfterID    string
+}
+
+// achievementRefSortColumns whitelists sortable columns
+var achievementRefSortColumns = map[string]string{
+	"created_at":   "ar.created_at",
+	"updated_at":   "ar.updated_at",
+	"submitted_at": "ar.submitted_at",
+	"verified_at":  "ar.verified_at",
+	"status":       "ar.status",
 }
 
 // Implementation
 type achievementRefRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewAchievementRefRepository(db *sql.DB) AchievementRefRepository {
 	return &achievementRefRepository{db: db}
 }
 
+// WithTx returns a copy of the repository that runs its statements in tx (nil tx returns r itself).
+func (r *achievementRefRepository) WithTx(tx *sql.Tx) AchievementRefRepository {
+	if tx == nil {
+		return r
+	}
+	return &achievementRefRepository{db: tx}
+}
+
 func (r *achievementRefRepository) Create(ctx context.Context, ref *pgmodel.AchievementReference) error {
 	now := time.Now()
 	ref.CreatedAt = now
@@ -68,6 +119,35 @@ func (r *achievementRefRepository) GetByID(ctx context.Context, id string) (*pgm
 	return &out, nil
 }
 
+// GetByIDForUpdate reads a reference and locks its row until the surrounding transaction ends
+// (only meaningful on a repository returned by WithTx).
+func (r *achievementRefRepository) GetByIDForUpdate(ctx context.Context, id string) (*pgmodel.AchievementReference, error) {
+	var out pgmodel.AchievementReference
+	q := `SELECT id, student_id, mongo_achievement_id, status, submitted_at, verified_at, verified_by, rejection_note, created_at, updated_at
+	      FROM achievement_references WHERE id=$1 FOR UPDATE`
+	row := r.db.QueryRowContext(ctx, q, id)
+	if err := row.Scan(&out.ID, &out.StudentID, &out.MongoAchievementID, &out.Status,
+		&out.SubmittedAt, &out.VerifiedAt, &out.VerifiedBy, &out.RejectionNote, &out.CreatedAt, &out.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *achievementRefRepository) GetByMongoID(ctx context.Context, mongoID string) (*pgmodel.AchievementReference, error) {
+	var out pgmodel.AchievementReference
+	q := `SELECT id, student_id, mongo_achievement_id, status, submitted_at, verified_at, verified_by, rejection_note, created_at, updated_at
+	      FROM achievement_references WHERE mongo_achievement_id=$1 LIMIT 1`
+	row := r.db.QueryRowContext(ctx, q, mongoID)
+	if err := row.Scan(&out.ID, &out.StudentID, &out.MongoAchievementID, &out.Status,
+		&out.SubmittedAt, &out.VerifiedAt, &out.VerifiedBy, &out.RejectionNote, &out.CreatedAt, &out.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
 func (r *achievementRefRepository) ListByStudent(ctx context.Context, studentID string) ([]*pgmodel.AchievementReference, error) {
 	q := `SELECT id, student_id, mongo_achievement_id, status, submitted_at, verified_at, verified_by, rejection_note, created_at, updated_at
 	      FROM achievement_references WHERE student_id=$1 ORDER BY created_at DESC`
@@ -89,6 +169,30 @@ func (r *achievementRefRepository) ListByStudent(ctx context.Context, studentID
 	return out, nil
 }
 
+func (r *achievementRefRepository) ListByStudents(ctx context.Context, studentIDs []string) ([]*pgmodel.AchievementReference, error) {
+	out := []*pgmodel.AchievementReference{}
+	if len(studentIDs) == 0 {
+		return out, nil
+	}
+	q := `SELECT id, student_id, mongo_achievement_id, status, submitted_at, verified_at, verified_by, rejection_note, created_at, updated_at
+	      FROM achievement_references WHERE student_id = ANY($1) ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, q, pq.Array(studentIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item pgmodel.AchievementReference
+		if err := rows.Scan(&item.ID, &item.StudentID, &item.MongoAchievementID, &item.Status,
+			&item.SubmittedAt, &item.VerifiedAt, &item.VerifiedBy, &item.RejectionNote, &item.CreatedAt, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &item)
+	}
+	return out, nil
+}
+
 func (r *achievementRefRepository) UpdateRejectionNote(ctx context.Context, id string, note string) error {
 	now := time.Now()
 	q := `UPDATE achievement_references SET rejection_note=$1, status='rejected', updated_at=$2 WHERE id=$3`
@@ -96,6 +200,13 @@ func (r *achievementRefRepository) UpdateRejectionNote(ctx context.Context, id s
 	return err
 }
 
+func (r *achievementRefRepository) NextRevisionNumber(ctx context.Context, id string, atLeast int) (int, error) {
+	var n int
+	q := `UPDATE achievement_references SET revision_count = GREATEST(revision_count, $2) + 1 WHERE id=$1 RETURNING revision_count`
+	err := r.db.QueryRowContext(ctx, q, id, atLeast).Scan(&n)
+	return n, err
+}
+
 func (r *achievementRefRepository) ListAll(ctx context.Context) ([]*pgmodel.AchievementReference, error) {
 	q := `SELECT id, student_id, mongo_achievement_id, status, submitted_at, verified_at, verified_by, rejection_note, created_at, updated_at
 	      FROM achievement_references ORDER BY created_at DESC`
@@ -136,3 +247,100 @@ func (r *achievementRefRepository) Delete(ctx context.Context, id string) error
 	_, err := r.db.ExecContext(ctx, q, id)
 	return err
 }
+
+// Search returns one page of references matching f together with the total number of matches.
+func (r *achievementRefRepository) Search(ctx context.Context, f AchievementRefFilter) ([]*pgmodel.AchievementReference, int, error) {
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.MongoIDs != nil {
+		if len(f.MongoIDs) == 0 {
+			return []*pgmodel.AchievementReference{}, 0, nil
+		}
+		where = append(where, "ar.mongo_achievement_id = ANY("+arg(pq.Array(f.MongoIDs))+")")
+	}
+	if len(f.Statuses) > 0 {
+		where = append(where, "ar.status = ANY("+arg(pq.Array(f.Statuses))+")")
+	}
+	if len(f.StudentIDs) > 0 {
+		where = append(where, "ar.student_id = ANY("+arg(pq.Array(f.StudentIDs))+")")
+	}
+	if f.AdvisorID != "" {
+		where = append(where, "s.advisor_id = "+arg(f.AdvisorID))
+	}
+	if f.SubmittedFrom != nil {
+		where = append(where, "ar.submitted_at >= "+arg(*f.SubmittedFrom))
+	}
+	if f.SubmittedTo != nil {
+		where = append(where, "ar.submitted_at <= "+arg(*f.SubmittedTo))
+	}
+	if f.VerifiedFrom != nil {
+		where = append(where, "ar.verified_at >= "+arg(*f.VerifiedFrom))
+	}
+	if f.VerifiedTo != nil {
+		where = append(where, "ar.verified_at <= "+arg(*f.VerifiedTo))
+	}
+
+	from := ` FROM achievement_references ar JOIN students s ON s.id = ar.student_id`
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	// total (ignores pagination)
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*)"+from+whereSQL, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	col, ok := achievementRefSortColumns[f.SortField]
+	if !ok {
+		col = "ar.created_at"
+	}
+	dir, cmp := "ASC", ">"
+	if f.SortDesc {
+		dir, cmp = "DESC", "<"
+	}
+
+	pageWhere := where
+	if f.AfterValue != nil && f.AfterID != "" {
+		pageWhere = append(pageWhere, fmt.Sprintf("(%s, ar.id) %s (%s, %s)", col, cmp, arg(*f.AfterValue), arg(f.AfterID)))
+	}
+	pageWhereSQL := ""
+	if len(pageWhere) > 0 {
+		pageWhereSQL = " WHERE " + strings.Join(pageWhere, " AND ")
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	q := `SELECT ar.id, ar.student_id, ar.mongo_achievement_id, ar.status, ar.submitted_at, ar.verified_at, ar.verified_by, ar.rejection_note, ar.created_at, ar.updated_at` +
+		from + pageWhereSQL +
+		fmt.Sprintf(" ORDER BY %s %s NULLS LAST, ar.id %s", col, dir, dir) +
+		" LIMIT " + arg(limit)
+	if f.AfterValue == nil && f.Offset > 0 {
+		q += " OFFSET " + arg(f.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := []*pgmodel.AchievementReference{}
+	for rows.Next() {
+		var item pgmodel.AchievementReference
+		if err := rows.Scan(&item.ID, &item.StudentID, &item.MongoAchievementID, &item.Status,
+			&item.SubmittedAt, &item.VerifiedAt, &item.VerifiedBy, &item.RejectionNote, &item.CreatedAt, &item.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, &item)
+	}
+	return out, total, rows.Err()
+}
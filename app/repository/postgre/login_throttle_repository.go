@@ -0,0 +1,61 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	pgmodel "UAS_BACKEND/app/model/postgre"
+)
+
+// LoginThrottleRepository handles the login_throttle table.
+type LoginThrottleRepository interface {
+	// Get returns sql.ErrNoRows when the username has no recorded failures.
+	Get(ctx context.Context, username string) (*pgmodel.LoginThrottle, error)
+	// RecordFailure increments the failure count and returns it. Failures older than window
+	// are forgotten: the count starts again at 1.
+	RecordFailure(ctx context.Context, username string, window time.Duration) (int, error)
+	Lock(ctx context.Context, username string, until time.Time) error
+	// Reset forgets the failures and any lock of the username.
+	Reset(ctx context.Context, username string) error
+}
+
+type loginThrottleRepository struct {
+	db *sql.DB
+}
+
+func NewLoginThrottleRepository(db *sql.DB) LoginThrottleRepository {
+	return &loginThrottleRepository{db: db}
+}
+
+func (r *loginThrottleRepository) Get(ctx context.Context, username string) (*pgmodel.LoginThrottle, error) {
+	var t pgmodel.LoginThrottle
+	q := `SELECT username, failed_count, last_failed_at, locked_until FROM login_throttle WHERE username=$1`
+	err := r.db.QueryRowContext(ctx, q, username).Scan(&t.Username, &t.FailedCount, &t.LastFailedAt, &t.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *loginThrottleRepository) RecordFailure(ctx context.Context, username string, window time.Duration) (int, error) {
+	q := `INSERT INTO login_throttle (username, failed_count, last_failed_at) VALUES ($1, 1, now())
+	      ON CONFLICT (username) DO UPDATE SET
+	          failed_count = CASE WHEN login_throttle.last_failed_at < now() - make_interval(secs => $2)
+	                              THEN 1 ELSE login_throttle.failed_count + 1 END,
+	          last_failed_at = now()
+	      RETURNING failed_count`
+	var n int
+	err := r.db.QueryRowContext(ctx, q, username, window.Seconds()).Scan(&n)
+	return n, err
+}
+
+func (r *loginThrottleRepository) Lock(ctx context.Context, username string, until time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE login_throttle SET locked_until=$2 WHERE username=$1`, username, until)
+	return err
+}
+
+func (r *loginThrottleRepository) Reset(ctx context.Context, username string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM login_throttle WHERE username=$1`, username)
+	return err
+}
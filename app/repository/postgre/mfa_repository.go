@@ -0,0 +1,131 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	pgmodel "UAS_BACKEND/app/model/postgre"
+)
+
+// MFARepository handles the user_mfa and mfa_recovery_codes tables.
+type MFARepository interface {
+	// Get returns sql.ErrNoRows when the user never started an enrollment.
+	Get(ctx context.Context, userID string) (*pgmodel.UserMFA, error)
+	// SetPending stores a new unconfirmed secret; it returns false when MFA is already enabled.
+	SetPending(ctx context.Context, userID string, secret string) (bool, error)
+	Enable(ctx context.Context, userID string) error
+	// UseStep records the time step of an accepted code. It returns false when that step (or a
+	// later one) was already used, so a code cannot be replayed.
+	UseStep(ctx context.Context, userID string, step int64) (bool, error)
+	// Delete removes the enrollment and the recovery codes.
+	Delete(ctx context.Context, userID string) error
+
+	// ReplaceRecoveryCodes deletes the old codes of the user and stores codes.
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codes []*pgmodel.MFARecoveryCode) error
+	// UseRecoveryCode marks the code used; it returns false when no unused code matches.
+	UseRecoveryCode(ctx context.Context, userID string, hash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+}
+
+type mfaRepository struct {
+	db *sql.DB
+}
+
+func NewMFARepository(db *sql.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) Get(ctx context.Context, userID string) (*pgmodel.UserMFA, error) {
+	var m pgmodel.UserMFA
+	q := `SELECT user_id, secret, enabled_at, last_used_step, created_at FROM user_mfa WHERE user_id=$1`
+	err := r.db.QueryRowContext(ctx, q, userID).Scan(&m.UserID, &m.Secret, &m.EnabledAt, &m.LastUsedStep, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *mfaRepository) SetPending(ctx context.Context, userID string, secret string) (bool, error) {
+	q := `INSERT INTO user_mfa (user_id, secret, created_at) VALUES ($1,$2,now())
+	      ON CONFLICT (user_id) DO UPDATE SET secret=EXCLUDED.secret, last_used_step=0, created_at=now()
+	      WHERE user_mfa.enabled_at IS NULL`
+	res, err := r.db.ExecContext(ctx, q, userID, secret)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *mfaRepository) Enable(ctx context.Context, userID string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE user_mfa SET enabled_at=now() WHERE user_id=$1 AND enabled_at IS NULL`, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *mfaRepository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE user_mfa SET last_used_step=$2 WHERE user_id=$1 AND last_used_step < $2`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *mfaRepository) Delete(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codes []*pgmodel.MFARecoveryCode) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	for _, c := range codes {
+		if c.CreatedAt.IsZero() {
+			c.CreatedAt = time.Now()
+		}
+		q := `INSERT INTO mfa_recovery_codes (id, user_id, code_hash, created_at) VALUES ($1,$2,$3,$4)`
+		if _, err := tx.ExecContext(ctx, q, c.ID, c.UserID, c.CodeHash, c.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID string, hash string) (bool, error) {
+	q := `UPDATE mfa_recovery_codes SET used_at=now() WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL`
+	res, err := r.db.ExecContext(ctx, q, userID, hash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *mfaRepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM mfa_recovery_codes WHERE user_id=$1 AND used_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
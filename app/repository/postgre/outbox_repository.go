@@ -0,0 +1,206 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	pgmodel "UAS_BACKEND/app/model/postgre"
+
+	"github.com/lib/pq"
+)
+
+// OutboxRepository handles the achievement_outbox table.
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, e *pgmodel.OutboxEntry) error
+	// ClaimPending leases up to limit due entries whose earlier entries (same aggregate) are all done,
+	// in seq order: their next_attempt_at moves lease into the future so no other worker picks them
+	// up while they are applied. Rows locked by a concurrent claim are skipped. The claim commits on
+	// its own; an entry whose worker dies becomes due again when the lease runs out.
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*pgmodel.OutboxEntry, error)
+	// ClaimByID leases a single entry under the same rules; nil means it is not claimable right now.
+	ClaimByID(ctx context.Context, id string, lease time.Duration) (*pgmodel.OutboxEntry, error)
+	// MarkDone, MarkRetry and MarkFailed record the outcome of a claimed entry; entries that are no
+	// longer pending (another worker finished them after the lease ran out) are left alone.
+	MarkDone(ctx context.Context, id string) error
+	MarkRetry(ctx context.Context, id string, attempts int, lastError string, next time.Time) error
+	MarkFailed(ctx context.Context, id string, attempts int, lastError string) error
+	Requeue(ctx context.Context, id string) (bool, error)
+	ListFailed(ctx context.Context, limit int) ([]*pgmodel.OutboxEntry, error)
+	Stats(ctx context.Context) (*pgmodel.OutboxStats, error)
+	// ListUnfinished returns the pending and failed entries of an aggregate with one of ops, in seq order.
+	ListUnfinished(ctx context.Context, aggregateID string, ops []string) ([]*pgmodel.OutboxEntry, error)
+	// UnfinishedAggregates returns the aggregate IDs that still have pending or failed entries.
+	UnfinishedAggregates(ctx context.Context) (map[string]bool, error)
+	WithTx(tx *sql.Tx) OutboxRepository
+}
+
+type outboxRepository struct {
+	db DBTX
+}
+
+func NewOutboxRepository(db *sql.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its statements in tx (nil tx returns r itself).
+func (r *outboxRepository) WithTx(tx *sql.Tx) OutboxRepository {
+	if tx == nil {
+		return r
+	}
+	return &outboxRepository{db: tx}
+}
+
+const outboxColumns = `id, seq, aggregate_id, operation, payload, status, attempts, last_error, next_attempt_at, created_at, processed_at`
+
+// outboxClaimable keeps per-aggregate order: an entry waits while an earlier one is pending or failed.
+const outboxClaimable = `o.status = 'pending' AND o.next_attempt_at <= now()
+	  AND NOT EXISTS (SELECT 1 FROM achievement_outbox p
+	                  WHERE p.aggregate_id = o.aggregate_id AND p.seq < o.seq AND p.status <> 'done')`
+
+func (r *outboxRepository) Enqueue(ctx context.Context, e *pgmodel.OutboxEntry) error {
+	now := time.Now()
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = now
+	}
+	if e.NextAttemptAt.IsZero() {
+		e.NextAttemptAt = e.CreatedAt
+	}
+	if e.Status == "" {
+		e.Status = pgmodel.OutboxPending
+	}
+	q := `INSERT INTO achievement_outbox (id, aggregate_id, operation, payload, status, attempts, next_attempt_at, created_at)
+	      VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING seq`
+	return r.db.QueryRowContext(ctx, q,
+		e.ID, e.AggregateID, e.Operation, []byte(e.Payload), e.Status, e.Attempts, e.NextAttemptAt, e.CreatedAt,
+	).Scan(&e.Seq)
+}
+
+func (r *outboxRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*pgmodel.OutboxEntry, error) {
+	q := `UPDATE achievement_outbox SET next_attempt_at = now() + $2 * interval '1 millisecond'
+	      WHERE id IN (SELECT o.id FROM achievement_outbox o
+	                   WHERE ` + outboxClaimable + `
+	                   ORDER BY o.seq
+	                   LIMIT $1
+	                   FOR UPDATE SKIP LOCKED)
+	      RETURNING ` + outboxColumns
+	list, err := r.query(ctx, q, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Seq < list[j].Seq })
+	return list, nil
+}
+
+func (r *outboxRepository) ClaimByID(ctx context.Context, id string, lease time.Duration) (*pgmodel.OutboxEntry, error) {
+	q := `UPDATE achievement_outbox SET next_attempt_at = now() + $2 * interval '1 millisecond'
+	      WHERE id IN (SELECT o.id FROM achievement_outbox o
+	                   WHERE o.id = $1 AND ` + outboxClaimable + `
+	                   FOR UPDATE SKIP LOCKED)
+	      RETURNING ` + outboxColumns
+	list, err := r.query(ctx, q, id, lease.Milliseconds())
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[0], nil
+}
+
+func (r *outboxRepository) MarkDone(ctx context.Context, id string) error {
+	q := `UPDATE achievement_outbox SET status='done', last_error=NULL, processed_at=now() WHERE id=$1 AND status='pending'`
+	_, err := r.db.ExecContext(ctx, q, id)
+	return err
+}
+
+func (r *outboxRepository) MarkRetry(ctx context.Context, id string, attempts int, lastError string, next time.Time) error {
+	q := `UPDATE achievement_outbox SET attempts=$1, last_error=$2, next_attempt_at=$3 WHERE id=$4 AND status='pending'`
+	_, err := r.db.ExecContext(ctx, q, attempts, lastError, next, id)
+	return err
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id string, attempts int, lastError string) error {
+	q := `UPDATE achievement_outbox SET status='failed', attempts=$1, last_error=$2, processed_at=now() WHERE id=$3 AND status='pending'`
+	_, err := r.db.ExecContext(ctx, q, attempts, lastError, id)
+	return err
+}
+
+// Requeue puts a failed entry back to pending with a fresh attempt budget.
+func (r *outboxRepository) Requeue(ctx context.Context, id string) (bool, error) {
+	q := `UPDATE achievement_outbox SET status='pending', attempts=0, next_attempt_at=now(), processed_at=NULL
+	      WHERE id=$1 AND status='failed'`
+	res, err := r.db.ExecContext(ctx, q, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *outboxRepository) ListFailed(ctx context.Context, limit int) ([]*pgmodel.OutboxEntry, error) {
+	q := `SELECT ` + outboxColumns + ` FROM achievement_outbox o
+	      WHERE o.status = 'failed' ORDER BY o.seq DESC LIMIT $1`
+	return r.query(ctx, q, limit)
+}
+
+func (r *outboxRepository) Stats(ctx context.Context) (*pgmodel.OutboxStats, error) {
+	var out pgmodel.OutboxStats
+	q := `SELECT
+	        COUNT(*) FILTER (WHERE status='pending'),
+	        COUNT(*) FILTER (WHERE status='failed'),
+	        COUNT(*) FILTER (WHERE status='pending' AND attempts > 0),
+	        MIN(created_at) FILTER (WHERE status='pending')
+	      FROM achievement_outbox`
+	if err := r.db.QueryRowContext(ctx, q).Scan(&out.Pending, &out.Failed, &out.Retrying, &out.OldestPendingAt); err != nil {
+		return nil, err
+	}
+	if out.OldestPendingAt != nil {
+		out.OldestPendingAge = time.Since(*out.OldestPendingAt).Seconds()
+	}
+	return &out, nil
+}
+
+func (r *outboxRepository) ListUnfinished(ctx context.Context, aggregateID string, ops []string) ([]*pgmodel.OutboxEntry, error) {
+	q := `SELECT ` + outboxColumns + ` FROM achievement_outbox o
+	      WHERE o.aggregate_id = $1 AND o.operation = ANY($2) AND o.status <> 'done'
+	      ORDER BY o.seq`
+	return r.query(ctx, q, aggregateID, pq.Array(ops))
+}
+
+func (r *outboxRepository) UnfinishedAggregates(ctx context.Context) (map[string]bool, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT aggregate_id FROM achievement_outbox WHERE status <> 'done'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out[id] = true
+	}
+	return out, rows.Err()
+}
+
+func (r *outboxRepository) query(ctx context.Context, q string, args ...interface{}) ([]*pgmodel.OutboxEntry, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*pgmodel.OutboxEntry{}
+	for rows.Next() {
+		var e pgmodel.OutboxEntry
+		var payload []byte
+		if err := rows.Scan(&e.ID, &e.Seq, &e.AggregateID, &e.Operation, &payload, &e.Status, &e.Attempts,
+			&e.LastError, &e.NextAttemptAt, &e.CreatedAt, &e.ProcessedAt); err != nil {
+			return nil, err
+		}
+		e.Payload = payload
+		out = append(out, &e)
+	}
+	return out, rows.Err()
+}
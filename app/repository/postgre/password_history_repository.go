@@ -0,0 +1,61 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	pgmodel "UAS_BACKEND/app/model/postgre"
+)
+
+// PasswordHistoryRepository handles the password_history table.
+type PasswordHistoryRepository interface {
+	Add(ctx context.Context, h *pgmodel.PasswordHistory) error
+	// Recent returns the newest limit hashes of the user, newest first.
+	Recent(ctx context.Context, userID string, limit int) ([]string, error)
+	// Prune deletes all but the newest keep entries of the user.
+	Prune(ctx context.Context, userID string, keep int) error
+}
+
+type passwordHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordHistoryRepository(db *sql.DB) PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: db}
+}
+
+func (r *passwordHistoryRepository) Add(ctx context.Context, h *pgmodel.PasswordHistory) error {
+	if h.CreatedAt.IsZero() {
+		h.CreatedAt = time.Now()
+	}
+	q := `INSERT INTO password_history (id, user_id, password_hash, created_at) VALUES ($1,$2,$3,$4)`
+	_, err := r.db.ExecContext(ctx, q, h.ID, h.UserID, h.PasswordHash, h.CreatedAt)
+	return err
+}
+
+func (r *passwordHistoryRepository) Recent(ctx context.Context, userID string, limit int) ([]string, error) {
+	q := `SELECT password_hash FROM password_history WHERE user_id=$1 ORDER BY created_at DESC LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, q, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := []string{}
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, h)
+	}
+	return hashes, rows.Err()
+}
+
+func (r *passwordHistoryRepository) Prune(ctx context.Context, userID string, keep int) error {
+	q := `DELETE FROM password_history WHERE user_id=$1 AND id NOT IN (
+	          SELECT id FROM password_history WHERE user_id=$1 ORDER BY created_at DESC LIMIT $2)`
+	_, err := r.db.ExecContext(ctx, q, userID, keep)
+	return err
+}
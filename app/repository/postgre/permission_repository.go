@@ -10,6 +10,9 @@ import (
 // PermissionRepository defines data access for permissions.
 type PermissionRepository interface {
 	Create(ctx context.Context, p *pgmodel.Permission) error
+	// Upsert inserts the permission or updates resource, action and description of the one with
+	// the same name; p.ID is set to the stored id.
+	Upsert(ctx context.Context, p *pgmodel.Permission) error
 	GetByID(ctx context.Context, id string) (*pgmodel.Permission, error)
 	GetByName(ctx context.Context, name string) (*pgmodel.Permission, error)
 	ListAll(ctx context.Context) ([]*pgmodel.Permission, error)
@@ -30,6 +33,13 @@ func (r *permissionRepository) Create(ctx context.Context, p *pgmodel.Permission
 	return err
 }
 
+func (r *permissionRepository) Upsert(ctx context.Context, p *pgmodel.Permission) error {
+	q := `INSERT INTO permissions (id, name, resource, action, description) VALUES ($1,$2,$3,$4,$5)
+	      ON CONFLICT (name) DO UPDATE SET resource = EXCLUDED.resource, action = EXCLUDED.action, description = EXCLUDED.description
+	      RETURNING id`
+	return r.db.QueryRowContext(ctx, q, p.ID, p.Name, p.Resource, p.Action, p.Description).Scan(&p.ID)
+}
+
 func (r *permissionRepository) GetByID(ctx context.Context, id string) (*pgmodel.Permission, error) {
 	var out pgmodel.Permission
 	q := `SELECT id, name, resource, action, description FROM permissions WHERE id=$1`
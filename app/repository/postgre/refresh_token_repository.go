@@ -0,0 +1,72 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	pgmodel "UAS_BACKEND/app/model/postgre"
+)
+
+// RefreshTokenRepository handles the refresh_tokens table.
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, t *pgmodel.RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*pgmodel.RefreshToken, error)
+	// MarkUsed flags the token as rotated. It returns false when the token was already used or
+	// revoked, so two concurrent refreshes with the same token cannot both succeed.
+	MarkUsed(ctx context.Context, id string) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string, reason string) error
+	RevokeUser(ctx context.Context, userID string, reason string) error
+}
+
+type refreshTokenRepository struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, t *pgmodel.RefreshToken) error {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	q := `INSERT INTO refresh_tokens (id, family_id, parent_id, user_id, token_hash, expires_at, created_at)
+	      VALUES ($1,$2,$3,$4,$5,$6,$7)`
+	_, err := r.db.ExecContext(ctx, q, t.ID, t.FamilyID, t.ParentID, t.UserID, t.TokenHash, t.ExpiresAt, t.CreatedAt)
+	return err
+}
+
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*pgmodel.RefreshToken, error) {
+	var t pgmodel.RefreshToken
+	q := `SELECT id, family_id, parent_id, user_id, token_hash, expires_at, used_at, revoked_at, revoked_reason, created_at
+	      FROM refresh_tokens WHERE token_hash=$1`
+	err := r.db.QueryRowContext(ctx, q, tokenHash).Scan(&t.ID, &t.FamilyID, &t.ParentID, &t.UserID, &t.TokenHash,
+		&t.ExpiresAt, &t.UsedAt, &t.RevokedAt, &t.RevokedReason, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *refreshTokenRepository) MarkUsed(ctx context.Context, id string) (bool, error) {
+	q := `UPDATE refresh_tokens SET used_at=now() WHERE id=$1 AND used_at IS NULL AND revoked_at IS NULL`
+	res, err := r.db.ExecContext(ctx, q, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, reason string) error {
+	q := `UPDATE refresh_tokens SET revoked_at=now(), revoked_reason=$2 WHERE family_id=$1 AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, q, familyID, reason)
+	return err
+}
+
+func (r *refreshTokenRepository) RevokeUser(ctx context.Context, userID string, reason string) error {
+	q := `UPDATE refresh_tokens SET revoked_at=now(), revoked_reason=$2 WHERE user_id=$1 AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, q, userID, reason)
+	return err
+}
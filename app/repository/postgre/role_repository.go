@@ -13,9 +13,17 @@ import (
 // -----------------------------
 type RoleRepository interface {
 	Create(ctx context.Context, r *pgmodel.Role) error
+	// Upsert inserts the role or updates the description of the role with the same name;
+	// r.ID and r.CreatedAt are set to the stored values.
+	Upsert(ctx context.Context, r *pgmodel.Role) error
 	GetByID(ctx context.Context, id string) (*pgmodel.Role, error)
 	GetByName(ctx context.Context, name string) (*pgmodel.Role, error)
 	ListAll(ctx context.Context) ([]*pgmodel.Role, error)
+	Update(ctx context.Context, r *pgmodel.Role) error
+	// SetMFARequired returns sql.ErrNoRows when the role does not exist.
+	SetMFARequired(ctx context.Context, id string, required bool) error
+	// Delete removes the role; its role_permissions rows are removed by the foreign key cascade.
+	Delete(ctx context.Context, id string) error
 }
 
 // -----------------------------
@@ -41,16 +49,26 @@ func (r *roleRepository) Create(ctx context.Context, role *pgmodel.Role) error {
 	return err
 }
 
+func (r *roleRepository) Upsert(ctx context.Context, role *pgmodel.Role) error {
+	if role.CreatedAt.IsZero() {
+		role.CreatedAt = time.Now()
+	}
+	q := `INSERT INTO roles (id, name, description, created_at) VALUES ($1,$2,$3,$4)
+	      ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description
+	      RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx, q, role.ID, role.Name, role.Description, role.CreatedAt).Scan(&role.ID, &role.CreatedAt)
+}
+
 
 func (r *roleRepository) GetByID(ctx context.Context, id string) (*pgmodel.Role, error) {
 	query := `
-		SELECT id, name, description, created_at
+		SELECT id, name, description, mfa_required, created_at
 		FROM roles WHERE id=$1
 	`
 	row := r.db.QueryRowContext(ctx, query, id)
 
 	var out pgmodel.Role
-	err := row.Scan(&out.ID, &out.Name, &out.Description, &out.CreatedAt)
+	err := row.Scan(&out.ID, &out.Name, &out.Description, &out.MFARequired, &out.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -60,13 +78,13 @@ func (r *roleRepository) GetByID(ctx context.Context, id string) (*pgmodel.Role,
 
 func (r *roleRepository) GetByName(ctx context.Context, name string) (*pgmodel.Role, error) {
 	query := `
-		SELECT id, name, description, created_at
+		SELECT id, name, description, mfa_required, created_at
 		FROM roles WHERE name=$1
 	`
 	row := r.db.QueryRowContext(ctx, query, name)
 
 	var out pgmodel.Role
-	err := row.Scan(&out.ID, &out.Name, &out.Description, &out.CreatedAt)
+	err := row.Scan(&out.ID, &out.Name, &out.Description, &out.MFARequired, &out.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +94,7 @@ func (r *roleRepository) GetByName(ctx context.Context, name string) (*pgmodel.R
 
 func (r *roleRepository) ListAll(ctx context.Context) ([]*pgmodel.Role, error) {
 	query := `
-		SELECT id, name, description, created_at
+		SELECT id, name, description, mfa_required, created_at
 		FROM roles ORDER BY name
 	`
 	rows, err := r.db.QueryContext(ctx, query)
@@ -88,7 +106,7 @@ func (r *roleRepository) ListAll(ctx context.Context) ([]*pgmodel.Role, error) {
 	var out []*pgmodel.Role
 	for rows.Next() {
 		var r pgmodel.Role
-		err := rows.Scan(&r.ID, &r.Name, &r.Description, &r.CreatedAt)
+		err := rows.Scan(&r.ID, &r.Name, &r.Description, &r.MFARequired, &r.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -96,3 +114,36 @@ func (r *roleRepository) ListAll(ctx context.Context) ([]*pgmodel.Role, error) {
 	}
 	return out, nil
 }
+
+func (r *roleRepository) Update(ctx context.Context, role *pgmodel.Role) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE roles SET name=$1, description=$2 WHERE id=$3`, role.Name, role.Description, role.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *roleRepository) SetMFARequired(ctx context.Context, id string, required bool) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE roles SET mfa_required=$1 WHERE id=$2`, required, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *roleRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM roles WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
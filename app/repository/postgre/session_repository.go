@@ -0,0 +1,112 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	pgmodel "UAS_BACKEND/app/model/postgre"
+)
+
+// SessionRepository handles the sessions table.
+type SessionRepository interface {
+	Create(ctx context.Context, s *pgmodel.Session) error
+	GetByID(ctx context.Context, id string) (*pgmodel.Session, error)
+	// ListActiveByUser returns the sessions that are not revoked and still hold an unused, unexpired
+	// refresh token (sessions.id is the token family), most recently seen first.
+	ListActiveByUser(ctx context.Context, userID string) ([]*pgmodel.Session, error)
+	// Touch sets last_seen_at (and ip when not empty) unless it was updated less than minAge ago.
+	Touch(ctx context.Context, id string, ip string, minAge time.Duration) error
+	// Revoke returns false when the session does not exist or is already revoked.
+	Revoke(ctx context.Context, id string, reason string) (bool, error)
+	RevokeUser(ctx context.Context, userID string, reason string) (int64, error)
+}
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+const sessionColumns = `id, user_id, device, ip, user_agent, created_at, last_seen_at, revoked_at, revoked_reason`
+
+func (r *sessionRepository) Create(ctx context.Context, s *pgmodel.Session) error {
+	now := time.Now()
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = now
+	}
+	if s.LastSeenAt.IsZero() {
+		s.LastSeenAt = s.CreatedAt
+	}
+	q := `INSERT INTO sessions (id, user_id, device, ip, user_agent, created_at, last_seen_at)
+	      VALUES ($1,$2,$3,$4,$5,$6,$7)`
+	_, err := r.db.ExecContext(ctx, q, s.ID, s.UserID, s.Device, s.IP, s.UserAgent, s.CreatedAt, s.LastSeenAt)
+	return err
+}
+
+func (r *sessionRepository) GetByID(ctx context.Context, id string) (*pgmodel.Session, error) {
+	list, err := r.query(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id=$1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return list[0], nil
+}
+
+func (r *sessionRepository) ListActiveByUser(ctx context.Context, userID string) ([]*pgmodel.Session, error) {
+	q := `SELECT ` + sessionColumns + ` FROM sessions s
+	      WHERE s.user_id=$1 AND s.revoked_at IS NULL
+	        AND EXISTS (SELECT 1 FROM refresh_tokens t WHERE t.family_id = s.id
+	                    AND t.used_at IS NULL AND t.revoked_at IS NULL AND t.expires_at > now())
+	      ORDER BY s.last_seen_at DESC`
+	return r.query(ctx, q, userID)
+}
+
+func (r *sessionRepository) Touch(ctx context.Context, id string, ip string, minAge time.Duration) error {
+	q := `UPDATE sessions SET last_seen_at=now(), ip=COALESCE(NULLIF($2, ''), ip)
+	      WHERE id=$1 AND revoked_at IS NULL AND last_seen_at < now() - make_interval(secs => $3)`
+	_, err := r.db.ExecContext(ctx, q, id, ip, minAge.Seconds())
+	return err
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, id string, reason string) (bool, error) {
+	q := `UPDATE sessions SET revoked_at=now(), revoked_reason=$2 WHERE id=$1 AND revoked_at IS NULL`
+	res, err := r.db.ExecContext(ctx, q, id, reason)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *sessionRepository) RevokeUser(ctx context.Context, userID string, reason string) (int64, error) {
+	q := `UPDATE sessions SET revoked_at=now(), revoked_reason=$2 WHERE user_id=$1 AND revoked_at IS NULL`
+	res, err := r.db.ExecContext(ctx, q, userID, reason)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *sessionRepository) query(ctx context.Context, q string, args ...interface{}) ([]*pgmodel.Session, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*pgmodel.Session{}
+	for rows.Next() {
+		var s pgmodel.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Device, &s.IP, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt,
+			&s.RevokedAt, &s.RevokedReason); err != nil {
+			return nil, err
+		}
+		out = append(out, &s)
+	}
+	return out, rows.Err()
+}
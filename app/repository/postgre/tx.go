@@ -0,0 +1,31 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX is the part of *sql.DB and *sql.Tx the repositories use, so the same
+// repository code can run on the pool or inside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// RunInTx runs fn in a transaction that is committed when fn returns nil and rolled back otherwise.
+// With a nil db, fn runs with a nil tx (repositories then keep using their own connection).
+func RunInTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	if db == nil {
+		return fn(nil)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
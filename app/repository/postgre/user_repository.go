@@ -15,10 +15,17 @@ type UserRepository interface {
 	Create(ctx context.Context, u *pgmodel.User) error
 	GetByID(ctx context.Context, id string) (*pgmodel.User, error)
 	GetByUsername(ctx context.Context, username string) (*pgmodel.User, error)
+	// GetByEmail matches case-insensitively.
+	GetByEmail(ctx context.Context, email string) (*pgmodel.User, error)
+	// Update saves the profile fields; the password only changes through SetPassword.
 	Update(ctx context.Context, u *pgmodel.User) error
 	Delete(ctx context.Context, id string) error
 	ListAll(ctx context.Context) ([]*pgmodel.User, error)
+	ListByRole(ctx context.Context, roleID string) ([]*pgmodel.User, error)
 	UpdateRole(ctx context.Context, userID string, roleID string) error
+	SetPassword(ctx context.Context, userID string, passwordHash string) error
+	// MarkEmailVerified returns false when the user's email is no longer email.
+	MarkEmailVerified(ctx context.Context, userID string, email string) (bool, error)
 }
 
 // ----------------------
@@ -53,7 +60,7 @@ func (r *userRepository) Create(ctx context.Context, u *pgmodel.User) error {
 func (r *userRepository) GetByID(ctx context.Context, id string) (*pgmodel.User, error) {
 	query := `
 		SELECT id, username, email, password_hash, full_name,
-		       role_id, is_active, created_at, updated_at
+		       role_id, is_active, email_verified_at, created_at, updated_at
 		FROM users WHERE id=$1
 	`
 
@@ -62,7 +69,7 @@ func (r *userRepository) GetByID(ctx context.Context, id string) (*pgmodel.User,
 
 	err := row.Scan(
 		&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.FullName,
-		&u.RoleID, &u.IsActive, &u.CreatedAt, &u.UpdatedAt,
+		&u.RoleID, &u.IsActive, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -74,7 +81,7 @@ func (r *userRepository) GetByID(ctx context.Context, id string) (*pgmodel.User,
 func (r *userRepository) GetByUsername(ctx context.Context, username string) (*pgmodel.User, error) {
 	query := `
 		SELECT id, username, email, password_hash, full_name,
-		       role_id, is_active, created_at, updated_at
+		       role_id, is_active, email_verified_at, created_at, updated_at
 		FROM users WHERE username=$1
 	`
 
@@ -83,7 +90,28 @@ func (r *userRepository) GetByUsername(ctx context.Context, username string) (*p
 
 	err := row.Scan(
 		&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.FullName,
-		&u.RoleID, &u.IsActive, &u.CreatedAt, &u.UpdatedAt,
+		&u.RoleID, &u.IsActive, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*pgmodel.User, error) {
+	query := `
+		SELECT id, username, email, password_hash, full_name,
+		       role_id, is_active, email_verified_at, created_at, updated_at
+		FROM users WHERE lower(email)=lower($1)
+	`
+
+	row := r.db.QueryRowContext(ctx, query, email)
+	var u pgmodel.User
+
+	err := row.Scan(
+		&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.FullName,
+		&u.RoleID, &u.IsActive, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -97,12 +125,13 @@ func (r *userRepository) Update(ctx context.Context, u *pgmodel.User) error {
 
 	query := `
 		UPDATE users
-		SET username=$1, email=$2, password_hash=$3, full_name=$4,
-		    role_id=$5, is_active=$6, updated_at=$7
-		WHERE id=$8
+		SET username=$1, email=$2, full_name=$3,
+		    role_id=$4, is_active=$5, updated_at=$6,
+		    email_verified_at = CASE WHEN email = $2 THEN email_verified_at END
+		WHERE id=$7
 	`
 	_, err := r.db.ExecContext(ctx, query,
-		u.Username, u.Email, u.PasswordHash, u.FullName,
+		u.Username, u.Email, u.FullName,
 		u.RoleID, u.IsActive, u.UpdatedAt, u.ID,
 	)
 	return err
@@ -117,7 +146,7 @@ func (r *userRepository) Delete(ctx context.Context, id string) error {
 func (r *userRepository) ListAll(ctx context.Context) ([]*pgmodel.User, error) {
 	query := `
 		SELECT id, username, email, password_hash, full_name,
-		       role_id, is_active, created_at, updated_at
+		       role_id, is_active, email_verified_at, created_at, updated_at
 		FROM users ORDER BY created_at DESC
 	`
 
@@ -132,7 +161,35 @@ func (r *userRepository) ListAll(ctx context.Context) ([]*pgmodel.User, error) {
 		var u pgmodel.User
 		err := rows.Scan(
 			&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.FullName,
-			&u.RoleID, &u.IsActive, &u.CreatedAt, &u.UpdatedAt,
+			&u.RoleID, &u.IsActive, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, &u)
+	}
+	return users, rows.Err()
+}
+
+func (r *userRepository) ListByRole(ctx context.Context, roleID string) ([]*pgmodel.User, error) {
+	query := `
+		SELECT id, username, email, password_hash, full_name,
+		       role_id, is_active, email_verified_at, created_at, updated_at
+		FROM users WHERE role_id=$1 ORDER BY username
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*pgmodel.User{}
+	for rows.Next() {
+		var u pgmodel.User
+		err := rows.Scan(
+			&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.FullName,
+			&u.RoleID, &u.IsActive, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -148,3 +205,25 @@ func (r *userRepository) UpdateRole(ctx context.Context, userID string, roleID s
 	_, err := r.db.ExecContext(ctx, query, roleID, now, userID)
 	return err
 }
+
+func (r *userRepository) SetPassword(ctx context.Context, userID string, passwordHash string) error {
+	query := `UPDATE users SET password_hash=$1, updated_at=now() WHERE id=$2`
+	res, err := r.db.ExecContext(ctx, query, passwordHash, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, userID string, email string) (bool, error) {
+	query := `UPDATE users SET email_verified_at=now() WHERE id=$1 AND email=$2 AND email_verified_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, userID, email)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,79 @@
+package postgre
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	pgmodel "UAS_BACKEND/app/model/postgre"
+)
+
+// UserTokenRepository handles the user_tokens table.
+type UserTokenRepository interface {
+	Create(ctx context.Context, t *pgmodel.UserToken) error
+	// Consume marks the token used and returns it. It returns sql.ErrNoRows when no unused,
+	// unexpired token of that purpose has the hash, so a token works exactly once.
+	Consume(ctx context.Context, purpose string, tokenHash string) (*pgmodel.UserToken, error)
+	// Peek returns the token like Consume does but leaves it unused.
+	Peek(ctx context.Context, purpose string, tokenHash string) (*pgmodel.UserToken, error)
+	// InvalidateUser uses up every open token of the user for purpose.
+	InvalidateUser(ctx context.Context, userID string, purpose string) error
+	// CountRecent counts the tokens of the user for purpose created after since.
+	CountRecent(ctx context.Context, userID string, purpose string, since time.Time) (int, error)
+}
+
+type userTokenRepository struct {
+	db *sql.DB
+}
+
+func NewUserTokenRepository(db *sql.DB) UserTokenRepository {
+	return &userTokenRepository{db: db}
+}
+
+func (r *userTokenRepository) Create(ctx context.Context, t *pgmodel.UserToken) error {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	q := `INSERT INTO user_tokens (id, user_id, purpose, token_hash, email, expires_at, created_at)
+	      VALUES ($1,$2,$3,$4,$5,$6,$7)`
+	_, err := r.db.ExecContext(ctx, q, t.ID, t.UserID, t.Purpose, t.TokenHash, t.Email, t.ExpiresAt, t.CreatedAt)
+	return err
+}
+
+func (r *userTokenRepository) Consume(ctx context.Context, purpose string, tokenHash string) (*pgmodel.UserToken, error) {
+	var t pgmodel.UserToken
+	q := `UPDATE user_tokens SET used_at=now()
+	      WHERE token_hash=$1 AND purpose=$2 AND used_at IS NULL AND expires_at > now()
+	      RETURNING id, user_id, purpose, token_hash, email, expires_at, used_at, created_at`
+	err := r.db.QueryRowContext(ctx, q, tokenHash, purpose).Scan(&t.ID, &t.UserID, &t.Purpose, &t.TokenHash, &t.Email,
+		&t.ExpiresAt, &t.UsedAt, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *userTokenRepository) Peek(ctx context.Context, purpose string, tokenHash string) (*pgmodel.UserToken, error) {
+	var t pgmodel.UserToken
+	q := `SELECT id, user_id, purpose, token_hash, email, expires_at, used_at, created_at FROM user_tokens
+	      WHERE token_hash=$1 AND purpose=$2 AND used_at IS NULL AND expires_at > now()`
+	err := r.db.QueryRowContext(ctx, q, tokenHash, purpose).Scan(&t.ID, &t.UserID, &t.Purpose, &t.TokenHash, &t.Email,
+		&t.ExpiresAt, &t.UsedAt, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *userTokenRepository) InvalidateUser(ctx context.Context, userID string, purpose string) error {
+	q := `UPDATE user_tokens SET used_at=now() WHERE user_id=$1 AND purpose=$2 AND used_at IS NULL`
+	_, err := r.db.ExecContext(ctx, q, userID, purpose)
+	return err
+}
+
+func (r *userTokenRepository) CountRecent(ctx context.Context, userID string, purpose string, since time.Time) (int, error) {
+	var n int
+	q := `SELECT count(*) FROM user_tokens WHERE user_id=$1 AND purpose=$2 AND created_at > $3`
+	err := r.db.QueryRowContext(ctx, q, userID, purpose, since).Scan(&n)
+	return n, err
+}
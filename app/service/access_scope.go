@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	pgModel "UAS_BACKEND/app/model/postgre"
+	pgRepo "UAS_BACKEND/app/repository/postgre"
+)
+
+var (
+	ErrForbidden  = &CustomError{"forbidden", "you are not allowed to access this resource", 403}
+	ErrNotAdvisor = &CustomError{"not_advisor", "only the student's academic advisor can verify or reject this achievement", 403}
+)
+
+// Caller identifies the authenticated user a service call is made on behalf of
+// (taken from the JWT "sub" and "role" claims).
+type Caller struct {
+	UserID string
+	RoleID string
+}
+
+// Viewer is a resolved Caller: which rows of achievement data it may see.
+type Viewer struct {
+	Caller
+	IsAdmin  bool
+	Student  *pgModel.Student  // set when the caller has a student profile
+	Lecturer *pgModel.Lecturer // set when the caller has a lecturer profile
+}
+
+// AccessScope resolves callers into viewers and answers row-level visibility questions.
+type AccessScope struct {
+	studentRepo  pgRepo.StudentRepository
+	lecturerRepo pgRepo.LecturerRepository
+	rbac         *RBACService
+}
+
+func NewAccessScope(studentRepo pgRepo.StudentRepository, lecturerRepo pgRepo.LecturerRepository, rbac *RBACService) *AccessScope {
+	return &AccessScope{
+		studentRepo:  studentRepo,
+		lecturerRepo: lecturerRepo,
+		rbac:         rbac,
+	}
+}
+
+// Resolve loads the caller's profile. Callers that are neither admin, student nor lecturer get ErrForbidden.
+func (a *AccessScope) Resolve(ctx context.Context, caller Caller) (*Viewer, error) {
+	v := &Viewer{Caller: caller}
+
+	if caller.RoleID != "" && a.rbac != nil {
+		ok, err := a.rbac.HasPermissionByRoleID(ctx, caller.RoleID, PermReadAllAchievements)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			v.IsAdmin = true
+			return v, nil
+		}
+	}
+
+	student, err := a.studentRepo.GetByUserID(ctx, caller.UserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if student != nil {
+		v.Student = student
+		return v, nil
+	}
+
+	lecturer, err := a.lecturerRepo.GetByUserID(ctx, caller.UserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if lecturer != nil {
+		v.Lecturer = lecturer
+		return v, nil
+	}
+
+	return nil, ErrForbidden
+}
+
+// CanViewStudent reports whether v may see data belonging to the given students.id.
+func (a *AccessScope) CanViewStudent(ctx context.Context, v *Viewer, studentID string) (bool, error) {
+	switch {
+	case v.IsAdmin:
+		return true, nil
+	case v.Student != nil:
+		return v.Student.ID == studentID, nil
+	case v.Lecturer != nil:
+		st, err := a.studentRepo.GetByID(ctx, studentID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return false, nil
+			}
+			return false, err
+		}
+		return st.AdvisorID != nil && *st.AdvisorID == v.Lecturer.ID, nil
+	}
+	return false, nil
+}
+
+// RequireStudent returns ErrForbidden unless v may see the given student.
+func (a *AccessScope) RequireStudent(ctx context.Context, v *Viewer, studentID string) error {
+	ok, err := a.CanViewStudent(ctx, v, studentID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// VisibleStudentIDs returns the students.id values v may see; nil means "all" (admin).
+func (a *AccessScope) VisibleStudentIDs(ctx context.Context, v *Viewer) ([]string, error) {
+	switch {
+	case v.IsAdmin:
+		return nil, nil
+	case v.Student != nil:
+		return []string{v.Student.ID}, nil
+	case v.Lecturer != nil:
+		advisees, err := a.studentRepo.ListByAdvisor(ctx, v.Lecturer.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, 0, len(advisees))
+		for _, st := range advisees {
+			ids = append(ids, st.ID)
+		}
+		return ids, nil
+	}
+	return []string{}, nil
+}
+
+// restrict narrows a reference filter to what v may see.
+// It returns false when the requested filter can never match for this viewer.
+func (v *Viewer) restrict(f *pgRepo.AchievementRefFilter) bool {
+	switch {
+	case v.IsAdmin:
+		return true
+	case v.Student != nil:
+		for _, id := range f.StudentIDs {
+			if id != v.Student.ID {
+				return false
+			}
+		}
+		f.StudentIDs = []string{v.Student.ID}
+		return true
+	case v.Lecturer != nil:
+		if f.AdvisorID != "" && f.AdvisorID != v.Lecturer.ID {
+			return false
+		}
+		f.AdvisorID = v.Lecturer.ID
+		return true
+	}
+	return false
+}
+
+// HasPermission reports whether the caller's role holds perm.
+func (a *AccessScope) HasPermission(ctx context.Context, caller Caller, perm string) (bool, error) {
+	if caller.RoleID == "" || a.rbac == nil {
+		return false, nil
+	}
+	return a.rbac.HasPermissionByRoleID(ctx, caller.RoleID, perm)
+}
+
+// IsOwner reports whether the caller is the student that owns studentID's data.
+func (a *AccessScope) IsOwner(ctx context.Context, caller Caller, studentID string) (bool, error) {
+	student, err := a.studentRepo.GetByUserID(ctx, caller.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return student.ID == studentID, nil
+}
+
+// CanVerify decides whether caller may verify/reject an achievement of the given student.
+// The caller must be the lecturer assigned as the student's advisor, unless its role holds
+// PermVerifyAnyAchievement. The returned reason is meant for the audit log when access is denied.
+func (a *AccessScope) CanVerify(ctx context.Context, caller Caller, studentID string) (bool, string, error) {
+	if caller.RoleID != "" && a.rbac != nil {
+		ok, err := a.rbac.HasPermissionByRoleID(ctx, caller.RoleID, PermVerifyAnyAchievement)
+		if err != nil {
+			return false, "", err
+		}
+		if ok {
+			return true, "admin_override", nil
+		}
+	}
+
+	lecturer, err := a.lecturerRepo.GetByUserID(ctx, caller.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, "verifier_is_not_lecturer", nil
+		}
+		return false, "", err
+	}
+
+	student, err := a.studentRepo.GetByID(ctx, studentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, "student_not_found", nil
+		}
+		return false, "", err
+	}
+	if student.AdvisorID == nil || *student.AdvisorID != lecturer.ID {
+		return false, "not_assigned_advisor", nil
+	}
+	return true, "advisor", nil
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	pgModel "UAS_BACKEND/app/model/postgre"
+	pgRepo "UAS_BACKEND/app/repository/postgre"
+	"UAS_BACKEND/utils"
+
+	"github.com/google/uuid"
+)
+
+// Token lifetimes, overridable with PASSWORD_RESET_TTL and EMAIL_VERIFY_TTL.
+const (
+	DefaultPasswordResetTTL = 30 * time.Minute
+	DefaultEmailVerifyTTL   = 48 * time.Hour
+	// accountMailsPerHour limits how many reset (or verification) mails one account gets per hour
+	accountMailsPerHour = 3
+	// mailSendTimeout bounds a mail sent in the background after the request has returned
+	mailSendTimeout = 30 * time.Second
+)
+
+var (
+	ErrInvalidResetToken    = &CustomError{"invalid_reset_token", "the reset link is invalid, expired or already used", 400}
+	ErrInvalidVerifyToken   = &CustomError{"invalid_verification_token", "the verification link is invalid, expired or already used", 400}
+	ErrEmailAlreadyVerified = &CustomError{"email_already_verified", "the email address is already verified", 409}
+	ErrTooManyEmails        = &CustomError{"too_many_emails", "too many emails requested, try again later", 429}
+)
+
+// AccountEmailService sends password reset and email verification links. The tokens are
+// random, single-use and expire; only their SHA-256 hash is stored.
+type AccountEmailService struct {
+	userRepo     pgRepo.UserRepository
+	tokenRepo    pgRepo.UserTokenRepository
+	activityRepo pgRepo.ActivityLogRepository
+	auth         *AuthService
+	sessions     *SessionService
+	mailer       utils.Mailer
+	baseURL      string // frontend that serves /reset-password and /verify-email
+	resetTTL     time.Duration
+	verifyTTL    time.Duration
+}
+
+func NewAccountEmailService(userRepo pgRepo.UserRepository, tokenRepo pgRepo.UserTokenRepository, activityRepo pgRepo.ActivityLogRepository,
+	auth *AuthService, sessions *SessionService, mailer utils.Mailer) *AccountEmailService {
+	baseURL := strings.TrimRight(os.Getenv("APP_BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:3000"
+	}
+	if mailer == nil {
+		mailer = utils.LogMailer{}
+	}
+	return &AccountEmailService{
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		activityRepo: activityRepo,
+		auth:         auth,
+		sessions:     sessions,
+		mailer:       mailer,
+		baseURL:      baseURL,
+		resetTTL:     durationEnv("PASSWORD_RESET_TTL", DefaultPasswordResetTTL),
+		verifyTTL:    durationEnv("EMAIL_VERIFY_TTL", DefaultEmailVerifyTTL),
+	}
+}
+
+// ForgotPassword mails a reset link to the account with that email or username. It reports
+// nothing about the account: unknown, disabled and rate limited accounts get no mail and no
+// error, and the mail is sent in the background so the response time is the same.
+func (s *AccountEmailService) ForgotPassword(ctx context.Context, login string, meta pgModel.SessionMeta) error {
+	login = strings.TrimSpace(login)
+	if login == "" {
+		return NewValidationError("login_required", "email or username is required")
+	}
+	var user *pgModel.User
+	var err error
+	if strings.Contains(login, "@") {
+		user, err = s.userRepo.GetByEmail(ctx, login)
+	} else {
+		user, err = s.userRepo.GetByUsername(ctx, login)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+	recent, err := s.tokenRepo.CountRecent(ctx, user.ID, pgModel.TokenPurposePasswordReset, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if recent >= accountMailsPerHour {
+		return nil
+	}
+
+	token, err := s.newToken(ctx, user, pgModel.TokenPurposePasswordReset, s.resetTTL)
+	if err != nil {
+		return err
+	}
+	s.log(ctx, user.ID, "password_reset_requested", map[string]interface{}{"ip": meta.IP, "user_agent": meta.UserAgent})
+
+	msg := utils.MailMessage{
+		To:      user.Email,
+		Subject: "Reset password",
+		Text: fmt.Sprintf("Halo %s,\n\n"+
+			"Kami menerima permintaan reset password untuk akun %s. Buka tautan berikut untuk membuat password baru "+
+			"(berlaku %s, hanya bisa dipakai sekali):\n\n%s\n\n"+
+			"Abaikan email ini jika Anda tidak memintanya; password Anda tidak berubah.\n",
+			user.FullName, user.Username, s.resetTTL, s.link("/reset-password", token)),
+	}
+	s.sendAsync(msg)
+	return nil
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. Every other open reset
+// link stops working and all sessions of the user are ended.
+func (s *AccountEmailService) ResetPassword(ctx context.Context, token, newPassword string, meta pgModel.SessionMeta) error {
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+	// a weak or reused password is refused before the single-use link is spent
+	open, err := s.tokenRepo.Peek(ctx, pgModel.TokenPurposePasswordReset, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.auth.CheckNewPassword(ctx, open.UserID, newPassword); err != nil {
+		return err
+	}
+	t, err := s.tokenRepo.Consume(ctx, pgModel.TokenPurposePasswordReset, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.auth.SetPassword(ctx, t.UserID, newPassword); err != nil {
+		return err
+	}
+	if err := s.tokenRepo.InvalidateUser(ctx, t.UserID, pgModel.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+	// the link arrived at this address, so it is verified as well (no-op if the email changed since)
+	_, _ = s.userRepo.MarkEmailVerified(ctx, t.UserID, t.Email)
+	if _, err := s.sessions.RevokeUser(ctx, t.UserID, SessionPasswordReset); err != nil {
+		return err
+	}
+	s.log(ctx, t.UserID, "password_reset", map[string]interface{}{"ip": meta.IP, "user_agent": meta.UserAgent})
+	return nil
+}
+
+// SendVerification mails a verification link for the current email of user; older links stop
+// working. Nothing is sent when the address is already verified.
+func (s *AccountEmailService) SendVerification(ctx context.Context, user *pgModel.User) error {
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	if err := s.tokenRepo.InvalidateUser(ctx, user.ID, pgModel.TokenPurposeEmailVerify); err != nil {
+		return err
+	}
+	token, err := s.newToken(ctx, user, pgModel.TokenPurposeEmailVerify, s.verifyTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, utils.MailMessage{
+		To:      user.Email,
+		Subject: "Verifikasi email",
+		Text: fmt.Sprintf("Halo %s,\n\n"+
+			"Buka tautan berikut untuk memverifikasi alamat email akun %s (berlaku %s):\n\n%s\n",
+			user.FullName, user.Username, s.verifyTTL, s.link("/verify-email", token)),
+	})
+}
+
+// ResendVerification mails a new verification link to the caller (rate limited).
+func (s *AccountEmailService) ResendVerification(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+	recent, err := s.tokenRepo.CountRecent(ctx, user.ID, pgModel.TokenPurposeEmailVerify, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if recent >= accountMailsPerHour {
+		return ErrTooManyEmails
+	}
+	return s.SendVerification(ctx, user)
+}
+
+// VerifyEmail marks the address the token was sent to as verified. A token sent before the
+// email was changed is rejected.
+func (s *AccountEmailService) VerifyEmail(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrInvalidVerifyToken
+	}
+	t, err := s.tokenRepo.Consume(ctx, pgModel.TokenPurposeEmailVerify, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidVerifyToken
+	}
+	if err != nil {
+		return err
+	}
+	ok, err := s.userRepo.MarkEmailVerified(ctx, t.UserID, t.Email)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidVerifyToken
+	}
+	s.log(ctx, t.UserID, "email_verified", map[string]interface{}{"email": t.Email})
+	return nil
+}
+
+// SendVerificationAsync is SendVerification in the background (e.g. right after an account is
+// created); failures are only logged.
+func (s *AccountEmailService) SendVerificationAsync(user *pgModel.User) {
+	u := *user
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+		if err := s.SendVerification(ctx, &u); err != nil {
+			log.Printf("verification mail for user %s failed: %v", u.ID, err)
+		}
+	}()
+}
+
+func (s *AccountEmailService) sendAsync(msg utils.MailMessage) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			log.Printf("sending %q mail failed: %v", msg.Subject, err)
+		}
+	}()
+}
+
+// newToken stores the hash of a new random token and returns the token itself.
+func (s *AccountEmailService) newToken(ctx context.Context, user *pgModel.User, purpose string, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	err := s.tokenRepo.Create(ctx, &pgModel.UserToken{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *AccountEmailService) link(path, token string) string {
+	return s.baseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// log writes an account event of userID to activity_logs (best-effort).
+func (s *AccountEmailService) log(ctx context.Context, userID, event string, metadata map[string]interface{}) {
+	if s.activityRepo == nil {
+		return
+	}
+	_ = s.activityRepo.Create(ctx, &pgModel.ActivityLog{
+		ID:         uuid.New().String(),
+		EntityType: "user",
+		EntityID:   userID,
+		EventType:  event,
+		ActorID:    &userID,
+		Metadata:   metadata,
+		CreatedAt:  time.Now(),
+	})
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"time"
+
+	mongoModel "UAS_BACKEND/app/model/mongo"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrAttachmentNotFound  = &CustomError{"attachment_not_found", "attachment not found", 404}
+	ErrInvalidDownloadLink = &CustomError{"invalid_download_link", "download link is invalid or has expired", 403}
+)
+
+// DownloadLinkPrefix is the public path signed attachment links point to (no bearer token needed).
+const DownloadLinkPrefix = "/api/v1/downloads/achievements/"
+
+// AttachmentLink is a short-lived URL that downloads one attachment without authentication.
+type AttachmentLink struct {
+	URL       string    `json:"url"` // path relative to the API host
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GetAttachment returns an attachment of an achievement the caller may see (same scoping as GetDetail).
+func (s *AchievementService) GetAttachment(ctx context.Context, caller Caller, refID, attachmentID string) (*mongoModel.Attachment, error) {
+	doc, _, err := s.GetDetail(ctx, caller, refID)
+	if err != nil {
+		return nil, err
+	}
+	return findAttachment(doc, attachmentID)
+}
+
+// CreateAttachmentLink signs a download link for an attachment the caller may see, valid for DOWNLOAD_LINK_TTL.
+func (s *AchievementService) CreateAttachmentLink(ctx context.Context, caller Caller, refID, attachmentID string) (*AttachmentLink, error) {
+	if s.links == nil {
+		return nil, errors.New("download links are not configured")
+	}
+	if _, err := s.GetAttachment(ctx, caller, refID, attachmentID); err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(s.linkTTL).Truncate(time.Second)
+	expires, signature := s.links.Sign(attachmentResource(refID, attachmentID), expiresAt)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("signature", signature)
+	return &AttachmentLink{
+		URL:       DownloadLinkPrefix + url.PathEscape(refID) + "/attachments/" + url.PathEscape(attachmentID) + "?" + q.Encode(),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// GetAttachmentByLink checks a signed download link and returns its attachment. Access was checked
+// when the link was created; the achievement and attachment must still exist.
+func (s *AchievementService) GetAttachmentByLink(ctx context.Context, refID, attachmentID, expires, signature string) (*mongoModel.Attachment, error) {
+	if s.links == nil || s.links.Verify(attachmentResource(refID, attachmentID), expires, signature) != nil {
+		return nil, ErrInvalidDownloadLink
+	}
+	ref, err := s.achievementRefPG.GetByID(ctx, refID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(ref.MongoAchievementID)
+	if err != nil {
+		return nil, errors.New("invalid mongo id stored in reference")
+	}
+	doc, err := s.achievementMongo.GetByID(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	return findAttachment(doc, attachmentID)
+}
+
+func attachmentResource(refID, attachmentID string) string {
+	return "attachment:" + refID + "/" + attachmentID
+}
+
+// findAttachment looks an attachment up by id; legacy attachments without a stored file are not
+// downloadable, nor are files that are not scanned yet or contain malware.
+func findAttachment(doc *mongoModel.Achievement, attachmentID string) (*mongoModel.Attachment, error) {
+	if doc == nil || attachmentID == "" {
+		return nil, ErrAttachmentNotFound
+	}
+	a := attachmentByID(doc.Attachments, attachmentID)
+	if a == nil || a.StorageKey == "" {
+		return nil, ErrAttachmentNotFound
+	}
+	if err := checkAttachmentScans(*a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// attachmentByID returns the attachment with id, or nil.
+func attachmentByID(attachments []mongoModel.Attachment, id string) *mongoModel.Attachment {
+	if id == "" {
+		return nil
+	}
+	for i := range attachments {
+		if attachments[i].ID == id {
+			return &attachments[i]
+		}
+	}
+	return nil
+}
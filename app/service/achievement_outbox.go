@@ -0,0 +1,510 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	mongoModel "UAS_BACKEND/app/model/mongo"
+	pgModel "UAS_BACKEND/app/model/postgre"
+	mongoRepo "UAS_BACKEND/app/repository/mongo"
+	pgRepo "UAS_BACKEND/app/repository/postgre"
+	"UAS_BACKEND/utils"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	driver "go.mongodb.org/mongo-driver/mongo"
+)
+
+// Outbox operations, each one an idempotent change to the Mongo side of an achievement
+const (
+	OutboxCreateDocument    = "create_document"
+	OutboxPatchDocument     = "patch_document"
+	OutboxDeleteDocument    = "soft_delete_document"
+	OutboxInsertRevision    = "insert_revision"
+	OutboxAddAttachment     = "add_attachment"
+	OutboxRemoveAttachment  = "remove_attachment"
+	OutboxReplaceAttachment = "replace_attachment"
+	OutboxScanAttachment    = "set_attachment_scan"
+)
+
+// outboxOperations lists every operation, for queries about any unfinished work of an aggregate.
+var outboxOperations = []string{
+	OutboxCreateDocument, OutboxPatchDocument, OutboxDeleteDocument, OutboxInsertRevision,
+	OutboxAddAttachment, OutboxRemoveAttachment, OutboxReplaceAttachment, OutboxScanAttachment,
+}
+
+const (
+	defaultOutboxMaxAttempts = 10
+	defaultOutboxBatchSize   = 50
+	maxOutboxBackoff         = 10 * time.Minute
+	// outboxClaimLease is how long a claimed entry is hidden from other workers while it is applied
+	outboxClaimLease = 2 * time.Minute
+)
+
+type outboxDocumentPayload struct {
+	Document *mongoModel.Achievement `json:"document"`
+}
+
+type outboxPatchPayload struct {
+	DocumentID string                 `json:"documentId"`
+	Set        map[string]interface{} `json:"set"`
+	Unset      map[string]interface{} `json:"unset,omitempty"`
+}
+
+type outboxDeletePayload struct {
+	DocumentID string `json:"documentId"`
+}
+
+type outboxRevisionPayload struct {
+	Revision *mongoModel.AchievementRevision `json:"revision"`
+}
+
+type outboxAttachmentPayload struct {
+	DocumentID string                `json:"documentId"`
+	Attachment mongoModel.Attachment `json:"attachment"`
+}
+
+// outboxAttachmentRemovalPayload removes (Replacement nil) or replaces an attachment; the file at
+// OldStorageKey is deleted once the document no longer points to it.
+type outboxAttachmentRemovalPayload struct {
+	DocumentID    string                 `json:"documentId"`
+	AttachmentID  string                 `json:"attachmentId"`
+	OldStorageKey string                 `json:"oldStorageKey,omitempty"`
+	Replacement   *mongoModel.Attachment `json:"replacement,omitempty"`
+}
+
+type outboxAttachmentScanPayload struct {
+	DocumentID   string    `json:"documentId"`
+	AttachmentID string    `json:"attachmentId"`
+	StorageKey   string    `json:"storageKey"`
+	Status       string    `json:"status"`
+	Signature    string    `json:"signature,omitempty"`
+	ScannedAt    time.Time `json:"scannedAt"`
+}
+
+// permanentError marks an entry that can never succeed (bad payload); it fails without retries.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+// AchievementOutbox makes achievement writes atomic across both stores. The Postgres change and an
+// outbox entry describing the Mongo change are committed in one transaction; the entry is then applied
+// right away (Dispatch) and, if that fails, retried by Run with exponential backoff until it succeeds
+// or runs out of attempts.
+//
+// An entry that runs out of attempts is marked failed and, because entries of one aggregate are applied
+// in order, holds back every later entry of that achievement. Only a failed create is compensated (the
+// reference is marked deleted, see compensate). Patches, deletes, revisions and attachment changes are
+// not rolled back: the Postgres side already told the user the change was accepted, so undoing it would
+// lose their write. They stay failed, show up in Stats and ListFailed, and are replayed with Retry once
+// the cause is fixed; every operation is idempotent, so replaying is always safe.
+type AchievementOutbox struct {
+	db               *sql.DB
+	repo             pgRepo.OutboxRepository
+	achievementRefPG pgRepo.AchievementRefRepository
+	achievementMongo mongoRepo.AchievementRepository
+	revisionRepo     mongoRepo.AchievementRevisionRepository
+	files            utils.FileStore // blobs of removed or replaced attachments are deleted here
+	MaxAttempts      int
+	BatchSize        int
+}
+
+func NewAchievementOutbox(
+	db *sql.DB,
+	repo pgRepo.OutboxRepository,
+	achievementRefPG pgRepo.AchievementRefRepository,
+	achievementMongo mongoRepo.AchievementRepository,
+	revisionRepo mongoRepo.AchievementRevisionRepository,
+	files utils.FileStore,
+) *AchievementOutbox {
+	return &AchievementOutbox{
+		db:               db,
+		repo:             repo,
+		achievementRefPG: achievementRefPG,
+		achievementMongo: achievementMongo,
+		revisionRepo:     revisionRepo,
+		files:            files,
+		MaxAttempts:      defaultOutboxMaxAttempts,
+		BatchSize:        defaultOutboxBatchSize,
+	}
+}
+
+// Enqueue records op for the achievement reference aggregateID inside tx and returns the entry ID.
+func (o *AchievementOutbox) Enqueue(ctx context.Context, tx *sql.Tx, aggregateID, op string, payload interface{}) (string, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	e := &pgModel.OutboxEntry{
+		ID:          uuid.New().String(),
+		AggregateID: aggregateID,
+		Operation:   op,
+		Payload:     b,
+	}
+	if err := o.repo.WithTx(tx).Enqueue(ctx, e); err != nil {
+		return "", err
+	}
+	return e.ID, nil
+}
+
+// Dispatch applies freshly committed entries immediately. It is best-effort: whatever is not
+// applied here stays pending for the background worker.
+func (o *AchievementOutbox) Dispatch(ctx context.Context, ids ...string) {
+	for _, id := range ids {
+		e, err := o.repo.ClaimByID(ctx, id, outboxClaimLease)
+		if err == nil && e != nil {
+			err = o.handle(ctx, e)
+		}
+		if err != nil {
+			log.Printf("outbox: dispatch %s: %v", id, err)
+		}
+		if err != nil || e == nil {
+			return // later entries of the same aggregate are blocked anyway
+		}
+	}
+}
+
+// ProcessBatch applies up to BatchSize due entries and returns how many were claimed. The claim is
+// committed first, so no transaction stays open while Mongo is called; each entry then records its
+// outcome in a short transaction of its own.
+func (o *AchievementOutbox) ProcessBatch(ctx context.Context) (int, error) {
+	entries, err := o.repo.ClaimPending(ctx, o.BatchSize, outboxClaimLease)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		if err := o.handle(ctx, e); err != nil {
+			return len(entries), err // the remaining claims expire and are picked up again
+		}
+	}
+	return len(entries), nil
+}
+
+// Run polls the outbox every interval until ctx is cancelled. A full batch is followed
+// immediately by the next one so a backlog drains quickly.
+func (o *AchievementOutbox) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := o.ProcessBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("outbox: process batch: %v", err)
+		}
+		if err == nil && n >= o.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stats returns the pending / failed counters for monitoring.
+func (o *AchievementOutbox) Stats(ctx context.Context) (*pgModel.OutboxStats, error) {
+	return o.repo.Stats(ctx)
+}
+
+// ListFailed returns the most recent failed entries.
+func (o *AchievementOutbox) ListFailed(ctx context.Context, limit int) ([]*pgModel.OutboxEntry, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return o.repo.ListFailed(ctx, limit)
+}
+
+// Retry puts a failed entry back into the queue and tries it once right away.
+func (o *AchievementOutbox) Retry(ctx context.Context, id string) error {
+	ok, err := o.repo.Requeue(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &CustomError{"resource_not_found", "no failed outbox entry with this id", 404}
+	}
+	o.Dispatch(ctx, id)
+	return nil
+}
+
+// handle applies one claimed entry and records the outcome; a failure is marked together with its
+// compensation in one transaction. Only errors of the bookkeeping itself are returned.
+func (o *AchievementOutbox) handle(ctx context.Context, e *pgModel.OutboxEntry) error {
+	// finish well within the lease so no other worker applies the entry at the same time
+	applyCtx, cancel := context.WithTimeout(ctx, outboxClaimLease/2)
+	applyErr := o.apply(applyCtx, e)
+	cancel()
+	if applyErr == nil {
+		return o.repo.MarkDone(ctx, e.ID)
+	}
+
+	attempts := e.Attempts + 1
+	var perm *permanentError
+	if !errors.As(applyErr, &perm) && attempts < o.MaxAttempts {
+		return o.repo.MarkRetry(ctx, e.ID, attempts, applyErr.Error(), time.Now().Add(outboxBackoff(attempts)))
+	}
+
+	log.Printf("outbox: entry %s (%s, aggregate %s) failed after %d attempts: %v", e.ID, e.Operation, e.AggregateID, attempts, applyErr)
+	return pgRepo.RunInTx(ctx, o.db, func(tx *sql.Tx) error {
+		if err := o.compensate(ctx, tx, e); err != nil {
+			return err
+		}
+		return o.repo.WithTx(tx).MarkFailed(ctx, e.ID, attempts, applyErr.Error())
+	})
+}
+
+// apply performs the Mongo side of an entry. Every operation is safe to replay.
+func (o *AchievementOutbox) apply(ctx context.Context, e *pgModel.OutboxEntry) error {
+	switch e.Operation {
+	case OutboxCreateDocument:
+		var p outboxDocumentPayload
+		if err := decodeOutboxPayload(e, &p); err != nil {
+			return err
+		}
+		if p.Document == nil {
+			return &permanentError{errors.New("create_document without document")}
+		}
+		return o.achievementMongo.CreateIfAbsent(ctx, p.Document)
+
+	case OutboxPatchDocument:
+		var p outboxPatchPayload
+		if err := decodeOutboxPayload(e, &p); err != nil {
+			return err
+		}
+		oid, err := outboxObjectID(p.DocumentID)
+		if err != nil {
+			return err
+		}
+		doc, err := o.achievementMongo.ApplyPatch(ctx, oid, p.Set, p.Unset)
+		if err != nil {
+			return err
+		}
+		if doc == nil {
+			return fmt.Errorf("achievement document %s not found", p.DocumentID)
+		}
+		return nil
+
+	case OutboxDeleteDocument:
+		var p outboxDeletePayload
+		if err := decodeOutboxPayload(e, &p); err != nil {
+			return err
+		}
+		oid, err := outboxObjectID(p.DocumentID)
+		if err != nil {
+			return err
+		}
+		if err := o.achievementMongo.SoftDelete(ctx, oid); err != nil && !errors.Is(err, driver.ErrNoDocuments) {
+			return err
+		}
+		return nil
+
+	case OutboxInsertRevision:
+		var p outboxRevisionPayload
+		if err := decodeOutboxPayload(e, &p); err != nil {
+			return err
+		}
+		if p.Revision == nil || p.Revision.ID.IsZero() {
+			return &permanentError{errors.New("insert_revision without revision id")}
+		}
+		_, err := o.revisionRepo.Create(ctx, p.Revision)
+		if err == nil || !driver.IsDuplicateKeyError(err) {
+			return err
+		}
+		// a replay of this entry is fine; another revision with the same number is not
+		existing, gerr := o.revisionRepo.GetByNumber(ctx, p.Revision.ReferenceID, p.Revision.Number)
+		if gerr != nil {
+			return gerr
+		}
+		if existing != nil && existing.ID == p.Revision.ID {
+			return nil
+		}
+		return &permanentError{fmt.Errorf("revision %d of %s already exists: %w", p.Revision.Number, p.Revision.ReferenceID, err)}
+
+	case OutboxAddAttachment:
+		var p outboxAttachmentPayload
+		if err := decodeOutboxPayload(e, &p); err != nil {
+			return err
+		}
+		oid, err := outboxObjectID(p.DocumentID)
+		if err != nil {
+			return err
+		}
+		return o.achievementMongo.AddAttachment(ctx, oid, p.Attachment)
+
+	case OutboxRemoveAttachment, OutboxReplaceAttachment:
+		var p outboxAttachmentRemovalPayload
+		if err := decodeOutboxPayload(e, &p); err != nil {
+			return err
+		}
+		oid, err := outboxObjectID(p.DocumentID)
+		if err != nil {
+			return err
+		}
+		if p.Replacement == nil {
+			err = o.achievementMongo.RemoveAttachment(ctx, oid, p.AttachmentID)
+		} else {
+			err = o.achievementMongo.ReplaceAttachment(ctx, oid, *p.Replacement)
+			if errors.Is(err, driver.ErrNoDocuments) {
+				// the attachment is gone, so nothing points to the new file either
+				err = o.deleteUnreferencedFile(ctx, e.AggregateID, p.Replacement.StorageKey)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return o.deleteUnreferencedFile(ctx, e.AggregateID, p.OldStorageKey)
+
+	case OutboxScanAttachment:
+		var p outboxAttachmentScanPayload
+		if err := decodeOutboxPayload(e, &p); err != nil {
+			return err
+		}
+		oid, err := outboxObjectID(p.DocumentID)
+		if err != nil {
+			return err
+		}
+		return o.achievementMongo.SetAttachmentScan(ctx, oid, p.AttachmentID, p.StorageKey, p.Status, p.Signature, p.ScannedAt)
+	}
+	return &permanentError{fmt.Errorf("unknown outbox operation %q", e.Operation)}
+}
+
+// pendingAttachments returns attachments as they will be once the unfinished attachment entries of
+// aggregateID are applied. Entries already applied to Mongo but not yet marked done change nothing.
+func (o *AchievementOutbox) pendingAttachments(ctx context.Context, tx *sql.Tx, aggregateID string, attachments []mongoModel.Attachment) ([]mongoModel.Attachment, error) {
+	entries, err := o.repo.WithTx(tx).ListUnfinished(ctx, aggregateID,
+		[]string{OutboxAddAttachment, OutboxRemoveAttachment, OutboxReplaceAttachment})
+	if err != nil {
+		return nil, err
+	}
+	out := append([]mongoModel.Attachment(nil), attachments...)
+	index := func(id string) int {
+		for i := range out {
+			if id != "" && out[i].ID == id {
+				return i
+			}
+		}
+		return -1
+	}
+	for _, e := range entries {
+		if e.Operation == OutboxAddAttachment {
+			var p outboxAttachmentPayload
+			if err := decodeOutboxPayload(e, &p); err != nil {
+				return nil, err
+			}
+			if index(p.Attachment.ID) < 0 {
+				out = append(out, p.Attachment)
+			}
+			continue
+		}
+		var p outboxAttachmentRemovalPayload
+		if err := decodeOutboxPayload(e, &p); err != nil {
+			return nil, err
+		}
+		i := index(p.AttachmentID)
+		switch {
+		case i < 0:
+		case p.Replacement == nil:
+			out = append(out[:i], out[i+1:]...)
+		default:
+			out[i] = *p.Replacement
+		}
+	}
+	return out, nil
+}
+
+// queuedChanges reports whether aggregateID has unfinished entries other than scan verdicts, i.e.
+// whether the Mongo document is still behind the changes committed in Postgres.
+func (o *AchievementOutbox) queuedChanges(ctx context.Context, tx *sql.Tx, aggregateID string) (bool, error) {
+	entries, err := o.repo.WithTx(tx).ListUnfinished(ctx, aggregateID, []string{
+		OutboxCreateDocument, OutboxPatchDocument, OutboxDeleteDocument, OutboxInsertRevision,
+		OutboxAddAttachment, OutboxRemoveAttachment, OutboxReplaceAttachment,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(entries) > 0, nil
+}
+
+// queuedScans returns the storage keys of aggregateID whose scan verdict is queued but not applied yet.
+func (o *AchievementOutbox) queuedScans(ctx context.Context, aggregateID string) (map[string]bool, error) {
+	entries, err := o.repo.ListUnfinished(ctx, aggregateID, []string{OutboxScanAttachment})
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		var p outboxAttachmentScanPayload
+		if err := decodeOutboxPayload(e, &p); err != nil {
+			return nil, err
+		}
+		out[p.StorageKey] = true
+	}
+	return out, nil
+}
+
+// deleteUnreferencedFile removes a blob the document no longer uses, unless a submitted revision
+// of the achievement still shows it (revisions are the audit trail and stay downloadable).
+func (o *AchievementOutbox) deleteUnreferencedFile(ctx context.Context, refID, key string) error {
+	if key == "" || o.files == nil {
+		return nil // legacy attachment without a FileStore key
+	}
+	revisions, err := o.revisionRepo.ListByReference(ctx, refID)
+	if err != nil {
+		return err
+	}
+	for _, rev := range revisions {
+		for _, a := range rev.Snapshot.Attachments {
+			if a.StorageKey == key {
+				return nil
+			}
+		}
+	}
+	return o.files.Delete(ctx, key)
+}
+
+// compensate undoes the Postgres side of an entry that will never be applied. Only a failed create
+// needs it: the reference would point at a document that does not exist, so it is marked deleted
+// and any partially written document is soft deleted. Other operations are left for Retry (see
+// AchievementOutbox).
+func (o *AchievementOutbox) compensate(ctx context.Context, tx *sql.Tx, e *pgModel.OutboxEntry) error {
+	if e.Operation != OutboxCreateDocument {
+		return nil
+	}
+	var p outboxDocumentPayload
+	if err := json.Unmarshal(e.Payload, &p); err == nil && p.Document != nil && !p.Document.ID.IsZero() {
+		_ = o.achievementMongo.SoftDelete(ctx, p.Document.ID)
+	}
+	return o.achievementRefPG.WithTx(tx).UpdateStatus(ctx, e.AggregateID, StatusDeleted, nil)
+}
+
+func decodeOutboxPayload(e *pgModel.OutboxEntry, v interface{}) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return &permanentError{fmt.Errorf("invalid %s payload: %w", e.Operation, err)}
+	}
+	return nil
+}
+
+func outboxObjectID(hex string) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return primitive.NilObjectID, &permanentError{errors.New("invalid mongo object id")}
+	}
+	return oid, nil
+}
+
+// outboxBackoff grows exponentially from 2s and is capped at maxOutboxBackoff.
+func outboxBackoff(attempts int) time.Duration {
+	if attempts > 10 {
+		return maxOutboxBackoff
+	}
+	d := time.Duration(1<<uint(attempts)) * time.Second
+	if d > maxOutboxBackoff {
+		return maxOutboxBackoff
+	}
+	return d
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	mongoModel "UAS_BACKEND/app/model/mongo"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// achievementPatchFields lists the top-level Achievement fields a student may change
+// through PUT /achievements/:id, together with the JSON kind each one accepts.
+var achievementPatchFields = map[string]string{
+	"title":    "string",
+	"type":     "string",
+	"category": "string",
+	"level":    "string",
+	"tags":     "string_array",
+	"details":  "object",
+}
+
+// achievementProtectedFields are document fields that exist but can never be patched.
+var achievementProtectedFields = map[string]bool{
+	"id":          true,
+	"_id":         true,
+	"studentId":   true,
+	"attachments": true,
+	"createdAt":   true,
+	"updatedAt":   true,
+	"deletedAt":   true,
+}
+
+// achievementPatch is a JSON Merge Patch (RFC 7396) translated into Mongo $set / $unset fields.
+// Details keeps the nested patch for "details"; detailOps turns it into dotted paths.
+type achievementPatch struct {
+	Set     map[string]interface{}
+	Unset   map[string]interface{}
+	Details map[string]interface{}
+}
+
+func (p *achievementPatch) empty() bool {
+	return len(p.Set) == 0 && len(p.Unset) == 0 && p.Details == nil
+}
+
+// preview returns a copy of current with the top-level $set / $unset applied,
+// so the merged document can be validated before it is written.
+func (p *achievementPatch) preview(current *mongoModel.Achievement) *mongoModel.Achievement {
+	out := *current
+	for field, value := range p.Set {
+		switch field {
+		case "title":
+			out.Title = value.(string)
+		case "type":
+			out.Type = value.(string)
+		case "category":
+			out.Category = value.(string)
+		case "level":
+			out.Level = value.(string)
+		case "tags":
+			out.Tags = value.([]string)
+		case "details":
+			out.Details, _ = asObject(value)
+		}
+	}
+	if _, ok := p.Unset["tags"]; ok {
+		out.Tags = nil
+	}
+	if p.Details != nil {
+		out.Details, _ = asObject(applyMergePatch(current.Details, p.Details))
+	}
+	return &out
+}
+
+// detailOps adds the nested patch for "details" to Set / Unset as dotted paths ("details.rank"),
+// so Mongo merges it into the stored value in outbox order and concurrent patches of different
+// members do not overwrite each other. current is the stored details; it only decides where a
+// member is not an object yet and has to be set as a whole.
+func (p *achievementPatch) detailOps(current interface{}) {
+	if p.Details == nil {
+		return
+	}
+	target, ok := asObject(current)
+	if !ok {
+		p.Set["details"] = applyMergePatch(nil, p.Details)
+		return
+	}
+	p.addMergeOps("details", target, p.Details)
+}
+
+func (p *achievementPatch) addMergeOps(prefix string, target, patch map[string]interface{}) {
+	for key, value := range patch {
+		path := prefix + "." + key
+		if value == nil {
+			p.Unset[path] = ""
+			continue
+		}
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			p.Set[path] = value
+			continue
+		}
+		if existing, ok := asObject(target[key]); ok {
+			p.addMergeOps(path, existing, nested)
+		} else {
+			p.Set[path] = applyMergePatch(nil, nested)
+		}
+	}
+}
+
+// parseAchievementPatch validates a merge patch document against the field allowlist
+// and converts it into update operators. Arrays replace the previous value and null
+// removes the member; "details" is merged member by member (see detailOps).
+func parseAchievementPatch(raw map[string]interface{}) (*achievementPatch, error) {
+	if len(raw) == 0 {
+		return nil, NewValidationError("invalid_patch", "patch document is empty")
+	}
+	p := &achievementPatch{
+		Set:   map[string]interface{}{},
+		Unset: map[string]interface{}{},
+	}
+
+	for field, value := range raw {
+		if achievementProtectedFields[field] {
+			return nil, NewValidationError("field_not_allowed", fmt.Sprintf("field %q cannot be modified", field))
+		}
+		kind, ok := achievementPatchFields[field]
+		if !ok {
+			return nil, NewValidationError("unknown_field", fmt.Sprintf("unknown field %q", field))
+		}
+
+		switch kind {
+		case "string":
+			if value == nil {
+				return nil, NewValidationError("invalid_value", fmt.Sprintf("field %q cannot be removed", field))
+			}
+			str, ok := value.(string)
+			if !ok {
+				return nil, NewValidationError("invalid_type", fmt.Sprintf("field %q must be a string", field))
+			}
+			if strings.TrimSpace(str) == "" {
+				return nil, NewValidationError("invalid_value", fmt.Sprintf("field %q cannot be empty", field))
+			}
+			p.Set[field] = str
+
+		case "string_array":
+			if value == nil {
+				p.Unset[field] = ""
+				continue
+			}
+			items, ok := value.([]interface{})
+			if !ok {
+				return nil, NewValidationError("invalid_type", fmt.Sprintf("field %q must be an array of strings", field))
+			}
+			tags := make([]string, 0, len(items))
+			for _, it := range items {
+				str, ok := it.(string)
+				if !ok {
+					return nil, NewValidationError("invalid_type", fmt.Sprintf("field %q must be an array of strings", field))
+				}
+				tags = append(tags, str)
+			}
+			p.Set[field] = tags
+
+		case "object":
+			if value == nil {
+				p.Set[field] = map[string]interface{}{}
+				continue
+			}
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, NewValidationError("invalid_type", fmt.Sprintf("field %q must be an object", field))
+			}
+			if err := validateMergeKeys(field, obj); err != nil {
+				return nil, err
+			}
+			p.Details = obj
+		}
+	}
+
+	if p.empty() {
+		return nil, NewValidationError("invalid_patch", "patch document has no effect")
+	}
+	return p, nil
+}
+
+// validateMergeKeys rejects keys Mongo cannot store (empty, dotted or $-prefixed).
+func validateMergeKeys(prefix string, obj map[string]interface{}) error {
+	for key, value := range obj {
+		if key == "" || strings.ContainsAny(key, ".$") {
+			return NewValidationError("invalid_key", fmt.Sprintf("invalid key %q in %s", key, prefix))
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			if err := validateMergeKeys(prefix+"."+key, nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyMergePatch implements the MergePatch algorithm from RFC 7396 section 2.
+func applyMergePatch(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := asObject(target)
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	out := make(map[string]interface{}, len(targetObj))
+	for k, v := range targetObj {
+		out[k] = v
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		out[k] = applyMergePatch(out[k], v)
+	}
+	return out
+}
+
+// asObject normalises the shapes an embedded document can take after decoding from Mongo.
+func asObject(v interface{}) (map[string]interface{}, bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return t, true
+	case primitive.M:
+		return t, true
+	case primitive.D:
+		return t.Map(), true
+	}
+	return nil, false
+}
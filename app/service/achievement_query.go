@@ -0,0 +1,190 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	pgModel "UAS_BACKEND/app/model/postgre"
+	mongoRepo "UAS_BACKEND/app/repository/mongo"
+	pgRepo "UAS_BACKEND/app/repository/postgre"
+)
+
+const (
+	defaultAchievementPageSize = 20
+	maxAchievementPageSize     = 100
+
+	// maxContentFilterMatches bounds how many Mongo documents a content filter (type, level,
+	// category, tag, search) may match; their ids are passed to Postgres as one array.
+	maxContentFilterMatches = 5000
+)
+
+// ErrQueryTooBroad is returned when a content filter matches more than maxContentFilterMatches documents.
+var ErrQueryTooBroad = &CustomError{"query_too_broad", fmt.Sprintf("the search matches more than %d achievements, narrow it down", maxContentFilterMatches), 422}
+
+// AchievementQuery holds the raw query parameters of GET /achievements.
+// Dates accept YYYY-MM-DD (the "to" bound then covers the whole day) or RFC 3339.
+type AchievementQuery struct {
+	Status    string // comma separated, e.g. "submitted,verified"
+	StudentID string
+	AdvisorID string
+	Type      string
+	Level     string
+	Category  string
+	Tag       string
+	Search    string
+
+	SubmittedFrom string
+	SubmittedTo   string
+	VerifiedFrom  string
+	VerifiedTo    string
+
+	Sort   string // column name, prefix with "-" for descending (default "-created_at")
+	Page   int
+	Limit  int
+	Cursor string // opaque value from AchievementPage.NextCursor
+}
+
+// AchievementPage is one page of achievement references.
+type AchievementPage struct {
+	Items      []*pgModel.AchievementReference `json:"items"`
+	Total      int                             `json:"total"`
+	Page       int                             `json:"page,omitempty"`
+	Limit      int                             `json:"limit"`
+	TotalPages int                             `json:"total_pages"`
+	NextCursor string                          `json:"next_cursor,omitempty"`
+}
+
+type achievementCursor struct {
+	Value time.Time `json:"v"`
+	ID    string    `json:"id"`
+}
+
+// keysetSortFields are the sort columns that are never NULL and therefore support cursors.
+var keysetSortFields = map[string]bool{"created_at": true, "updated_at": true}
+
+var achievementStatuses = map[string]bool{
+	"draft": true, "submitted": true, "verified": true, "rejected": true, "revision": true, "deleted": true,
+}
+
+// defaultListStatuses are listed when no status filter is given: deleted achievements only show up
+// when asked for explicitly.
+var defaultListStatuses = []string{StatusDraft, StatusSubmitted, StatusVerified, StatusRejected, StatusRevision}
+
+// toFilters validates q and splits it into the Postgres and Mongo parts.
+func (q AchievementQuery) toFilters() (pgRepo.AchievementRefFilter, mongoRepo.AchievementFilter, error) {
+	var f pgRepo.AchievementRefFilter
+	var err error
+
+	if q.Status != "" {
+		for _, st := range strings.Split(q.Status, ",") {
+			st = strings.TrimSpace(st)
+			if !achievementStatuses[st] {
+				return f, mongoRepo.AchievementFilter{}, NewValidationError("invalid_query", fmt.Sprintf("unknown status %q", st))
+			}
+			f.Statuses = append(f.Statuses, st)
+		}
+	} else {
+		f.Statuses = defaultListStatuses
+	}
+	if q.StudentID != "" {
+		f.StudentIDs = []string{q.StudentID}
+	}
+	f.AdvisorID = q.AdvisorID
+
+	if f.SubmittedFrom, err = parseQueryDate("submitted_from", q.SubmittedFrom, false); err != nil {
+		return f, mongoRepo.AchievementFilter{}, err
+	}
+	if f.SubmittedTo, err = parseQueryDate("submitted_to", q.SubmittedTo, true); err != nil {
+		return f, mongoRepo.AchievementFilter{}, err
+	}
+	if f.VerifiedFrom, err = parseQueryDate("verified_from", q.VerifiedFrom, false); err != nil {
+		return f, mongoRepo.AchievementFilter{}, err
+	}
+	if f.VerifiedTo, err = parseQueryDate("verified_to", q.VerifiedTo, true); err != nil {
+		return f, mongoRepo.AchievementFilter{}, err
+	}
+
+	sort := q.Sort
+	if sort == "" {
+		sort = "-created_at"
+	}
+	f.SortDesc = strings.HasPrefix(sort, "-")
+	f.SortField = strings.TrimPrefix(sort, "-")
+	switch f.SortField {
+	case "created_at", "updated_at", "submitted_at", "verified_at", "status":
+	default:
+		return f, mongoRepo.AchievementFilter{}, NewValidationError("invalid_query", fmt.Sprintf("cannot sort by %q", f.SortField))
+	}
+
+	f.Limit = q.Limit
+	if f.Limit <= 0 {
+		f.Limit = defaultAchievementPageSize
+	}
+	if f.Limit > maxAchievementPageSize {
+		f.Limit = maxAchievementPageSize
+	}
+
+	if q.Cursor != "" {
+		if !keysetSortFields[f.SortField] {
+			return f, mongoRepo.AchievementFilter{}, NewValidationError("invalid_query", "cursor pagination only supports sort by created_at or updated_at")
+		}
+		cur, err := decodeAchievementCursor(q.Cursor)
+		if err != nil {
+			return f, mongoRepo.AchievementFilter{}, err
+		}
+		f.AfterValue = &cur.Value
+		f.AfterID = cur.ID
+	} else if q.Page > 1 {
+		f.Offset = (q.Page - 1) * f.Limit
+	}
+
+	mf := mongoRepo.AchievementFilter{
+		Type:     q.Type,
+		Level:    q.Level,
+		Category: q.Category,
+		Tag:      q.Tag,
+		Text:     strings.TrimSpace(q.Search),
+	}
+	return f, mf, nil
+}
+
+func parseQueryDate(name, value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, NewValidationError("invalid_query", fmt.Sprintf("%s must be YYYY-MM-DD or RFC 3339", name))
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+func encodeAchievementCursor(ref *pgModel.AchievementReference, sortField string) string {
+	v := ref.CreatedAt
+	if sortField == "updated_at" {
+		v = ref.UpdatedAt
+	}
+	b, _ := json.Marshal(achievementCursor{Value: v, ID: ref.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeAchievementCursor(s string) (*achievementCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, NewValidationError("invalid_query", "invalid cursor")
+	}
+	var cur achievementCursor
+	if err := json.Unmarshal(b, &cur); err != nil || cur.ID == "" {
+		return nil, NewValidationError("invalid_query", "invalid cursor")
+	}
+	return &cur, nil
+}
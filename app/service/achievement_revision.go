@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	mongoModel "UAS_BACKEND/app/model/mongo"
+	pgModel "UAS_BACKEND/app/model/postgre"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RevisionChange is one field that differs between two revisions.
+type RevisionChange struct {
+	Path string      `json:"path"` // e.g. "title", "details.rank"
+	Op   string      `json:"op"`   // added, removed, changed
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// RevisionDiff compares two revisions of the same achievement.
+type RevisionDiff struct {
+	ReferenceID      string                        `json:"reference_id"`
+	From             int                           `json:"from"`
+	To               int                           `json:"to"`
+	AnswersRejection *mongoModel.RevisionRejection `json:"answers_rejection,omitempty"`
+	Changes          []RevisionChange              `json:"changes"`
+}
+
+// revisionIgnoredFields are document metadata that change on every save and are left out of diffs.
+var revisionIgnoredFields = map[string]bool{"id": true, "createdAt": true, "updatedAt": true, "deletedAt": true}
+
+// createRevision snapshots doc as the next revision of ref and queues its insert in w. The number
+// comes from achievement_references.revision_count on the row locked by w, not from Mongo, where the
+// previous revision may still be queued. A submission coming out of "revision" status is linked to
+// the rejection of the previous revision.
+func (s *AchievementService) createRevision(ctx context.Context, w *achievementWrite, ref *pgModel.AchievementReference, doc *mongoModel.Achievement, userID string, at time.Time) (*mongoModel.AchievementRevision, error) {
+	// revisions written before revision_count existed are only known to Mongo
+	latest, err := s.revisionRepo.GetLatest(ctx, ref.ID)
+	if err != nil {
+		return nil, err
+	}
+	stored := 0
+	if latest != nil {
+		stored = latest.Number
+	}
+	number, err := w.refs.NextRevisionNumber(ctx, ref.ID, stored)
+	if err != nil {
+		return nil, err
+	}
+
+	rev := &mongoModel.AchievementRevision{
+		ID:            primitive.NewObjectID(), // preset so a replayed insert is recognised as a duplicate
+		ReferenceID:   ref.ID,
+		AchievementID: doc.ID,
+		Number:        number,
+		Snapshot:      *doc,
+		SubmittedBy:   userID,
+		SubmittedAt:   at,
+	}
+	if ref.Status == StatusRevision && number > 1 {
+		note := ""
+		if ref.RejectionNote != nil {
+			note = *ref.RejectionNote
+		}
+		rev.AnswersRejection = &mongoModel.RevisionRejection{
+			RevisionNumber: number - 1,
+			Note:           note,
+		}
+	}
+
+	if err := w.enqueue(ctx, ref.ID, OutboxInsertRevision, outboxRevisionPayload{Revision: rev}); err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// visibleReference loads a reference and checks that caller may see it.
+func (s *AchievementService) visibleReference(ctx context.Context, caller Caller, refID string) (*pgModel.AchievementReference, error) {
+	viewer, err := s.access.Resolve(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := s.achievementRefPG.GetByID(ctx, refID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := s.access.RequireStudent(ctx, viewer, ref.StudentID); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// ListRevisions returns every submitted revision of an achievement, oldest first.
+func (s *AchievementService) ListRevisions(ctx context.Context, caller Caller, refID string) ([]*mongoModel.AchievementRevision, error) {
+	if _, err := s.visibleReference(ctx, caller, refID); err != nil {
+		return nil, err
+	}
+	return s.revisionRepo.ListByReference(ctx, refID)
+}
+
+// GetRevision returns a single revision by number.
+func (s *AchievementService) GetRevision(ctx context.Context, caller Caller, refID string, number int) (*mongoModel.AchievementRevision, error) {
+	if _, err := s.visibleReference(ctx, caller, refID); err != nil {
+		return nil, err
+	}
+	rev, err := s.revisionRepo.GetByNumber(ctx, refID, number)
+	if err != nil {
+		return nil, err
+	}
+	if rev == nil {
+		return nil, ErrNotFound
+	}
+	return rev, nil
+}
+
+// DiffRevisions compares revision from against revision to. When to is 0 the latest revision is used;
+// when from is 0 it defaults to the revision the latest one answers (the rejected one), or to-1.
+func (s *AchievementService) DiffRevisions(ctx context.Context, caller Caller, refID string, from, to int) (*RevisionDiff, error) {
+	if _, err := s.visibleReference(ctx, caller, refID); err != nil {
+		return nil, err
+	}
+
+	var toRev *mongoModel.AchievementRevision
+	var err error
+	if to > 0 {
+		toRev, err = s.revisionRepo.GetByNumber(ctx, refID, to)
+	} else {
+		toRev, err = s.revisionRepo.GetLatest(ctx, refID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if toRev == nil {
+		return nil, ErrNotFound
+	}
+
+	if from <= 0 {
+		if toRev.AnswersRejection != nil {
+			from = toRev.AnswersRejection.RevisionNumber
+		} else {
+			from = toRev.Number - 1
+		}
+	}
+	if from <= 0 || from == toRev.Number {
+		return nil, NewValidationError("invalid_revision", "there is no earlier revision to compare with")
+	}
+	fromRev, err := s.revisionRepo.GetByNumber(ctx, refID, from)
+	if err != nil {
+		return nil, err
+	}
+	if fromRev == nil {
+		return nil, &CustomError{"resource_not_found", fmt.Sprintf("revision %d not found", from), 404}
+	}
+
+	changes, err := diffAchievements(&fromRev.Snapshot, &toRev.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &RevisionDiff{
+		ReferenceID:      refID,
+		From:             fromRev.Number,
+		To:               toRev.Number,
+		AnswersRejection: toRev.AnswersRejection,
+		Changes:          changes,
+	}, nil
+}
+
+// diffAchievements flattens both documents (objects become dotted paths, arrays are compared whole)
+// and lists the paths whose value differs.
+func diffAchievements(a, b *mongoModel.Achievement) ([]RevisionChange, error) {
+	left, err := flattenAchievement(a)
+	if err != nil {
+		return nil, err
+	}
+	right, err := flattenAchievement(b)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]bool, len(left)+len(right))
+	for k := range left {
+		paths[k] = true
+	}
+	for k := range right {
+		paths[k] = true
+	}
+	keys := make([]string, 0, len(paths))
+	for k := range paths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	changes := []RevisionChange{}
+	for _, k := range keys {
+		lv, inLeft := left[k]
+		rv, inRight := right[k]
+		switch {
+		case inLeft && !inRight:
+			changes = append(changes, RevisionChange{Path: k, Op: "removed", From: lv})
+		case !inLeft && inRight:
+			changes = append(changes, RevisionChange{Path: k, Op: "added", To: rv})
+		case !reflect.DeepEqual(lv, rv):
+			changes = append(changes, RevisionChange{Path: k, Op: "changed", From: lv, To: rv})
+		}
+	}
+	return changes, nil
+}
+
+func flattenAchievement(a *mongoModel.Achievement) (map[string]interface{}, error) {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	out := map[string]interface{}{}
+	for k, v := range doc {
+		if revisionIgnoredFields[k] {
+			continue
+		}
+		flattenInto(out, k, v)
+	}
+	return out, nil
+}
+
+func flattenInto(out map[string]interface{}, prefix string, v interface{}) {
+	obj, ok := v.(map[string]interface{})
+	if !ok || len(obj) == 0 {
+		if v != nil {
+			out[prefix] = v
+		}
+		return
+	}
+	for k, child := range obj {
+		flattenInto(out, prefix+"."+k, child)
+	}
+}
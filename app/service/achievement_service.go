@@ -1,14 +1,20 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"errors"
+	"io"
 	"time"
 
 	mongoModel "UAS_BACKEND/app/model/mongo"
 	pgModel "UAS_BACKEND/app/model/postgre"
 	mongoRepo "UAS_BACKEND/app/repository/mongo"
 	pgRepo "UAS_BACKEND/app/repository/postgre"
+	"UAS_BACKEND/utils"
 
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -21,6 +27,17 @@ type AchievementService struct {
 	studentRepo      pgRepo.StudentRepository
 	userRepo         pgRepo.UserRepository
 	activityRepo     pgRepo.ActivityLogRepository
+	typeSvc          *AchievementTypeService
+	access           *AccessScope
+	revisionRepo     mongoRepo.AchievementRevisionRepository
+	db               *sql.DB
+	outbox           *AchievementOutbox
+	files            utils.FileStore   // attachment blobs
+	links            *utils.LinkSigner // signs attachment download links
+	linkTTL          time.Duration
+	attachmentPolicy AttachmentPolicy
+	scanner          utils.Scanner
+	machine          *achievementStateMachine
 }
 
 // NewAchievementService creates an instance of AchievementService.
@@ -31,14 +48,33 @@ func NewAchievementService(
 	studentRepo pgRepo.StudentRepository,
 	userRepo pgRepo.UserRepository,
 	activityRepo pgRepo.ActivityLogRepository,
+	typeSvc *AchievementTypeService,
+	access *AccessScope,
+	revisionRepo mongoRepo.AchievementRevisionRepository,
+	db *sql.DB,
+	outbox *AchievementOutbox,
+	files utils.FileStore,
+	links *utils.LinkSigner,
 ) *AchievementService {
-	return &AchievementService{
+	s := &AchievementService{
 		achievementMongo: achievementMongo,
 		achievementRefPG: achievementRefPG,
 		studentRepo:      studentRepo,
 		userRepo:         userRepo,
 		activityRepo:     activityRepo,
-	}
+		typeSvc:          typeSvc,
+		access:           access,
+		revisionRepo:     revisionRepo,
+		db:               db,
+		outbox:           outbox,
+		files:            files,
+		links:            links,
+		linkTTL:          durationEnv("DOWNLOAD_LINK_TTL", 15*time.Minute),
+		attachmentPolicy: DefaultAttachmentPolicy,
+		scanner:          utils.NoopScanner{},
+	}
+	s.machine = newAchievementStateMachine(s)
+	return s
 }
 
 // helper: create activity log best-effort
@@ -50,6 +86,52 @@ func (s *AchievementService) writeActivityLog(ctx context.Context, logEntry *pgM
 	_ = s.activityRepo.Create(ctx, logEntry)
 }
 
+// achievementWrite is one atomic achievement mutation: Postgres changes go through refs (bound to tx)
+// and Mongo changes are recorded as outbox entries in the same transaction.
+type achievementWrite struct {
+	svc     *AchievementService
+	tx      *sql.Tx
+	refs    pgRepo.AchievementRefRepository
+	entries []string
+}
+
+func (w *achievementWrite) enqueue(ctx context.Context, aggregateID, op string, payload interface{}) error {
+	id, err := w.svc.outbox.Enqueue(ctx, w.tx, aggregateID, op, payload)
+	if err != nil {
+		return err
+	}
+	w.entries = append(w.entries, id)
+	return nil
+}
+
+// lockRef re-reads a reference inside the transaction and locks its row, so concurrent writes to
+// the same achievement run one after the other and see each other's status.
+func (w *achievementWrite) lockRef(ctx context.Context, refID string) (*pgModel.AchievementReference, error) {
+	ref, err := w.refs.GetByIDForUpdate(ctx, refID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return ref, nil
+}
+
+// write runs fn in one Postgres transaction and, once committed, applies the queued Mongo changes.
+func (s *AchievementService) write(ctx context.Context, fn func(w *achievementWrite) error) error {
+	w := &achievementWrite{svc: s}
+	err := pgRepo.RunInTx(ctx, s.db, func(tx *sql.Tx) error {
+		w.tx = tx
+		w.refs = s.achievementRefPG.WithTx(tx)
+		return fn(w)
+	})
+	if err != nil {
+		return err
+	}
+	s.outbox.Dispatch(ctx, w.entries...)
+	return nil
+}
+
 // CreateDraft saves achievement doc to Mongo and creates a reference row in Postgres (status=draft)
 func (s *AchievementService) CreateDraft(ctx context.Context, userID string, doc *mongoModel.Achievement) (*pgModel.AchievementReference, error) {
 	// 1. validate student
@@ -61,26 +143,34 @@ func (s *AchievementService) CreateDraft(ctx context.Context, userID string, doc
 		return nil, errors.New("student profile not found")
 	}
 
-	// 2. save to mongo
-	doc.StudentID = student.ID
-	oid, err := s.achievementMongo.Create(ctx, doc)
-	if err != nil {
+	// 2. validate details against the type schema (draft rules)
+	if err := s.typeSvc.Validate(ctx, doc, false); err != nil {
 		return nil, err
 	}
 
-	// 3. create reference in postgres
+	// 3. reference row and the Mongo insert (via outbox) in one transaction
+	now := time.Now()
+	doc.ID = primitive.NewObjectID()
+	doc.StudentID = student.ID
+	doc.CreatedAt = now
+	doc.UpdatedAt = now
+
 	ref := &pgModel.AchievementReference{
 		ID:                 uuid.New().String(),
 		StudentID:          student.ID,
-		MongoAchievementID: oid.Hex(),
-		Status:             "draft",
-		CreatedAt:          time.Now(),
-		UpdatedAt:          time.Now(),
-	}
-
-	if err := s.achievementRefPG.Create(ctx, ref); err != nil {
-		// cleanup mongo doc best-effort
-		_ = s.achievementMongo.SoftDelete(ctx, oid)
+		MongoAchievementID: doc.ID.Hex(),
+		Status:             StatusDraft,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+
+	err = s.write(ctx, func(w *achievementWrite) error {
+		if err := w.refs.Create(ctx, ref); err != nil {
+			return err
+		}
+		return w.enqueue(ctx, ref.ID, OutboxCreateDocument, outboxDocumentPayload{Document: doc})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -103,329 +193,542 @@ func (s *AchievementService) CreateDraft(ctx context.Context, userID string, doc
 	return ref, nil
 }
 
-// Submit transitions draft -> submitted
-func (s *AchievementService) Submit(ctx context.Context, refID string, userID string) error {
-	// validate student
-	student, err := s.studentRepo.GetByUserID(ctx, userID)
+// loadReference fetches a reference row, mapping a missing row to ErrNotFound.
+func (s *AchievementService) loadReference(ctx context.Context, refID string) (*pgModel.AchievementReference, error) {
+	ref, err := s.achievementRefPG.GetByID(ctx, refID)
 	if err != nil {
-		return err
-	}
-	if student == nil {
-		return errors.New("student not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
 	}
+	return ref, nil
+}
 
-	// get reference
-	ref, err := s.achievementRefPG.GetByID(ctx, refID)
+// loadDocument fetches the Mongo document a reference points to.
+func (s *AchievementService) loadDocument(ctx context.Context, ref *pgModel.AchievementReference) (*mongoModel.Achievement, error) {
+	oid, err := primitive.ObjectIDFromHex(ref.MongoAchievementID)
 	if err != nil {
-		return err
+		return nil, errors.New("invalid mongo object id")
 	}
-	if ref == nil {
-		return errors.New("achievement reference not found")
+	doc, err := s.achievementMongo.GetByID(ctx, oid)
+	if err != nil {
+		return nil, err
 	}
-	if ref.StudentID != student.ID {
-		return errors.New("not owner")
+	if doc == nil {
+		return nil, ErrNotFound
 	}
-	if ref.Status != "draft" {
-		return errors.New("invalid status transition: only draft can be submitted")
+	return doc, nil
+}
+
+// fire loads the reference and runs a state machine transition on it.
+func (s *AchievementService) fire(ctx context.Context, name, refID string, caller Caller, note string) error {
+	ref, err := s.loadReference(ctx, refID)
+	if err != nil {
+		return err
 	}
+	return s.machine.Fire(ctx, name, &transitionContext{caller: caller, ref: ref, note: note, now: time.Now()})
+}
 
-	// update status
-	now := time.Now()
-	ref.SubmittedAt = &now
-	if err := s.achievementRefPG.UpdateStatus(ctx, refID, "submitted", nil); err != nil {
+// Submit transitions draft -> submitted (or revision -> submitted for a resubmission)
+// and stores an immutable revision snapshot of the document being submitted.
+func (s *AchievementService) Submit(ctx context.Context, refID string, caller Caller) error {
+	return s.fire(ctx, TransitionSubmit, refID, caller, "")
+}
+
+// StartRevision moves a rejected achievement back into editing (rejected -> revision). Owner only.
+func (s *AchievementService) StartRevision(ctx context.Context, refID string, caller Caller) error {
+	return s.fire(ctx, TransitionRevise, refID, caller, "")
+}
+
+// authorizeVerifier checks that caller is the student's advisor (or holds the admin override)
+// before a verify/reject. Denied attempts are written to the activity log.
+func (s *AchievementService) authorizeVerifier(ctx context.Context, caller Caller, ref *pgModel.AchievementReference, action string) error {
+	ok, reason, err := s.access.CanVerify(ctx, caller, ref.StudentID)
+	if err != nil {
 		return err
 	}
+	if ok {
+		return nil
+	}
 
-	// activity log
 	logEntry := &pgModel.ActivityLog{
 		ID:         uuid.New().String(),
 		EntityType: "achievement_reference",
 		EntityID:   ref.ID,
-		EventType:  "status_changed",
-		ActorID:    &userID,
-		Previous:   map[string]interface{}{"status": "draft"},
-		Current:    map[string]interface{}{"status": "submitted", "submitted_at": now},
+		EventType:  "verification_denied",
+		ActorID:    &caller.UserID,
+		ActorRole:  &caller.RoleID,
+		Current:    map[string]interface{}{"status": ref.Status},
+		Metadata:   map[string]interface{}{"action": action, "reason": reason, "student_id": ref.StudentID},
 		CreatedAt:  time.Now(),
 	}
 	s.writeActivityLog(ctx, logEntry)
-	return nil
+	return ErrNotAdvisor
+}
+
+// Verify transitions submitted -> verified (student's advisor only, or admin override)
+func (s *AchievementService) Verify(ctx context.Context, refID string, caller Caller) error {
+	return s.fire(ctx, TransitionVerify, refID, caller, "")
+}
+
+// Reject transitions submitted -> rejected with a note (student's advisor only, or admin override)
+func (s *AchievementService) Reject(ctx context.Context, refID string, caller Caller, note string) error {
+	return s.fire(ctx, TransitionReject, refID, caller, note)
+}
+
+// DeleteDraft soft deletes the Mongo document and marks the reference as deleted (owner only, draft only)
+func (s *AchievementService) DeleteDraft(ctx context.Context, refID string, caller Caller) error {
+	return s.fire(ctx, TransitionDelete, refID, caller, "")
 }
 
-// Verify transitions submitted -> verified
-func (s *AchievementService) Verify(ctx context.Context, refID string, verifierUserID string) error {
-	// verifier existence check
-	verifier, err := s.userRepo.GetByID(ctx, verifierUserID)
+// AllowedTransitions lists the transitions caller may perform next on ref.
+func (s *AchievementService) AllowedTransitions(ctx context.Context, caller Caller, ref *pgModel.AchievementReference) ([]string, error) {
+	return s.machine.Allowed(ctx, caller, ref)
+}
+
+// GetDetail returns both Mongo document and Postgres reference, if the caller may see the student
+func (s *AchievementService) GetDetail(ctx context.Context, caller Caller, refID string) (*mongoModel.Achievement, *pgModel.AchievementReference, error) {
+	viewer, err := s.access.Resolve(ctx, caller)
 	if err != nil {
-		return err
-	}
-	if verifier == nil {
-		return errors.New("verifier user not found")
+		return nil, nil, err
 	}
-
-	// get reference
 	ref, err := s.achievementRefPG.GetByID(ctx, refID)
 	if err != nil {
-		return err
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
 	}
-	if ref == nil {
-		return errors.New("achievement reference not found")
+	if err := s.access.RequireStudent(ctx, viewer, ref.StudentID); err != nil {
+		return nil, nil, err
 	}
-	if ref.Status != "submitted" {
-		return errors.New("only submitted achievements can be verified")
+	// the document may be soft-deleted or not written yet (outbox); that is a 404, not an empty 200
+	ach, err := s.loadDocument(ctx, ref)
+	if err != nil {
+		return nil, nil, err
 	}
+	return ach, ref, nil
+}
 
-	// update status in db (use UpdateStatus which sets verified_by & verified_at when provided)
-	if err := s.achievementRefPG.UpdateStatus(ctx, refID, "verified", &verifierUserID); err != nil {
-		return err
+// ListByStudent returns all achievements for a student the caller may see
+func (s *AchievementService) ListByStudent(ctx context.Context, caller Caller, studentID string) ([]*pgModel.AchievementReference, error) {
+	viewer, err := s.access.Resolve(ctx, caller)
+	if err != nil {
+		return nil, err
 	}
-
-	// activity log
-	now := time.Now()
-	logEntry := &pgModel.ActivityLog{
-		ID:         uuid.New().String(),
-		EntityType: "achievement_reference",
-		EntityID:   ref.ID,
-		EventType:  "status_changed",
-		ActorID:    &verifierUserID,
-		ActorRole:  nil, // optional: you can fetch role name if needed
-		Previous:   map[string]interface{}{"status": "submitted"},
-		Current:    map[string]interface{}{"status": "verified", "verified_at": now, "verified_by": verifierUserID},
-		CreatedAt:  time.Now(),
+	if err := s.access.RequireStudent(ctx, viewer, studentID); err != nil {
+		return nil, err
 	}
-	s.writeActivityLog(ctx, logEntry)
-	return nil
+	return s.achievementRefPG.ListByStudent(ctx, studentID)
 }
 
-// Reject sets status to rejected and saves rejection note
-func (s *AchievementService) Reject(ctx context.Context, refID string, verifierUserID string, note string) error {
-	// verifier existence check
-	verifier, err := s.userRepo.GetByID(ctx, verifierUserID)
+// GetAllAchievements returns one page of achievement references matching q, limited to what the caller may see.
+// Content filters (type, level, category, tag, search) are resolved in Mongo first,
+// everything else including sorting, counting and pagination runs in Postgres.
+func (s *AchievementService) GetAllAchievements(ctx context.Context, caller Caller, q AchievementQuery) (*AchievementPage, error) {
+	filter, docFilter, err := q.toFilters()
 	if err != nil {
-		return err
-	}
-	if verifier == nil {
-		return errors.New("verifier user not found")
+		return nil, err
 	}
 
-	// get reference
-	ref, err := s.achievementRefPG.GetByID(ctx, refID)
+	viewer, err := s.access.Resolve(ctx, caller)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if ref == nil {
-		return errors.New("achievement reference not found")
+	if !viewer.restrict(&filter) {
+		return &AchievementPage{Items: []*pgModel.AchievementReference{}, Page: 1, Limit: filter.Limit}, nil
 	}
-	if ref.Status != "submitted" {
-		return errors.New("only submitted achievements can be rejected")
+
+	if !docFilter.IsEmpty() {
+		docFilter.StudentIDs = filter.StudentIDs
+		oids, err := s.achievementMongo.FindIDs(ctx, docFilter, maxContentFilterMatches+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(oids) > maxContentFilterMatches {
+			return nil, ErrQueryTooBroad
+		}
+		filter.MongoIDs = make([]string, 0, len(oids))
+		for _, oid := range oids {
+			filter.MongoIDs = append(filter.MongoIDs, oid.Hex())
+		}
 	}
 
-	// update rejection note and status
-	if err := s.achievementRefPG.UpdateRejectionNote(ctx, refID, note); err != nil {
-		return err
+	items, total, err := s.achievementRefPG.Search(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	// activity log
-	now := time.Now()
-	logEntry := &pgModel.ActivityLog{
-		ID:         uuid.New().String(),
-		EntityType: "achievement_reference",
-		EntityID:   ref.ID,
-		EventType:  "status_changed",
-		ActorID:    &verifierUserID,
-		Previous:   map[string]interface{}{"status": "submitted"},
-		Current:    map[string]interface{}{"status": "rejected", "rejection_note": note, "rejected_at": now},
-		CreatedAt:  time.Now(),
+	page := &AchievementPage{
+		Items: items,
+		Total: total,
+		Limit: filter.Limit,
 	}
-	s.writeActivityLog(ctx, logEntry)
-	return nil
+	page.TotalPages = (total + filter.Limit - 1) / filter.Limit
+	if q.Cursor == "" {
+		page.Page = filter.Offset/filter.Limit + 1
+	}
+	if len(items) == filter.Limit && keysetSortFields[filter.SortField] {
+		page.NextCursor = encodeAchievementCursor(items[len(items)-1], filter.SortField)
+	}
+	return page, nil
 }
 
-// DeleteDraft: soft delete in Mongo + update reference in Postgres to 'deleted' (only owner, only draft)
-func (s *AchievementService) DeleteDraft(ctx context.Context, refID string, userID string) error {
-	// validate student
-	student, err := s.studentRepo.GetByUserID(ctx, userID)
+// UpdateDraft applies a JSON Merge Patch (RFC 7396) to the Mongo document of a draft owned by the caller.
+// It returns the merged document together with its reference row.
+func (s *AchievementService) UpdateDraft(ctx context.Context, refID string, userID string, rawPatch map[string]interface{}) (*mongoModel.Achievement, *pgModel.AchievementReference, error) {
+	patch, err := parseAchievementPatch(rawPatch)
 	if err != nil {
-		return err
-	}
-
-	if err := s.achievementRefPG.Delete(ctx, refID); err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	if student == nil {
-		return errors.New("student not found")
+	// validate student
+	student, err := s.studentRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrNotOwner
+		}
+		return nil, nil, err
 	}
 
 	// get reference
 	ref, err := s.achievementRefPG.GetByID(ctx, refID)
 	if err != nil {
-		return err
-	}
-	if ref == nil {
-		return errors.New("achievement reference not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
 	}
 	if ref.StudentID != student.ID {
-		return errors.New("not owner")
+		return nil, nil, ErrNotOwner
 	}
-	if ref.Status != "draft" {
-		return errors.New("only draft achievements can be deleted")
+	if !isEditable(ref.Status) {
+		return nil, nil, &CustomError{"invalid_transition", "only draft or revision achievements can be updated", 409}
 	}
 
-	// soft delete mongo doc
 	oid, err := primitive.ObjectIDFromHex(ref.MongoAchievementID)
 	if err != nil {
-		// still update postgres to deleted for safety
-		_ = s.achievementRefPG.UpdateStatus(ctx, refID, "deleted", nil)
-		return errors.New("invalid mongo object id")
+		return nil, nil, errors.New("invalid mongo object id")
+	}
+	current, err := s.achievementMongo.GetByID(ctx, oid)
+	if err != nil {
+		return nil, nil, err
 	}
-	if err := s.achievementMongo.SoftDelete(ctx, oid); err != nil {
-		// continue to update postgres anyway (best-effort)
+	if current == nil {
+		return nil, nil, ErrNotFound
 	}
 
-	// update postgres reference status to deleted
-	if err := s.achievementRefPG.UpdateStatus(ctx, refID, "deleted", nil); err != nil {
-		return err
+	// details is sent as dotted paths; Mongo merges them so nested members keep RFC 7396 semantics
+	patch.detailOps(current.Details)
+	if err := s.typeSvc.Validate(ctx, patch.preview(current), false); err != nil {
+		return nil, nil, err
+	}
+
+	// Update Timestamp Postgres + queue the Mongo patch
+	err = s.write(ctx, func(w *achievementWrite) error {
+		// a submit may have won the race since the status check above
+		locked, err := w.lockRef(ctx, ref.ID)
+		if err != nil {
+			return err
+		}
+		if !isEditable(locked.Status) {
+			return &CustomError{"invalid_transition", "only draft or revision achievements can be updated", 409}
+		}
+		*ref = *locked
+		if err := w.refs.Update(ctx, ref); err != nil {
+			return err
+		}
+		return w.enqueue(ctx, ref.ID, OutboxPatchDocument, outboxPatchPayload{
+			DocumentID: ref.MongoAchievementID,
+			Set:        patch.Set,
+			Unset:      patch.Unset,
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the patch is normally applied by now; otherwise answer with what the document will become
+	updated := patch.preview(current)
+	if doc, err := s.achievementMongo.GetByID(ctx, oid); err == nil && doc != nil {
+		updated = doc
 	}
 
 	// activity log
-	now := time.Now()
 	logEntry := &pgModel.ActivityLog{
 		ID:         uuid.New().String(),
 		EntityType: "achievement_reference",
 		EntityID:   ref.ID,
-		EventType:  "deleted",
+		EventType:  "updated",
 		ActorID:    &userID,
-		Previous:   map[string]interface{}{"status": "draft"},
-		Current:    map[string]interface{}{"status": "deleted", "deleted_at": now},
+		Previous:   achievementFields(current, rawPatch),
+		Current:    achievementFields(updated, rawPatch),
 		CreatedAt:  time.Now(),
 	}
 	s.writeActivityLog(ctx, logEntry)
-	return nil
+
+	return updated, ref, nil
 }
 
-// GetDetail returns both Mongo document and Postgres reference
-func (s *AchievementService) GetDetail(ctx context.Context, refID string) (*mongoModel.Achievement, *pgModel.AchievementReference, error) {
-	ref, err := s.achievementRefPG.GetByID(ctx, refID)
-	if err != nil {
-		return nil, nil, err
-	}
-	if ref == nil {
-		return nil, nil, errors.New("reference not found")
-	}
-	oid, err := primitive.ObjectIDFromHex(ref.MongoAchievementID)
-	if err != nil {
-		return nil, nil, errors.New("invalid mongo id stored in reference")
-	}
-	ach, err := s.achievementMongo.GetByID(ctx, oid)
-	if err != nil {
-		return nil, nil, err
-	}
-	return ach, ref, nil
+// achievementFields picks the patched top-level fields from a document for activity logs.
+func achievementFields(a *mongoModel.Achievement, patch map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(patch))
+	for field := range patch {
+		switch field {
+		case "title":
+			out[field] = a.Title
+		case "type":
+			out[field] = a.Type
+		case "category":
+			out[field] = a.Category
+		case "level":
+			out[field] = a.Level
+		case "tags":
+			out[field] = a.Tags
+		case "details":
+			out[field] = a.Details
+		}
+	}
+	return out
 }
 
-// ListByStudent returns all achievements for a student
-func (s *AchievementService) ListByStudent(ctx context.Context, studentID string) ([]*pgModel.AchievementReference, error) {
-	return s.achievementRefPG.ListByStudent(ctx, studentID)
+// AttachmentUpload is a file received for an achievement; Body yields Size bytes. FileName and
+// ContentType come from the client and are not trusted.
+type AttachmentUpload struct {
+	FileName    string
+	ContentType string
+	Size        int64
+	Body        io.Reader
 }
 
-func (s *AchievementService) GetAllAchievements(ctx context.Context, filters map[string]interface{}) ([]*pgModel.AchievementReference, error) {
-	// Saat ini repo hanya punya ListAll tanpa filter dinamis,
-	// Anda bisa update repository untuk menerima filter, atau ambil semua dulu (jika data sedikit).
-	return s.achievementRefPG.ListAll(ctx)
+// SetAttachmentPolicy changes the upload limits (see DefaultAttachmentPolicy).
+func (s *AchievementService) SetAttachmentPolicy(policy AttachmentPolicy) {
+	s.attachmentPolicy = policy
 }
 
-func (s *AchievementService) UpdateDraft(ctx context.Context, refID string, userID string, updates map[string]interface{}) error {
-	// validate student
-	student, err := s.studentRepo.GetByUserID(ctx, userID)
+// Method baru untuk handle logika attachment.
+// Tipe file ditentukan dari isinya (magic bytes), file disimpan lewat FileStore dengan key acak;
+// bila transaksi gagal, file dihapus lagi.
+func (s *AchievementService) AddAttachment(ctx context.Context, refID string, userID string, upload AttachmentUpload) (*mongoModel.Attachment, error) {
+	ref, doc, err := s.editableAttachments(ctx, refID, userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if student == nil {
-		return errors.New("student not found")
+
+	// Batas ukuran dan jumlah (per file dan per prestasi); dicek dulu sebelum file disimpan
+	if err := s.attachmentPolicy.check(upload.Size, doc.Attachments, ""); err != nil {
+		return nil, err
 	}
 
-	// get reference
-	ref, err := s.achievementRefPG.GetByID(ctx, refID)
+	attachment, err := s.storeUpload(ctx, ref, uuid.New().String(), upload)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if ref == nil {
-		return errors.New("achievement reference not found")
+
+	// Update MongoDB (via outbox, reference updated_at in the same transaction)
+	err = s.write(ctx, func(w *achievementWrite) error {
+		// dicek lagi dengan lock: upload paralel dan perubahan yang masih antre di outbox ikut dihitung
+		locked, attachments, err := w.lockAttachments(ctx, ref.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.attachmentPolicy.check(upload.Size, attachments, ""); err != nil {
+			return err
+		}
+		*ref = *locked
+		if err := w.refs.Update(ctx, ref); err != nil {
+			return err
+		}
+		return w.enqueue(ctx, ref.ID, OutboxAddAttachment, outboxAttachmentPayload{
+			DocumentID: ref.MongoAchievementID,
+			Attachment: *attachment,
+		})
+	})
+	if err != nil {
+		// the file is not referenced by anything, remove it again
+		_ = s.files.Delete(context.Background(), attachment.StorageKey)
+		return nil, err
 	}
-	if ref.StudentID != student.ID {
-		return errors.New("not owner")
+	s.scanAttachmentAsync(ref.ID, ref.MongoAchievementID, *attachment)
+	return attachment, nil
+}
+
+// ReplaceAttachment swaps the file of an attachment, keeping its id and position. The old file is
+// deleted by the outbox once Mongo points to the new one.
+func (s *AchievementService) ReplaceAttachment(ctx context.Context, refID, attachmentID, userID string, upload AttachmentUpload) (*mongoModel.Attachment, error) {
+	ref, doc, err := s.editableAttachments(ctx, refID, userID)
+	if err != nil {
+		return nil, err
 	}
-	if ref.Status != "draft" {
-		return errors.New("only draft achievements can be updated")
+	old := attachmentByID(doc.Attachments, attachmentID)
+	if old == nil {
+		return nil, ErrAttachmentNotFound
 	}
 
-	// update MongoDB document
-	oid, err := primitive.ObjectIDFromHex(ref.MongoAchievementID)
-	if err != nil {
-		return errors.New("invalid mongo object id")
+	// the replaced file does not count towards the limits
+	if err := s.attachmentPolicy.check(upload.Size, doc.Attachments, attachmentID); err != nil {
+		return nil, err
 	}
 
-	// Update MongoDB
-	if err := s.achievementMongo.Update(ctx, oid, updates); err != nil {
-		return err
+	attachment, err := s.storeUpload(ctx, ref, attachmentID, upload)
+	if err != nil {
+		return nil, err
 	}
+	err = s.write(ctx, func(w *achievementWrite) error {
+		// re-check under the lock; a replace still queued in the outbox decides which file is old
+		locked, attachments, err := w.lockAttachments(ctx, ref.ID)
+		if err != nil {
+			return err
+		}
+		old = attachmentByID(attachments, attachmentID)
+		if old == nil {
+			return ErrAttachmentNotFound
+		}
+		if err := s.attachmentPolicy.check(upload.Size, attachments, attachmentID); err != nil {
+			return err
+		}
+		*ref = *locked
+		if err := w.refs.Update(ctx, ref); err != nil {
+			return err
+		}
+		return w.enqueue(ctx, ref.ID, OutboxReplaceAttachment, outboxAttachmentRemovalPayload{
+			DocumentID:    ref.MongoAchievementID,
+			AttachmentID:  attachmentID,
+			OldStorageKey: old.StorageKey,
+			Replacement:   attachment,
+		})
+	})
+	if err != nil {
+		_ = s.files.Delete(context.Background(), attachment.StorageKey)
+		return nil, err
+	}
+	s.scanAttachmentAsync(ref.ID, ref.MongoAchievementID, *attachment)
+	return attachment, nil
+}
 
-	// Update Timestamp Postgres
-	ref.UpdatedAt = time.Now()
-	if err := s.achievementRefPG.Update(ctx, ref); err != nil {
+// DeleteAttachment removes an attachment from a draft (or revision); its file is deleted by the
+// outbox after the Mongo update, so a failure on either side is retried instead of leaving a
+// document that points to a missing file.
+func (s *AchievementService) DeleteAttachment(ctx context.Context, refID, attachmentID, userID string) error {
+	ref, doc, err := s.editableAttachments(ctx, refID, userID)
+	if err != nil {
 		return err
 	}
+	if attachmentByID(doc.Attachments, attachmentID) == nil {
+		return ErrAttachmentNotFound
+	}
+	return s.write(ctx, func(w *achievementWrite) error {
+		// a replace still queued in the outbox decides which file is deleted
+		locked, attachments, err := w.lockAttachments(ctx, ref.ID)
+		if err != nil {
+			return err
+		}
+		old := attachmentByID(attachments, attachmentID)
+		if old == nil {
+			return ErrAttachmentNotFound
+		}
+		*ref = *locked
+		if err := w.refs.Update(ctx, ref); err != nil {
+			return err
+		}
+		return w.enqueue(ctx, ref.ID, OutboxRemoveAttachment, outboxAttachmentRemovalPayload{
+			DocumentID:    ref.MongoAchievementID,
+			AttachmentID:  attachmentID,
+			OldStorageKey: old.StorageKey,
+		})
+	})
+}
 
-	// activity log
-	logEntry := &pgModel.ActivityLog{
-		ID:         uuid.New().String(),
-		EntityType: "achievement_reference",
-		EntityID:   ref.ID,
-		EventType:  "updated",
-		ActorID:    &userID,
-		Previous:   nil,
-		Current:    updates,
-		CreatedAt:  time.Now(),
+// editableAttachments loads an achievement whose attachments userID may change: the owner, while
+// the achievement is a draft or in revision.
+func (s *AchievementService) editableAttachments(ctx context.Context, refID, userID string) (*pgModel.AchievementReference, *mongoModel.Achievement, error) {
+	if s.files == nil {
+		return nil, nil, errors.New("file storage is not configured")
 	}
-	s.writeActivityLog(ctx, logEntry)
 
-	return nil
-}
-
-// Method baru untuk handle logika attachment
-func (s *AchievementService) AddAttachment(ctx context.Context, refID string, userID string, fileData mongoModel.Attachment) error {
 	// 1. Cek Reference di Postgres
 	ref, err := s.achievementRefPG.GetByID(ctx, refID)
 	if err != nil {
-		return err
-	}
-	if ref == nil {
-		return errors.New("achievement not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
 	}
 
-	// 2. Validasi Owner (Hanya pemilik yang boleh upload)
-	// Note: Jika student_id di table students berbeda dengan user_id,
-	// Anda mungkin perlu fetch Student dulu seperti di CreateDraft.
-	// Asumsi: Logic validasi owner user -> student sudah benar
+	// 2. Validasi Owner (Hanya pemilik yang boleh mengubah lampiran)
 	student, err := s.studentRepo.GetByUserID(ctx, userID)
-	if err != nil || student == nil {
-		return errors.New("unauthorized student")
-	}
-	if ref.StudentID != student.ID {
-		return errors.New("you are not the owner of this achievement")
+	if err != nil || student == nil || ref.StudentID != student.ID {
+		return nil, nil, ErrNotOwner
 	}
 
-	// 3. Validasi Status (Hanya boleh edit jika Draft)
-	if ref.Status != "draft" {
-		return errors.New("cannot add attachment to submitted/verified achievement")
+	// 3. Validasi Status (Hanya boleh edit jika Draft / Revision)
+	if !isEditable(ref.Status) {
+		return nil, nil, ErrInvalidState
 	}
-
-	// 4. Update MongoDB
 	oid, err := primitive.ObjectIDFromHex(ref.MongoAchievementID)
 	if err != nil {
-		return errors.New("invalid mongo id")
+		return nil, nil, errors.New("invalid mongo id")
+	}
+	doc, err := s.achievementMongo.GetByID(ctx, oid)
+	if err != nil {
+		return nil, nil, err
+	}
+	if doc == nil {
+		return nil, nil, ErrNotFound // soft deleted, or its create is still queued in the outbox
 	}
+	return ref, doc, nil
+}
 
-	return s.achievementMongo.AddAttachment(ctx, oid, fileData)
+// lockAttachments locks the reference of an achievement whose attachments are about to change and
+// returns them as they will be once the outbox has applied the attachment changes still queued, so
+// limits hold across concurrent uploads.
+func (w *achievementWrite) lockAttachments(ctx context.Context, refID string) (*pgModel.AchievementReference, []mongoModel.Attachment, error) {
+	ref, err := w.lockRef(ctx, refID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isEditable(ref.Status) {
+		return nil, nil, ErrInvalidState
+	}
+	doc, err := w.svc.loadDocument(ctx, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	attachments, err := w.svc.outbox.pendingAttachments(ctx, w.tx, ref.ID, doc.Attachments)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ref, attachments, nil
+}
+
+// storeUpload sniffs the file type and writes the upload to the FileStore under a random key.
+func (s *AchievementService) storeUpload(ctx context.Context, ref *pgModel.AchievementReference, attachmentID string, upload AttachmentUpload) (*mongoModel.Attachment, error) {
+	// Sniff tipe file dari byte awal
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(upload.Body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	mimeType, ext, err := sniffFileType(head[:n])
+	if err != nil {
+		return nil, err
+	}
 
+	// Simpan file ke FileStore dengan key acak; Mongo hanya menyimpan key dan checksum-nya
+	attachment := &mongoModel.Attachment{
+		ID:         attachmentID,
+		FileName:   safeFileName(upload.FileName, ext),
+		StorageKey: "achievements/" + ref.ID + "/" + uuid.New().String() + ext,
+		MimeType:   mimeType,
+		Size:       upload.Size,
+		ScanStatus: mongoModel.ScanPending, // not downloadable until the scanner says clean
+	}
+	hash := sha256.New()
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head[:n]), upload.Body), hash)
+	if err := s.files.Put(ctx, attachment.StorageKey, body, upload.Size, mimeType); err != nil {
+		return nil, err
+	}
+	attachment.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return attachment, nil
 }
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	mongoModel "UAS_BACKEND/app/model/mongo"
+	pgModel "UAS_BACKEND/app/model/postgre"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Achievement statuses stored in achievement_references.status
+const (
+	StatusDraft     = "draft"
+	StatusSubmitted = "submitted"
+	StatusVerified  = "verified"
+	StatusRejected  = "rejected"
+	StatusRevision  = "revision"
+	StatusDeleted   = "deleted"
+)
+
+// Transition names, also returned to clients as "allowed_transitions"
+const (
+	TransitionSubmit = "submit"
+	TransitionVerify = "verify"
+	TransitionReject = "reject"
+	TransitionRevise = "revise"
+	TransitionDelete = "delete"
+)
+
+// editableStatuses are the statuses in which the student may change the document and its attachments.
+var editableStatuses = map[string]bool{StatusDraft: true, StatusRevision: true}
+
+func isEditable(status string) bool {
+	return editableStatuses[status]
+}
+
+// transitionContext carries everything a transition step needs.
+type transitionContext struct {
+	name   string // transition being evaluated
+	to     string // its target status
+	caller Caller
+	ref    *pgModel.AchievementReference
+	doc    *mongoModel.Achievement // loaded by prechecks that need it
+	note   string                  // rejection note
+	now    time.Time
+	dryRun bool                   // true while computing allowed transitions: no side effects
+	logged map[string]interface{} // extra fields for the activity log "current" entry
+	w      *achievementWrite      // set while Apply runs: transaction-bound repository and outbox
+}
+
+type transitionStep func(ctx context.Context, tc *transitionContext) error
+
+// achievementTransition declares one edge of the state machine.
+//   - Authorize decides whether the caller may perform it at all (evaluated for allowed_transitions too)
+//   - Precheck validates the request and content (only when firing)
+//   - Apply persists the change; the machine then writes the activity log
+type achievementTransition struct {
+	Name       string
+	From       []string
+	To         string
+	Permission string
+	EventType  string
+	Authorize  transitionStep
+	Precheck   transitionStep
+	Apply      transitionStep
+}
+
+func (t *achievementTransition) allowedFrom(status string) bool {
+	for _, f := range t.From {
+		if f == status {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidFrom is the 409 returned when t cannot be fired from status.
+func (t *achievementTransition) invalidFrom(status string) error {
+	return &CustomError{"invalid_transition",
+		fmt.Sprintf("cannot %s an achievement with status %q (allowed from: %s)", t.Name, status, strings.Join(t.From, ", ")), 409}
+}
+
+// achievementStateMachine is the single place that knows how an achievement moves between statuses.
+type achievementStateMachine struct {
+	svc         *AchievementService
+	transitions []*achievementTransition
+}
+
+func newAchievementStateMachine(s *AchievementService) *achievementStateMachine {
+	m := &achievementStateMachine{svc: s}
+	m.transitions = []*achievementTransition{
+		{
+			Name: TransitionSubmit, From: []string{StatusDraft, StatusRevision}, To: StatusSubmitted,
+			Permission: PermAchievementSubmit, EventType: "status_changed",
+			Authorize: m.requireOwner,
+			Apply:     m.applySubmit,
+		},
+		{
+			Name: TransitionVerify, From: []string{StatusSubmitted}, To: StatusVerified,
+			Permission: PermAchievementVerify, EventType: "status_changed",
+			Authorize: m.requireAdvisor,
+			Apply:     m.applyVerify,
+		},
+		{
+			Name: TransitionReject, From: []string{StatusSubmitted}, To: StatusRejected,
+			Permission: PermAchievementVerify, EventType: "status_changed",
+			Authorize: m.requireAdvisor,
+			Precheck:  m.requireNote,
+			Apply:     m.applyReject,
+		},
+		{
+			Name: TransitionRevise, From: []string{StatusRejected}, To: StatusRevision,
+			Permission: PermAchievementUpdate, EventType: "status_changed",
+			Authorize: m.requireOwner,
+			Apply:     m.applyStatusOnly,
+		},
+		{
+			Name: TransitionDelete, From: []string{StatusDraft}, To: StatusDeleted,
+			Permission: PermAchievementDelete, EventType: "deleted",
+			Authorize: m.requireOwner,
+			Apply:     m.applyDelete,
+		},
+	}
+	return m
+}
+
+func (m *achievementStateMachine) get(name string) *achievementTransition {
+	for _, t := range m.transitions {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Fire runs transition name on tc.ref: status check, permission, authorization, prechecks,
+// persistence and the activity log, in that order.
+func (m *achievementStateMachine) Fire(ctx context.Context, name string, tc *transitionContext) error {
+	t := m.get(name)
+	if t == nil {
+		return fmt.Errorf("unknown transition %q", name)
+	}
+	if !t.allowedFrom(tc.ref.Status) {
+		return t.invalidFrom(tc.ref.Status)
+	}
+	ok, err := m.svc.access.HasPermission(ctx, tc.caller, t.Permission)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrForbidden
+	}
+	tc.name, tc.to = t.Name, t.To
+	if err := t.Authorize(ctx, tc); err != nil {
+		return err
+	}
+	if t.Precheck != nil {
+		if err := t.Precheck(ctx, tc); err != nil {
+			return err
+		}
+	}
+
+	// the checks above ran on a reference read before the transaction; re-check the status on the
+	// locked row so two concurrent requests cannot both fire a transition from the same status
+	loaded := *tc.ref
+	prev := tc.ref.Status
+	tc.logged = map[string]interface{}{}
+	err = m.svc.write(ctx, func(w *achievementWrite) error {
+		locked, err := w.lockRef(ctx, tc.ref.ID)
+		if err != nil {
+			return err
+		}
+		if !t.allowedFrom(locked.Status) {
+			return t.invalidFrom(locked.Status)
+		}
+		*tc.ref = *locked
+		prev = locked.Status
+		tc.w = w
+		return t.Apply(ctx, tc)
+	})
+	if err != nil {
+		*tc.ref = loaded
+		return err
+	}
+
+	current := map[string]interface{}{"status": t.To}
+	for k, v := range tc.logged {
+		current[k] = v
+	}
+	m.svc.writeActivityLog(ctx, &pgModel.ActivityLog{
+		ID:         uuid.New().String(),
+		EntityType: "achievement_reference",
+		EntityID:   tc.ref.ID,
+		EventType:  t.EventType,
+		ActorID:    &tc.caller.UserID,
+		ActorRole:  &tc.caller.RoleID,
+		Previous:   map[string]interface{}{"status": prev},
+		Current:    current,
+		Metadata:   map[string]interface{}{"transition": t.Name},
+		CreatedAt:  tc.now,
+	})
+	return nil
+}
+
+// Allowed lists the transitions caller could fire on ref right now (status, permission and authorization).
+func (m *achievementStateMachine) Allowed(ctx context.Context, caller Caller, ref *pgModel.AchievementReference) ([]string, error) {
+	out := []string{}
+	for _, t := range m.transitions {
+		if !t.allowedFrom(ref.Status) {
+			continue
+		}
+		ok, err := m.svc.access.HasPermission(ctx, caller, t.Permission)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		tc := &transitionContext{name: t.Name, to: t.To, caller: caller, ref: ref, now: time.Now(), dryRun: true}
+		if err := t.Authorize(ctx, tc); err != nil {
+			var ce *CustomError
+			if errors.As(err, &ce) {
+				continue
+			}
+			return nil, err
+		}
+		out = append(out, t.Name)
+	}
+	return out, nil
+}
+
+// ---- guards ----
+
+func (m *achievementStateMachine) requireOwner(ctx context.Context, tc *transitionContext) error {
+	ok, err := m.svc.access.IsOwner(ctx, tc.caller, tc.ref.StudentID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+func (m *achievementStateMachine) requireAdvisor(ctx context.Context, tc *transitionContext) error {
+	if tc.dryRun {
+		ok, _, err := m.svc.access.CanVerify(ctx, tc.caller, tc.ref.StudentID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNotAdvisor
+		}
+		return nil
+	}
+	return m.svc.authorizeVerifier(ctx, tc.caller, tc.ref, tc.name)
+}
+
+func (m *achievementStateMachine) requireNote(ctx context.Context, tc *transitionContext) error {
+	if strings.TrimSpace(tc.note) == "" {
+		return NewValidationError("note_required", "a rejection note is required")
+	}
+	return nil
+}
+
+// validateForSubmit enforces the full type schema (RequiredOnSubmit fields) and clean attachments on
+// the document that will be snapshotted. It runs under the reference lock and refuses while edits
+// are still queued in the outbox, since the Mongo document would not show them yet.
+func (m *achievementStateMachine) validateForSubmit(ctx context.Context, tc *transitionContext) error {
+	queued, err := m.svc.outbox.queuedChanges(ctx, tc.w.tx, tc.ref.ID)
+	if err != nil {
+		return err
+	}
+	if queued {
+		return ErrChangesQueued
+	}
+	doc, err := m.svc.loadDocument(ctx, tc.ref)
+	if err != nil {
+		return err
+	}
+	if err := checkAttachmentScans(doc.Attachments...); err != nil {
+		return err
+	}
+	if err := m.svc.typeSvc.Validate(ctx, doc, true); err != nil {
+		return err
+	}
+	tc.doc = doc
+	return nil
+}
+
+// ---- side effects ----
+
+// applySubmit validates and snapshots the submitted content, then persists submitted_at and clears the answered rejection note
+func (m *achievementStateMachine) applySubmit(ctx context.Context, tc *transitionContext) error {
+	if err := m.validateForSubmit(ctx, tc); err != nil {
+		return err
+	}
+	rev, err := m.svc.createRevision(ctx, tc.w, tc.ref, tc.doc, tc.caller.UserID, tc.now)
+	if err != nil {
+		return err
+	}
+	tc.ref.Status = StatusSubmitted
+	tc.ref.SubmittedAt = &tc.now
+	tc.ref.RejectionNote = nil
+	if err := tc.w.refs.Update(ctx, tc.ref); err != nil {
+		return err
+	}
+	tc.logged["submitted_at"] = tc.now
+	tc.logged["revision"] = rev.Number
+	return nil
+}
+
+func (m *achievementStateMachine) applyVerify(ctx context.Context, tc *transitionContext) error {
+	// UpdateStatus sets verified_by & verified_at when a verifier is provided
+	if err := tc.w.refs.UpdateStatus(ctx, tc.ref.ID, StatusVerified, &tc.caller.UserID); err != nil {
+		return err
+	}
+	tc.ref.Status = StatusVerified
+	tc.ref.VerifiedBy = &tc.caller.UserID
+	tc.ref.VerifiedAt = &tc.now
+	tc.logged["verified_at"] = tc.now
+	tc.logged["verified_by"] = tc.caller.UserID
+	return nil
+}
+
+func (m *achievementStateMachine) applyReject(ctx context.Context, tc *transitionContext) error {
+	// UpdateRejectionNote also sets status='rejected'
+	if err := tc.w.refs.UpdateRejectionNote(ctx, tc.ref.ID, tc.note); err != nil {
+		return err
+	}
+	tc.ref.Status = StatusRejected
+	tc.ref.RejectionNote = &tc.note
+	tc.logged["rejection_note"] = tc.note
+	tc.logged["rejected_at"] = tc.now
+	return nil
+}
+
+// applyStatusOnly only moves the status; the rejection note is kept so the resubmission can answer it
+func (m *achievementStateMachine) applyStatusOnly(ctx context.Context, tc *transitionContext) error {
+	if err := tc.w.refs.UpdateStatus(ctx, tc.ref.ID, tc.to, nil); err != nil {
+		return err
+	}
+	if tc.ref.RejectionNote != nil {
+		tc.logged["answers_rejection_note"] = *tc.ref.RejectionNote
+	}
+	tc.ref.Status = tc.to
+	return nil
+}
+
+// applyDelete marks the reference as deleted and queues the soft delete of the Mongo document
+func (m *achievementStateMachine) applyDelete(ctx context.Context, tc *transitionContext) error {
+	if _, err := primitive.ObjectIDFromHex(tc.ref.MongoAchievementID); err != nil {
+		return errors.New("invalid mongo object id")
+	}
+	if err := tc.w.enqueue(ctx, tc.ref.ID, OutboxDeleteDocument, outboxDeletePayload{DocumentID: tc.ref.MongoAchievementID}); err != nil {
+		return err
+	}
+	if err := tc.w.refs.UpdateStatus(ctx, tc.ref.ID, StatusDeleted, nil); err != nil {
+		return err
+	}
+	tc.ref.Status = StatusDeleted
+	tc.logged["deleted_at"] = tc.now
+	return nil
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	mongoModel "UAS_BACKEND/app/model/mongo"
+	mongoRepo "UAS_BACKEND/app/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Field kinds understood by the details validator.
+const (
+	FieldString      = "string"
+	FieldNumber      = "number"
+	FieldBoolean     = "boolean"
+	FieldDate        = "date"
+	FieldStringArray = "string_array"
+	FieldEnum        = "enum"
+)
+
+var validFieldKinds = map[string]bool{
+	FieldString: true, FieldNumber: true, FieldBoolean: true,
+	FieldDate: true, FieldStringArray: true, FieldEnum: true,
+}
+
+// defaultAchievementTypes are used for any type that has no stored schema document.
+var defaultAchievementTypes = []*mongoModel.AchievementTypeSchema{
+	{
+		Type: "competition", Name: "Kompetisi / Lomba",
+		Description: "Lomba, kompetisi atau olimpiade",
+		Fields: []mongoModel.DetailField{
+			{Name: "competitionName", Label: "Nama Kompetisi", Kind: FieldString, RequiredOnDraft: true, RequiredOnSubmit: true},
+			{Name: "organizer", Label: "Penyelenggara", Kind: FieldString, RequiredOnSubmit: true},
+			{Name: "rank", Label: "Peringkat", Kind: FieldString, RequiredOnSubmit: true},
+			{Name: "eventDate", Label: "Tanggal Pelaksanaan", Kind: FieldDate, RequiredOnSubmit: true},
+			{Name: "location", Label: "Lokasi", Kind: FieldString},
+			{Name: "participants", Label: "Jumlah Peserta", Kind: FieldNumber},
+		},
+	},
+	{
+		Type: "publication", Name: "Publikasi / Karya Tulis",
+		Description: "Artikel jurnal, prosiding atau buku",
+		Fields: []mongoModel.DetailField{
+			{Name: "publicationType", Label: "Jenis Publikasi", Kind: FieldEnum, Options: []string{"journal", "conference", "book", "other"}, RequiredOnSubmit: true},
+			{Name: "authors", Label: "Penulis", Kind: FieldStringArray, RequiredOnSubmit: true},
+			{Name: "publisher", Label: "Penerbit / Jurnal", Kind: FieldString, RequiredOnSubmit: true},
+			{Name: "doi", Label: "DOI", Kind: FieldString, Pattern: `^10\.\d{4,9}/\S+$`, RequiredOnSubmit: true},
+			{Name: "publicationDate", Label: "Tanggal Terbit", Kind: FieldDate, RequiredOnSubmit: true},
+			{Name: "url", Label: "Tautan", Kind: FieldString},
+		},
+	},
+	{
+		Type: "organization", Name: "Organisasi",
+		Description: "Kepengurusan organisasi kemahasiswaan",
+		Fields: []mongoModel.DetailField{
+			{Name: "organizationName", Label: "Nama Organisasi", Kind: FieldString, RequiredOnDraft: true, RequiredOnSubmit: true},
+			{Name: "position", Label: "Jabatan", Kind: FieldString, RequiredOnSubmit: true},
+			{Name: "periodStart", Label: "Mulai Menjabat", Kind: FieldDate, RequiredOnSubmit: true},
+			{Name: "periodEnd", Label: "Selesai Menjabat", Kind: FieldDate},
+		},
+	},
+	{
+		Type: "certification", Name: "Sertifikasi",
+		Description: "Sertifikat kompetensi atau profesi",
+		Fields: []mongoModel.DetailField{
+			{Name: "certificationName", Label: "Nama Sertifikasi", Kind: FieldString, RequiredOnDraft: true, RequiredOnSubmit: true},
+			{Name: "issuedBy", Label: "Lembaga Penerbit", Kind: FieldString, RequiredOnSubmit: true},
+			{Name: "certificateNumber", Label: "Nomor Sertifikat", Kind: FieldString, RequiredOnSubmit: true},
+			{Name: "issueDate", Label: "Tanggal Terbit", Kind: FieldDate, RequiredOnSubmit: true},
+			{Name: "expiryDate", Label: "Berlaku Hingga", Kind: FieldDate},
+		},
+	},
+	{
+		Type: "academic", Name: "Akademik",
+		Description: "Beasiswa, pertukaran pelajar atau penghargaan akademik",
+		Fields: []mongoModel.DetailField{
+			{Name: "programName", Label: "Nama Program", Kind: FieldString, RequiredOnDraft: true, RequiredOnSubmit: true},
+			{Name: "institution", Label: "Institusi", Kind: FieldString, RequiredOnSubmit: true},
+			{Name: "period", Label: "Periode", Kind: FieldString, RequiredOnSubmit: true},
+			{Name: "score", Label: "Nilai / IPK", Kind: FieldNumber},
+		},
+	},
+	{
+		Type: "other", Name: "Lainnya",
+		Description: "Prestasi yang tidak masuk kategori lain",
+		Fields: []mongoModel.DetailField{
+			{Name: "description", Label: "Deskripsi", Kind: FieldString, RequiredOnSubmit: true},
+			{Name: "eventDate", Label: "Tanggal", Kind: FieldDate},
+		},
+	},
+}
+
+// AchievementTypeService serves the details schema per achievement type and validates documents against it.
+type AchievementTypeService struct {
+	repo mongoRepo.AchievementTypeRepository
+}
+
+// NewAchievementTypeService creates an instance of AchievementTypeService.
+// repo can be nil, in which case only the built-in defaults are available.
+func NewAchievementTypeService(repo mongoRepo.AchievementTypeRepository) *AchievementTypeService {
+	return &AchievementTypeService{repo: repo}
+}
+
+// List returns every known type; stored schemas override the built-in defaults.
+func (s *AchievementTypeService) List(ctx context.Context) ([]*mongoModel.AchievementTypeSchema, error) {
+	byType := make(map[string]*mongoModel.AchievementTypeSchema, len(defaultAchievementTypes))
+	for _, d := range defaultAchievementTypes {
+		byType[d.Type] = d
+	}
+	if s.repo != nil {
+		stored, err := s.repo.ListAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, st := range stored {
+			byType[st.Type] = st
+		}
+	}
+
+	out := make([]*mongoModel.AchievementTypeSchema, 0, len(byType))
+	for _, v := range byType {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	return out, nil
+}
+
+// Get returns the schema for a type, or ErrNotFound.
+func (s *AchievementTypeService) Get(ctx context.Context, typ string) (*mongoModel.AchievementTypeSchema, error) {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if s.repo != nil {
+		stored, err := s.repo.GetByType(ctx, typ)
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			return stored, nil
+		}
+	}
+	for _, d := range defaultAchievementTypes {
+		if d.Type == typ {
+			return d, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Upsert validates and stores a schema for schema.Type.
+func (s *AchievementTypeService) Upsert(ctx context.Context, schema *mongoModel.AchievementTypeSchema) error {
+	if s.repo == nil {
+		return fmt.Errorf("achievement type storage is not configured")
+	}
+	schema.Type = strings.ToLower(strings.TrimSpace(schema.Type))
+	if schema.Type == "" {
+		return NewValidationError("invalid_schema", "type is required")
+	}
+	seen := map[string]bool{}
+	for _, f := range schema.Fields {
+		if f.Name == "" || strings.ContainsAny(f.Name, ".$") {
+			return NewValidationError("invalid_schema", fmt.Sprintf("invalid field name %q", f.Name))
+		}
+		if seen[f.Name] {
+			return NewValidationError("invalid_schema", fmt.Sprintf("duplicate field %q", f.Name))
+		}
+		seen[f.Name] = true
+		if !validFieldKinds[f.Kind] {
+			return NewValidationError("invalid_schema", fmt.Sprintf("field %q has unknown kind %q", f.Name, f.Kind))
+		}
+		if f.Kind == FieldEnum && len(f.Options) == 0 {
+			return NewValidationError("invalid_schema", fmt.Sprintf("enum field %q needs options", f.Name))
+		}
+		if f.Pattern != "" {
+			if _, err := regexp.Compile(f.Pattern); err != nil {
+				return NewValidationError("invalid_schema", fmt.Sprintf("field %q has invalid pattern: %v", f.Name, err))
+			}
+		}
+	}
+	return s.repo.Upsert(ctx, schema)
+}
+
+// Delete removes a stored schema. Built-in types fall back to their default schema afterwards.
+func (s *AchievementTypeService) Delete(ctx context.Context, typ string) error {
+	if s.repo == nil {
+		return fmt.Errorf("achievement type storage is not configured")
+	}
+	if err := s.repo.Delete(ctx, strings.ToLower(strings.TrimSpace(typ))); err != nil {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Validate checks an achievement against the schema of its type (falling back to its category).
+// strict=false is used for draft saves (field kinds and RequiredOnDraft);
+// strict=true is used on submit and also enforces RequiredOnSubmit and the top-level fields.
+func (s *AchievementTypeService) Validate(ctx context.Context, a *mongoModel.Achievement, strict bool) error {
+	var problems []string
+
+	schema, err := s.resolve(ctx, a)
+	if err != nil {
+		return err
+	}
+	if schema == nil {
+		return NewValidationError("unknown_achievement_type",
+			fmt.Sprintf("unknown achievement type %q; see GET /achievement-types", a.Type))
+	}
+
+	if strings.TrimSpace(a.Title) == "" {
+		problems = append(problems, "title is required")
+	}
+	if strict {
+		if strings.TrimSpace(a.Category) == "" {
+			problems = append(problems, "category is required")
+		}
+		if strings.TrimSpace(a.Level) == "" {
+			problems = append(problems, "level is required")
+		}
+	}
+
+	for _, f := range schema.Fields {
+		value, present := a.Details[f.Name]
+		if !present || value == nil || value == "" {
+			if f.RequiredOnDraft || (strict && f.RequiredOnSubmit) {
+				problems = append(problems, fmt.Sprintf("details.%s is required", f.Name))
+			}
+			continue
+		}
+		if msg := checkDetailValue(f, value); msg != "" {
+			problems = append(problems, fmt.Sprintf("details.%s %s", f.Name, msg))
+		}
+	}
+
+	if len(problems) > 0 {
+		return NewValidationError("invalid_details", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (s *AchievementTypeService) resolve(ctx context.Context, a *mongoModel.Achievement) (*mongoModel.AchievementTypeSchema, error) {
+	for _, key := range []string{a.Type, a.Category} {
+		if strings.TrimSpace(key) == "" {
+			continue
+		}
+		schema, err := s.Get(ctx, key)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return schema, nil
+	}
+	return nil, nil
+}
+
+// checkDetailValue returns an empty string when value matches the field kind, otherwise the problem.
+func checkDetailValue(f mongoModel.DetailField, value interface{}) string {
+	switch f.Kind {
+	case FieldString:
+		str, ok := value.(string)
+		if !ok {
+			return "must be a string"
+		}
+		if f.Pattern != "" {
+			if re, err := regexp.Compile(f.Pattern); err == nil && !re.MatchString(str) {
+				return "has an invalid format"
+			}
+		}
+	case FieldNumber:
+		switch value.(type) {
+		case float64, float32, int, int32, int64:
+		default:
+			return "must be a number"
+		}
+	case FieldBoolean:
+		if _, ok := value.(bool); !ok {
+			return "must be a boolean"
+		}
+	case FieldDate:
+		switch v := value.(type) {
+		case time.Time, primitive.DateTime:
+		case string:
+			if _, err := time.Parse("2006-01-02", v); err != nil {
+				if _, err := time.Parse(time.RFC3339, v); err != nil {
+					return "must be a date (YYYY-MM-DD)"
+				}
+			}
+		default:
+			return "must be a date (YYYY-MM-DD)"
+		}
+	case FieldStringArray:
+		var items []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			items = v
+		case primitive.A:
+			items = v
+		case []string:
+			if len(v) == 0 {
+				return "must not be empty"
+			}
+			return ""
+		default:
+			return "must be an array of strings"
+		}
+		if len(items) == 0 {
+			return "must not be empty"
+		}
+		for _, it := range items {
+			if _, ok := it.(string); !ok {
+				return "must be an array of strings"
+			}
+		}
+	case FieldEnum:
+		str, ok := value.(string)
+		if !ok {
+			return "must be a string"
+		}
+		for _, opt := range f.Options {
+			if opt == str {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(f.Options, ", "))
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	mongoModel "UAS_BACKEND/app/model/mongo"
+)
+
+// Attachment limits used until SetAttachmentPolicy is called (main applies ATTACHMENT_MAX_FILE_MB,
+// ATTACHMENT_MAX_TOTAL_MB and ATTACHMENT_MAX_COUNT).
+var DefaultAttachmentPolicy = AttachmentPolicy{
+	MaxFileSize:  10 << 20,
+	MaxTotalSize: 50 << 20,
+	MaxCount:     10,
+}
+
+// Upload rejections, each with its own code so clients can tell the user what to fix.
+var (
+	ErrFileRequired         = &CustomError{"file_required", "a file is required in the \"file\" form field", 400}
+	ErrEmptyFile            = &CustomError{"empty_file", "the uploaded file is empty", 400}
+	ErrFileTooLarge         = &CustomError{"file_too_large", "the file is larger than the allowed size", 413}
+	ErrUnsupportedFileType  = &CustomError{"unsupported_file_type", "only PDF, PNG, JPEG and WebP files are accepted", 415}
+	ErrTooManyAttachments   = &CustomError{"too_many_attachments", "the achievement already has the maximum number of attachments", 409}
+	ErrAttachmentQuotaTotal = &CustomError{"attachment_quota_exceeded", "the attachments of this achievement would exceed the total size limit", 413}
+)
+
+// AttachmentPolicy limits what AddAttachment accepts; sizes are in bytes.
+type AttachmentPolicy struct {
+	MaxFileSize  int64
+	MaxTotalSize int64 // of all attachments of one achievement
+	MaxCount     int   // attachments per achievement
+}
+
+// sniffLen is how many leading bytes sniffFileType needs.
+const sniffLen = 12
+
+// allowedFileTypes are recognised by their magic bytes only; the client's Content-Type and file
+// extension are ignored.
+var allowedFileTypes = []struct {
+	mimeType  string
+	extension string
+	match     func(head []byte) bool
+}{
+	{"application/pdf", ".pdf", func(h []byte) bool { return bytes.HasPrefix(h, []byte("%PDF-")) }},
+	{"image/png", ".png", func(h []byte) bool { return bytes.HasPrefix(h, []byte("\x89PNG\r\n\x1a\n")) }},
+	{"image/jpeg", ".jpg", func(h []byte) bool { return bytes.HasPrefix(h, []byte("\xff\xd8\xff")) }},
+	{"image/webp", ".webp", func(h []byte) bool {
+		return len(h) >= 12 && bytes.Equal(h[:4], []byte("RIFF")) && bytes.Equal(h[8:12], []byte("WEBP"))
+	}},
+}
+
+// sniffFileType returns the MIME type and extension of an allowed file, or ErrUnsupportedFileType.
+func sniffFileType(head []byte) (string, string, error) {
+	for _, t := range allowedFileTypes {
+		if t.match(head) {
+			return t.mimeType, t.extension, nil
+		}
+	}
+	return "", "", ErrUnsupportedFileType
+}
+
+// checkSize rejects a file of size bytes for an achievement that already has count attachments
+// totalling total bytes.
+func (p AttachmentPolicy) checkSize(size int64, count int, total int64) error {
+	switch {
+	case size <= 0:
+		return ErrEmptyFile
+	case p.MaxFileSize > 0 && size > p.MaxFileSize:
+		return &CustomError{ErrFileTooLarge.Code, fmt.Sprintf("the file is larger than %s", formatBytes(p.MaxFileSize)), ErrFileTooLarge.Status}
+	case p.MaxCount > 0 && count >= p.MaxCount:
+		return &CustomError{ErrTooManyAttachments.Code, fmt.Sprintf("an achievement can have at most %d attachments", p.MaxCount), ErrTooManyAttachments.Status}
+	case p.MaxTotalSize > 0 && total+size > p.MaxTotalSize:
+		return &CustomError{ErrAttachmentQuotaTotal.Code, fmt.Sprintf("the attachments of an achievement may not exceed %s together", formatBytes(p.MaxTotalSize)), ErrAttachmentQuotaTotal.Status}
+	}
+	return nil
+}
+
+// check rejects a file of size bytes for an achievement that has attachments; the attachment with
+// id replacing (if any) is about to be swapped out and does not count.
+func (p AttachmentPolicy) check(size int64, attachments []mongoModel.Attachment, replacing string) error {
+	count, total := 0, int64(0)
+	for _, a := range attachments {
+		if replacing != "" && a.ID == replacing {
+			continue
+		}
+		count++
+		total += a.Size
+	}
+	return p.checkSize(size, count, total)
+}
+
+func formatBytes(n int64) string {
+	if n >= 1<<20 && n%(1<<20) == 0 {
+		return fmt.Sprintf("%d MB", n>>20)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// safeFileName keeps the display name of an upload: no directories, control characters or
+// leading dots, at most 200 characters, and ext when the name lacks it.
+func safeFileName(name, ext string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError || r == '/' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	if runes := []rune(name); len(runes) > 200 {
+		name = string(runes[:200])
+	}
+	if name == "" {
+		name = "file"
+	}
+	if !strings.EqualFold(path.Ext(name), ext) && !(ext == ".jpg" && strings.EqualFold(path.Ext(name), ".jpeg")) {
+		name += ext
+	}
+	return name
+}
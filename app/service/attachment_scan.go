@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	mongoModel "UAS_BACKEND/app/model/mongo"
+	pgModel "UAS_BACKEND/app/model/postgre"
+	pgRepo "UAS_BACKEND/app/repository/postgre"
+	"UAS_BACKEND/utils"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrAttachmentScanPending = &CustomError{"attachment_scan_pending", "an attachment is still being scanned for malware, try again shortly", 409}
+	ErrAttachmentInfected    = &CustomError{"attachment_infected", "an attachment was flagged as malware and must be replaced", 422}
+	ErrAttachmentMissing     = &CustomError{"attachment_missing", "the file of an attachment is missing, upload it again", 422}
+)
+
+// scanTimeout bounds one scan including reading the file back from the FileStore.
+const scanTimeout = 5 * time.Minute
+
+// pendingScanBatch is how many documents one ScanPending round looks at.
+const pendingScanBatch = 50
+
+// SetScanner replaces the malware scanner (default utils.NoopScanner, which marks every file clean).
+func (s *AchievementService) SetScanner(scanner utils.Scanner) {
+	s.scanner = scanner
+}
+
+// checkAttachmentScans blocks submitting (and downloading) while a scan is pending or found malware.
+func checkAttachmentScans(attachments ...mongoModel.Attachment) error {
+	for _, a := range attachments {
+		switch a.ScanStatus {
+		case mongoModel.ScanInfected:
+			return ErrAttachmentInfected
+		case mongoModel.ScanMissing:
+			return ErrAttachmentMissing
+		case mongoModel.ScanPending:
+			return ErrAttachmentScanPending
+		}
+	}
+	return nil
+}
+
+// scanAttachmentAsync scans a freshly stored attachment in the background. If the process stops
+// or the scanner fails, the attachment stays pending and ScanPending picks it up later.
+func (s *AchievementService) scanAttachmentAsync(refID, documentID string, attachment mongoModel.Attachment) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+		defer cancel()
+		if err := s.scanAttachment(ctx, refID, documentID, attachment); err != nil {
+			log.Printf("attachment scan %s (achievement %s): %v, will retry", attachment.ID, refID, err)
+		}
+	}()
+}
+
+// scanAttachment reads the file back from the FileStore, scans it and records the verdict through
+// the outbox, after the add_attachment entry of the same achievement.
+func (s *AchievementService) scanAttachment(ctx context.Context, refID, documentID string, attachment mongoModel.Attachment) error {
+	body, _, err := s.files.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		return err
+	}
+	result, err := s.scanner.Scan(ctx, body)
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	status := mongoModel.ScanClean
+	if result.Infected {
+		status = mongoModel.ScanInfected
+	}
+	if err := s.recordScan(ctx, refID, documentID, attachment, status, result.Signature, now); err != nil {
+		return err
+	}
+
+	if result.Infected {
+		log.Printf("attachment %s (achievement %s) is infected: %s", attachment.ID, refID, result.Signature)
+		s.writeActivityLog(ctx, &pgModel.ActivityLog{
+			ID:         uuid.New().String(),
+			EntityType: "achievement",
+			EntityID:   refID,
+			EventType:  "attachment_infected",
+			Metadata: map[string]interface{}{
+				"attachment_id": attachment.ID,
+				"file_name":     attachment.FileName,
+				"sha256":        attachment.SHA256,
+				"signature":     result.Signature,
+			},
+			CreatedAt: now,
+		})
+	}
+	return nil
+}
+
+// recordScan stores the scan status of an attachment through the outbox, after the add_attachment
+// entry of the same achievement.
+func (s *AchievementService) recordScan(ctx context.Context, refID, documentID string, attachment mongoModel.Attachment, status, signature string, at time.Time) error {
+	return s.write(ctx, func(w *achievementWrite) error {
+		return w.enqueue(ctx, refID, OutboxScanAttachment, outboxAttachmentScanPayload{
+			DocumentID:   documentID,
+			AttachmentID: attachment.ID,
+			StorageKey:   attachment.StorageKey,
+			Status:       status,
+			Signature:    signature,
+			ScannedAt:    at,
+		})
+	})
+}
+
+// ScanPending scans attachments that are still pending (scanner was down, process restarted) and
+// returns how many got a verdict. Attachments whose verdict is queued in the outbox are skipped, and
+// a file that is gone from the FileStore is marked missing so the student knows to upload it again.
+func (s *AchievementService) ScanPending(ctx context.Context) (int, error) {
+	done := 0
+	after := primitive.NilObjectID
+	for {
+		// page by _id: skipped documents (orphans, queued verdicts) stay pending and must not
+		// hold back the ones after them
+		docs, err := s.achievementMongo.ListPendingScans(ctx, after, pendingScanBatch)
+		if err != nil || len(docs) == 0 {
+			return done, err
+		}
+		n, err := s.scanPendingPage(ctx, docs)
+		done += n
+		if err != nil || len(docs) < pendingScanBatch {
+			return done, err
+		}
+		after = docs[len(docs)-1].ID
+	}
+}
+
+func (s *AchievementService) scanPendingPage(ctx context.Context, docs []*mongoModel.Achievement) (int, error) {
+	mongoIDs := make([]string, len(docs))
+	for i, d := range docs {
+		mongoIDs[i] = d.ID.Hex()
+	}
+	refs, _, err := s.achievementRefPG.Search(ctx, pgRepo.AchievementRefFilter{MongoIDs: mongoIDs, Limit: len(mongoIDs)})
+	if err != nil {
+		return 0, err
+	}
+	refIDs := make(map[string]string, len(refs))
+	for _, ref := range refs {
+		refIDs[ref.MongoAchievementID] = ref.ID
+	}
+
+	done := 0
+	for _, d := range docs {
+		refID, ok := refIDs[d.ID.Hex()]
+		if !ok {
+			continue // orphaned document, left to reconciliation
+		}
+		queued, err := s.outbox.queuedScans(ctx, refID)
+		if err != nil {
+			return done, err
+		}
+		for _, a := range d.Attachments {
+			if a.ScanStatus != mongoModel.ScanPending || a.ID == "" || a.StorageKey == "" || queued[a.StorageKey] {
+				continue
+			}
+			scanCtx, cancel := context.WithTimeout(ctx, scanTimeout)
+			err := s.scanAttachment(scanCtx, refID, d.ID.Hex(), a)
+			cancel()
+			if errors.Is(err, utils.ErrFileNotFound) {
+				log.Printf("attachment scan %s (achievement %s): file %s is missing", a.ID, refID, a.StorageKey)
+				err = s.recordScan(ctx, refID, d.ID.Hex(), a, mongoModel.ScanMissing, "", time.Now())
+			}
+			if err != nil {
+				return done, err // scanner still unavailable: stop this round
+			}
+			done++
+		}
+	}
+	return done, nil
+}
+
+// RunPendingScans calls ScanPending every interval until ctx is cancelled.
+func (s *AchievementService) RunPendingScans(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.ScanPending(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("attachment scan: %v", err)
+		} else if n > 0 {
+			log.Printf("attachment scan: %d pending attachment(s) scanned", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
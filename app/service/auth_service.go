@@ -2,17 +2,67 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"os"
+	"sync"
 	"time"
 
 	pgModel "UAS_BACKEND/app/model/postgre"
 	pgRepo "UAS_BACKEND/app/repository/postgre"
+	"UAS_BACKEND/utils"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Default token lifetimes, overridable with ACCESS_TOKEN_TTL / REFRESH_TOKEN_TTL (e.g. "15m", "720h").
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+	MFATokenTTL            = 5 * time.Minute // time to enter the second factor after the password
+)
+
+// Values of the "typ" claim. The JWT middleware only accepts access tokens.
+const (
+	TokenTypeAccess     = "access"
+	TokenTypeMFAPending = "mfa_pending"
+)
+
+var (
+	ErrInvalidRefreshToken = &CustomError{"invalid_refresh_token", "refresh token is invalid, expired or revoked", 401}
+	ErrRefreshTokenReused  = &CustomError{"refresh_token_reused", "refresh token was already used; this login has been revoked", 401}
+	ErrInvalidMFAToken     = &CustomError{"invalid_mfa_token", "mfa token is invalid or expired, log in again", 401}
+	ErrInvalidCredentials  = &CustomError{"invalid_credentials", "invalid credentials", 401}
+	ErrAccountDisabled     = &CustomError{"account_disabled", "account is disabled", 403}
+	ErrWrongPassword       = &CustomError{"invalid_current_password", "current password is incorrect", 400}
+)
+
+// TokenPair is what Login and Refresh hand out: a short-lived JWT access token and an opaque
+// refresh token that can be exchanged exactly once for the next pair.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // access token lifetime in seconds
+}
+
+// LoginResult is either a token pair, or (MFARequired) an mfa_token to finish the login with
+// a second factor at /auth/login/mfa.
+type LoginResult struct {
+	*TokenPair
+	User                  *pgModel.User
+	MFARequired           bool
+	MFAEnrollmentRequired bool     // the role requires MFA but the user has not enrolled yet
+	MFAToken              string   // short-lived, only accepted by the MFA login step
+	RecoveryCodes         []string // set when the enrollment was completed during this login
+}
+
 // Definisikan interface untuk Token Repository di sini (atau import dari domain layer)
 // Implementasinya nanti bisa menggunakan Redis (disarankan) atau Database SQL
 type TokenRepository interface {
@@ -21,23 +71,47 @@ type TokenRepository interface {
 }
 
 type AuthService struct {
-	userRepo  pgRepo.UserRepository
-	tokenRepo TokenRepository // Tambahkan dependency ini
-	jwtSecret string
+	userRepo    pgRepo.UserRepository
+	tokenRepo   TokenRepository // Tambahkan dependency ini
+	refreshRepo pgRepo.RefreshTokenRepository
+	sessions    *SessionService
+	mfa         *MFAService
+	throttle    *LoginThrottle
+	keys        *utils.JWTKeySet
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+
+	historyRepo  pgRepo.PasswordHistoryRepository
+	activityRepo pgRepo.ActivityLogRepository
+	policy       PasswordPolicy
 }
 
 // Update constructor untuk menerima tokenRepo
 // Note: Anda perlu mengupdate wiring di service_factory.go juga nantinya
-func NewAuthService(userRepo pgRepo.UserRepository, tokenRepo TokenRepository) *AuthService {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "dev-secret"
-	}
+func NewAuthService(userRepo pgRepo.UserRepository, tokenRepo TokenRepository, refreshRepo pgRepo.RefreshTokenRepository, sessions *SessionService, mfa *MFAService, throttle *LoginThrottle, keys *utils.JWTKeySet,
+	historyRepo pgRepo.PasswordHistoryRepository, activityRepo pgRepo.ActivityLogRepository) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		tokenRepo: tokenRepo,
-		jwtSecret: secret,
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		refreshRepo: refreshRepo,
+		sessions:    sessions,
+		mfa:         mfa,
+		throttle:    throttle,
+		keys:        keys,
+		accessTTL:   durationEnv("ACCESS_TOKEN_TTL", DefaultAccessTokenTTL),
+		refreshTTL:  durationEnv("REFRESH_TOKEN_TTL", DefaultRefreshTokenTTL),
+
+		historyRepo:  historyRepo,
+		activityRepo: activityRepo,
+		policy:       PasswordPolicyFromEnv(),
+	}
+}
+
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
 	}
+	return fallback
 }
 
 func (s *AuthService) HashPassword(password string) (string, error) {
@@ -49,45 +123,398 @@ func (s *AuthService) ComparePassword(hash, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-// Login authenticates and returns JWT token
-func (s *AuthService) Login(ctx context.Context, username, password string) (string, *pgModel.User, error) {
-	user, err := s.userRepo.GetByUsername(ctx, username)
+// CheckPasswordPolicy returns a weak_password error when password breaks the policy.
+func (s *AuthService) CheckPasswordPolicy(password, username string) error {
+	return s.policy.Check(password, username)
+}
+
+// HashNewPassword checks password against the policy and hashes it, for an account that does
+// not exist yet.
+func (s *AuthService) HashNewPassword(username, password string) (string, error) {
+	if err := s.policy.Check(password, username); err != nil {
+		return "", err
+	}
+	return s.HashPassword(password)
+}
+
+// CheckNewPassword runs the checks of SetPassword (policy and recent passwords) without storing
+// anything, for callers that must reject a password before spending something on it.
+func (s *AuthService) CheckNewPassword(ctx context.Context, userID, password string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.checkNewPassword(ctx, user, password)
+}
+
+// CheckNewPasswordFor is CheckNewPassword for user as it is about to be saved (e.g. with a new username).
+func (s *AuthService) CheckNewPasswordFor(ctx context.Context, user *pgModel.User, password string) error {
+	return s.checkNewPassword(ctx, user, password)
+}
+
+func (s *AuthService) checkNewPassword(ctx context.Context, user *pgModel.User, password string) error {
+	if err := s.policy.Check(password, user.Username); err != nil {
+		return err
+	}
+	return s.checkReuse(ctx, user, password)
+}
+
+// SetPassword checks the policy and the recent passwords of the user, stores the new hash and
+// keeps the replaced one in the history. Sessions are left alone; callers end them.
+func (s *AuthService) SetPassword(ctx context.Context, userID, password string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkNewPassword(ctx, user, password); err != nil {
+		return err
+	}
+	hash, err := s.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.SetPassword(ctx, userID, hash); err != nil {
+		return err
+	}
+	if s.historyRepo != nil && s.policy.History > 1 && user.PasswordHash != "" {
+		if err := s.historyRepo.Add(ctx, &pgModel.PasswordHistory{ID: uuid.New().String(), UserID: userID, PasswordHash: user.PasswordHash}); err != nil {
+			return err
+		}
+		return s.historyRepo.Prune(ctx, userID, s.policy.History-1)
+	}
+	return nil
+}
+
+// ChangePassword is the self-service change: the current password is required (a wrong one
+// counts as a failed login) and every other session of the user ends.
+func (s *AuthService) ChangePassword(ctx context.Context, userID, sessionID, current, password string, meta pgModel.SessionMeta) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.throttle.Check(ctx, user.Username, meta); err != nil {
+		return err
+	}
+	if s.ComparePassword(user.PasswordHash, current) != nil {
+		s.throttle.Failure(ctx, user.Username, user, meta, "wrong_current_password")
+		return ErrWrongPassword
+	}
+	if err := s.SetPassword(ctx, userID, password); err != nil {
+		return err
+	}
+	n, err := s.sessions.RevokeOthers(ctx, userID, sessionID, SessionPasswordChanged)
 	if err != nil {
-		return "", nil, err
+		return err
 	}
-	if user == nil {
-		return "", nil, errors.New("invalid credentials")
+	s.logPassword(ctx, userID, userID, "password_changed", map[string]interface{}{"ip": meta.IP, "user_agent": meta.UserAgent, "sessions_revoked": n})
+	return nil
+}
+
+// AdminSetPassword sets the password of another user and ends all of their sessions.
+func (s *AuthService) AdminSetPassword(ctx context.Context, caller Caller, userID, password string) error {
+	if err := s.SetPassword(ctx, userID, password); err != nil {
+		return err
+	}
+	n, err := s.sessions.RevokeUser(ctx, userID, SessionPasswordChanged)
+	if err != nil {
+		return err
+	}
+	s.logPassword(ctx, caller.UserID, userID, "password_set", map[string]interface{}{"sessions_revoked": n})
+	return nil
+}
+
+// checkReuse refuses the current password and the ones in the history (policy.History in total).
+func (s *AuthService) checkReuse(ctx context.Context, user *pgModel.User, password string) error {
+	if s.policy.History < 1 {
+		return nil
+	}
+	hashes := []string{user.PasswordHash}
+	if s.historyRepo != nil && s.policy.History > 1 {
+		previous, err := s.historyRepo.Recent(ctx, user.ID, s.policy.History-1)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, previous...)
+	}
+	for _, h := range hashes {
+		if h != "" && s.ComparePassword(h, password) == nil {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// logPassword writes a password change of userID to activity_logs (best-effort).
+func (s *AuthService) logPassword(ctx context.Context, actorID, userID, event string, metadata map[string]interface{}) {
+	if s.activityRepo == nil {
+		return
+	}
+	_ = s.activityRepo.Create(ctx, &pgModel.ActivityLog{
+		ID:         uuid.New().String(),
+		EntityType: "user",
+		EntityID:   userID,
+		EventType:  event,
+		ActorID:    &actorID,
+		Metadata:   metadata,
+		CreatedAt:  time.Now(),
+	})
+}
+
+// Login checks the password. Users with MFA enabled (or whose role requires it) get an
+// mfa_token instead of tokens; everyone else gets a new session and its first token pair.
+func (s *AuthService) Login(ctx context.Context, username, password string, meta pgModel.SessionMeta) (*LoginResult, error) {
+	if err := s.throttle.Check(ctx, username, meta); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user == nil) {
+		// same bcrypt cost as a real account, so response times do not reveal unknown usernames
+		_ = s.ComparePassword(dummyPasswordHash(), password)
+		s.throttle.Failure(ctx, username, nil, meta, "unknown_user")
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
 	}
 	if err := s.ComparePassword(user.PasswordHash, password); err != nil {
-		return "", nil, errors.New("invalid credentials")
+		s.throttle.Failure(ctx, username, user, meta, "bad_password")
+		return nil, ErrInvalidCredentials
+	}
+	if !user.IsActive {
+		s.throttle.NotCounted(ctx, user, meta, "account_disabled")
+		return nil, ErrAccountDisabled
+	}
+
+	if s.mfa != nil {
+		enabled, err := s.mfa.Enabled(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		required, err := s.mfa.Required(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		if enabled || required {
+			token, err := s.mfaToken(user, !enabled)
+			if err != nil {
+				return nil, err
+			}
+			return &LoginResult{User: user, MFARequired: true, MFAEnrollmentRequired: !enabled, MFAToken: token}, nil
+		}
+	}
+	return s.startSession(ctx, user, meta, false)
+}
+
+// LoginMFA finishes a login started with Login: code is a TOTP or recovery code. When the
+// login was waiting for a forced enrollment (see BeginMFAEnrollment), code confirms it and the
+// recovery codes are returned with the tokens.
+func (s *AuthService) LoginMFA(ctx context.Context, mfaToken, code string, meta pgModel.SessionMeta) (*LoginResult, error) {
+	user, enroll, err := s.parseMFAToken(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.throttle.Check(ctx, user.Username, meta); err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		s.throttle.NotCounted(ctx, user, meta, "account_disabled")
+		return nil, ErrAccountDisabled
+	}
+
+	var recovery []string
+	enabled, err := s.mfa.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enroll && !enabled {
+		recovery, err = s.mfa.Confirm(ctx, user.ID, code)
+	} else {
+		err = s.mfa.Verify(ctx, user.ID, code)
+	}
+	if errors.Is(err, ErrInvalidMFACode) {
+		s.throttle.Failure(ctx, user.Username, user, meta, "bad_mfa_code")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.startSession(ctx, user, meta, true)
+	if err != nil {
+		return nil, err
+	}
+	res.RecoveryCodes = recovery
+	return res, nil
+}
+
+// BeginMFAEnrollment starts the enrollment of a user whose role requires MFA, authenticated by
+// the mfa_token of the login (they cannot obtain an access token before enrolling).
+func (s *AuthService) BeginMFAEnrollment(ctx context.Context, mfaToken string) (*MFAEnrollment, error) {
+	user, enroll, err := s.parseMFAToken(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if !enroll {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	return s.mfa.Begin(ctx, user)
+}
+
+func (s *AuthService) startSession(ctx context.Context, user *pgModel.User, meta pgModel.SessionMeta, mfa bool) (*LoginResult, error) {
+	sessionID, err := s.sessions.Start(ctx, user.ID, meta)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := s.issue(ctx, user, sessionID, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.throttle.Success(ctx, user, meta, sessionID, mfa)
+	return &LoginResult{TokenPair: pair, User: user}, nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash is compared against when the username does not exist.
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		b, _ := bcrypt.GenerateFromPassword([]byte(uuid.New().String()), bcrypt.DefaultCost)
+		dummyHash = string(b)
+	})
+	return dummyHash
+}
+
+// mfaToken proves that the password was checked; it is only accepted by LoginMFA and
+// BeginMFAEnrollment, never as an access token.
+func (s *AuthService) mfaToken(user *pgModel.User, enroll bool) (string, error) {
+	now := time.Now()
+	return s.keys.Sign(jwt.MapClaims{
+		"sub":    user.ID,
+		"typ":    TokenTypeMFAPending,
+		"enroll": enroll,
+		"jti":    uuid.New().String(),
+		"exp":    now.Add(MFATokenTTL).Unix(),
+		"iat":    now.Unix(),
+	})
+}
+
+func (s *AuthService) parseMFAToken(ctx context.Context, tokenString string) (*pgModel.User, bool, error) {
+	if s.mfa == nil {
+		return nil, false, ErrInvalidMFAToken
+	}
+	claims := jwt.MapClaims{}
+	if _, err := s.keys.Parse(tokenString, claims); err != nil {
+		return nil, false, ErrInvalidMFAToken
+	}
+	sub, _ := claims["sub"].(string)
+	if typ, _ := claims["typ"].(string); typ != TokenTypeMFAPending || sub == "" {
+		return nil, false, ErrInvalidMFAToken
+	}
+	user, err := s.userRepo.GetByID(ctx, sub)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	enroll, _ := claims["enroll"].(bool)
+	return user, enroll, nil
+}
+
+// Refresh exchanges a refresh token for a new pair. The presented token is used up; presenting
+// it again (a stolen copy, or a client replaying it) revokes every token of its family.
+// The new access token carries the user's current role.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, ip string) (*TokenPair, error) {
+	if refreshToken == "" || s.refreshRepo == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	rt, err := s.refreshRepo.GetByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rt.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if rt.UsedAt != nil {
+		return nil, s.reuseDetected(ctx, rt)
+	}
+	if time.Now().After(rt.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	ok, err := s.refreshRepo.MarkUsed(ctx, rt.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok { // lost a race against another refresh with the same token
+		return nil, s.reuseDetected(ctx, rt)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, rt.UserID)
+	if err != nil || !user.IsActive {
+		_ = s.sessions.Revoke(ctx, rt.FamilyID, SessionDeactivated)
+		return nil, ErrInvalidRefreshToken
 	}
-	// create token
+	s.sessions.Seen(ctx, rt.FamilyID, ip)
+	return s.issue(ctx, user, rt.FamilyID, &rt.ID)
+}
+
+func (s *AuthService) reuseDetected(ctx context.Context, rt *pgModel.RefreshToken) error {
+	if err := s.sessions.Revoke(ctx, rt.FamilyID, "reuse_detected"); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// issue signs an access token for the session and stores a fresh refresh token in its family
+// (the family id is the session id).
+func (s *AuthService) issue(ctx context.Context, user *pgModel.User, sessionID string, parentID *string) (*TokenPair, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"sub":  user.ID,
+		"typ":  TokenTypeAccess,
 		"role": user.RoleID,
-		"exp":  time.Now().Add(24 * time.Hour).Unix(),
-		"iat":  time.Now().Unix(),
+		"sid":  sessionID,
+		"jti":  uuid.New().String(),
+		"exp":  now.Add(s.accessTTL).Unix(),
+		"iat":  now.Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString([]byte(s.jwtSecret))
+	access, err := s.keys.Sign(claims)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
-	return ss, user, nil
-}
 
-func (s *AuthService) Refresh(ctx context.Context, userID string) (string, error) {
-	if userID == "" {
-		return "", errors.New("user_id is required")
+	pair := &TokenPair{AccessToken: access, TokenType: "Bearer", ExpiresIn: int64(s.accessTTL.Seconds())}
+	if s.refreshRepo == nil {
+		return pair, nil
 	}
-	claims := jwt.MapClaims{
-		"sub": userID,
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
-		"iat": time.Now().Unix(),
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	pair.RefreshToken = base64.RawURLEncoding.EncodeToString(raw)
+	err = s.refreshRepo.Create(ctx, &pgModel.RefreshToken{
+		ID:        uuid.New().String(),
+		FamilyID:  sessionID,
+		ParentID:  parentID,
+		UserID:    user.ID,
+		TokenHash: hashToken(pair.RefreshToken),
+		ExpiresAt: now.Add(s.refreshTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString([]byte(s.jwtSecret))
-	return ss, err
+	return pair, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // Logout memasukkan token ke dalam blacklist hingga masa berlakunya habis
@@ -109,7 +536,14 @@ func (s *AuthService) Logout(ctx context.Context, tokenString string) error {
 		return errors.New("invalid token claims")
 	}
 
-	// 2. Ambil waktu kadaluarsa (exp)
+	// 2. Akhiri session ini beserta refresh token-nya (klaim 'sid'), juga bila access token sudah expired
+	if sid, ok := claims["sid"].(string); ok && sid != "" {
+		if err := s.sessions.Revoke(ctx, sid, SessionLogout); err != nil {
+			return err
+		}
+	}
+
+	// 3. Ambil waktu kadaluarsa (exp)
 	expFloat, ok := claims["exp"].(float64)
 	if !ok {
 		return errors.New("token does not have expiration time")
@@ -122,7 +556,7 @@ func (s *AuthService) Logout(ctx context.Context, tokenString string) error {
 		return nil 
 	}
 
-	// 3. Simpan token ke blacklist repository
+	// 4. Simpan token ke blacklist repository
 	// Token akan disimpan di DB/Redis sampai waktu 'expiresAt' tercapai
 	return s.tokenRepo.AddToBlacklist(ctx, tokenString, expiresAt)
 }
@@ -141,12 +575,7 @@ func (s *AuthService) VerifyToken(tokenString string) (map[string]interface{}, e
 	}
 
 	// 2. Standard JWT verification
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(s.jwtSecret), nil
-	})
+	token, err := s.keys.Parse(tokenString, jwt.MapClaims{})
 	if err != nil {
 		return nil, err
 	}
@@ -156,6 +585,9 @@ func (s *AuthService) VerifyToken(tokenString string) (map[string]interface{}, e
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok {
+		if typ, ok := claims["typ"].(string); ok && typ != TokenTypeAccess {
+			return nil, errors.New("not an access token")
+		}
 		return claims, nil
 	}
 	return nil, errors.New("invalid token claims")
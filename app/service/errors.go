@@ -0,0 +1,36 @@
+package service
+
+import "errors"
+
+var (
+	ErrNotFound      = &CustomError{"resource_not_found", "resource not found", 404}
+	ErrNotOwner      = &CustomError{"not_owner", "you are not the owner of this achievement", 403}
+	ErrInvalidState  = &CustomError{"invalid_status", "operation not allowed in current status", 409}
+	ErrChangesQueued = &CustomError{"changes_pending", "earlier changes to this achievement are still being saved, try again shortly", 409}
+)
+
+// CustomError carries an application error code together with the HTTP status
+// the route layer should answer with.
+type CustomError struct {
+	Code    string
+	Message string
+	Status  int
+}
+
+func (e *CustomError) Error() string {
+	return e.Message
+}
+
+// NewValidationError returns a 400 error with the given code and message.
+func NewValidationError(code, message string) *CustomError {
+	return &CustomError{Code: code, Message: message, Status: 400}
+}
+
+// ErrorStatus returns the HTTP status carried by err, or fallback when err is not a CustomError.
+func ErrorStatus(err error, fallback int) int {
+	var ce *CustomError
+	if errors.As(err, &ce) && ce.Status != 0 {
+		return ce.Status
+	}
+	return fallback
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	pgModel "UAS_BACKEND/app/model/postgre"
+	pgRepo "UAS_BACKEND/app/repository/postgre"
+
+	"github.com/google/uuid"
+)
+
+// Lockout defaults, overridable with LOGIN_LOCKOUT_THRESHOLD, LOGIN_LOCKOUT_BASE and LOGIN_LOCKOUT_MAX.
+// The first lock comes after DefaultLockoutThreshold failures and lasts DefaultLockoutBase; every
+// further failure doubles it up to DefaultLockoutMax.
+const (
+	DefaultLockoutThreshold = 5
+	DefaultLockoutBase      = time.Minute
+	DefaultLockoutMax       = time.Hour
+	// lockoutWindow: failures are forgotten after this long without another one
+	lockoutWindow = 24 * time.Hour
+)
+
+var ErrNotLocked = &CustomError{"not_locked", "the account has no failed logins to clear", 409}
+
+// Login audit events (activity_logs entity_type "login", entity_id = username)
+const (
+	LoginSucceeded  = "login_succeeded"
+	LoginFailed     = "login_failed"
+	AccountLocked   = "account_locked"
+	AccountUnlocked = "account_unlocked"
+)
+
+// LoginThrottle counts failed logins per username, locks the username progressively and writes
+// every login attempt to activity_logs with ip and user agent. Unknown usernames are counted
+// and locked the same way, so the lockout does not reveal which accounts exist.
+type LoginThrottle struct {
+	repo         pgRepo.LoginThrottleRepository
+	activityRepo pgRepo.ActivityLogRepository
+	threshold    int
+	base         time.Duration
+	max          time.Duration
+}
+
+func NewLoginThrottle(repo pgRepo.LoginThrottleRepository, activityRepo pgRepo.ActivityLogRepository) *LoginThrottle {
+	threshold, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_THRESHOLD"))
+	if err != nil || threshold <= 0 {
+		threshold = DefaultLockoutThreshold
+	}
+	return &LoginThrottle{
+		repo:         repo,
+		activityRepo: activityRepo,
+		threshold:    threshold,
+		base:         durationEnv("LOGIN_LOCKOUT_BASE", DefaultLockoutBase),
+		max:          durationEnv("LOGIN_LOCKOUT_MAX", DefaultLockoutMax),
+	}
+}
+
+// Check returns an account_locked error while the username is locked. Attempts during a lock
+// are logged but not counted, and the password is not checked.
+func (t *LoginThrottle) Check(ctx context.Context, username string, meta pgModel.SessionMeta) error {
+	if t.repo == nil {
+		return nil
+	}
+	key := throttleKey(username)
+	row, err := t.repo.Get(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if row.LockedUntil == nil || !time.Now().Before(*row.LockedUntil) {
+		return nil
+	}
+	t.log(ctx, key, nil, LoginFailed, meta, map[string]interface{}{"reason": "locked", "locked_until": row.LockedUntil})
+	return accountLockedError(*row.LockedUntil)
+}
+
+// Failure records a failed attempt (user is nil for unknown usernames) and locks the username
+// once the threshold is reached.
+func (t *LoginThrottle) Failure(ctx context.Context, username string, user *pgModel.User, meta pgModel.SessionMeta, reason string) {
+	key := throttleKey(username)
+	extra := map[string]interface{}{"reason": reason}
+	if t.repo == nil {
+		t.log(ctx, key, user, LoginFailed, meta, extra)
+		return
+	}
+	n, err := t.repo.RecordFailure(ctx, key, lockoutWindow)
+	if err != nil {
+		t.log(ctx, key, user, LoginFailed, meta, extra)
+		return
+	}
+	extra["failed_count"] = n
+	t.log(ctx, key, user, LoginFailed, meta, extra)
+	if n < t.threshold {
+		return
+	}
+
+	lock := t.base
+	for i := t.threshold; i < n && lock < t.max; i++ {
+		lock *= 2
+	}
+	if lock > t.max {
+		lock = t.max
+	}
+	until := time.Now().Add(lock)
+	if err := t.repo.Lock(ctx, key, until); err == nil {
+		t.log(ctx, key, user, AccountLocked, meta, map[string]interface{}{"failed_count": n, "locked_until": until})
+	}
+}
+
+// NotCounted logs a refused login that is not a guessing attempt (e.g. a disabled account
+// with the right password).
+func (t *LoginThrottle) NotCounted(ctx context.Context, user *pgModel.User, meta pgModel.SessionMeta, reason string) {
+	t.log(ctx, throttleKey(user.Username), user, LoginFailed, meta, map[string]interface{}{"reason": reason})
+}
+
+// Success clears the failures of the username and logs the login.
+func (t *LoginThrottle) Success(ctx context.Context, user *pgModel.User, meta pgModel.SessionMeta, sessionID string, mfa bool) {
+	key := throttleKey(user.Username)
+	if t.repo != nil {
+		_ = t.repo.Reset(ctx, key)
+	}
+	t.log(ctx, key, user, LoginSucceeded, meta, map[string]interface{}{"session_id": sessionID, "mfa": mfa})
+}
+
+// Unlock lifts the lock of user and forgets the failed attempts (admin action).
+func (t *LoginThrottle) Unlock(ctx context.Context, caller Caller, user *pgModel.User) (*pgModel.LoginThrottle, error) {
+	key := throttleKey(user.Username)
+	previous, err := t.repo.Get(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := t.repo.Reset(ctx, key); err != nil {
+		return nil, err
+	}
+	if t.activityRepo != nil {
+		_ = t.activityRepo.Create(ctx, &pgModel.ActivityLog{
+			ID:         uuid.New().String(),
+			EntityType: "login",
+			EntityID:   key,
+			EventType:  AccountUnlocked,
+			ActorID:    &caller.UserID,
+			ActorRole:  &caller.RoleID,
+			Previous:   map[string]interface{}{"failed_count": previous.FailedCount, "locked_until": previous.LockedUntil},
+			Metadata:   map[string]interface{}{"user_id": user.ID},
+			CreatedAt:  time.Now(),
+		})
+	}
+	return previous, nil
+}
+
+// log writes a login event (best-effort). The actor is the account when it exists.
+func (t *LoginThrottle) log(ctx context.Context, key string, user *pgModel.User, event string, meta pgModel.SessionMeta, extra map[string]interface{}) {
+	if t.activityRepo == nil {
+		return
+	}
+	metadata := map[string]interface{}{"ip": meta.IP, "user_agent": meta.UserAgent}
+	for k, v := range extra {
+		metadata[k] = v
+	}
+	entry := &pgModel.ActivityLog{
+		ID:         uuid.New().String(),
+		EntityType: "login",
+		EntityID:   key,
+		EventType:  event,
+		Metadata:   metadata,
+		CreatedAt:  time.Now(),
+	}
+	if user != nil {
+		entry.ActorID = &user.ID
+		entry.ActorRole = &user.RoleID
+		metadata["user_id"] = user.ID
+	}
+	_ = t.activityRepo.Create(ctx, entry)
+}
+
+func accountLockedError(until time.Time) error {
+	wait := time.Until(until).Round(time.Second)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return &CustomError{"account_locked", fmt.Sprintf("too many failed logins, try again in %s", wait), 429}
+}
+
+// throttleKey is the username exactly as login looks it up (users.username is compared
+// case-sensitively), cut to the 50 characters the column holds without splitting a UTF-8 sequence.
+func throttleKey(username string) string {
+	if utf8.RuneCountInString(username) <= 50 {
+		return username
+	}
+	return string([]rune(username)[:50])
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	pgModel "UAS_BACKEND/app/model/postgre"
+	pgRepo "UAS_BACKEND/app/repository/postgre"
+	"UAS_BACKEND/utils"
+
+	"github.com/google/uuid"
+)
+
+// RecoveryCodeCount is how many one-time recovery codes an enrollment gets.
+const RecoveryCodeCount = 10
+
+var (
+	ErrMFAAlreadyEnabled = &CustomError{"mfa_already_enabled", "two-factor authentication is already enabled", 409}
+	ErrMFANotEnrolled    = &CustomError{"mfa_not_enrolled", "start the enrollment first", 409}
+	ErrMFANotEnabled     = &CustomError{"mfa_not_enabled", "two-factor authentication is not enabled", 409}
+	ErrMFARequiredByRole = &CustomError{"mfa_required_by_role", "two-factor authentication is required for your role", 403}
+	ErrInvalidMFACode    = &CustomError{"invalid_mfa_code", "invalid or already used code", 401}
+)
+
+// MFAStatus is what a user sees about their own two-factor setup.
+type MFAStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	Required          bool       `json:"required"` // the user's role enforces MFA
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// MFAEnrollment is shown once while enrolling; the URI is meant to be rendered as a QR code.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// MFAService manages TOTP (RFC 6238) enrollment, verification and recovery codes.
+type MFAService struct {
+	mfaRepo      pgRepo.MFARepository
+	roleRepo     pgRepo.RoleRepository
+	activityRepo pgRepo.ActivityLogRepository
+	issuer       string
+}
+
+func NewMFAService(mfaRepo pgRepo.MFARepository, roleRepo pgRepo.RoleRepository, activityRepo pgRepo.ActivityLogRepository) *MFAService {
+	issuer := os.Getenv("MFA_ISSUER")
+	if issuer == "" {
+		issuer = "UAS Prestasi"
+	}
+	return &MFAService{mfaRepo: mfaRepo, roleRepo: roleRepo, activityRepo: activityRepo, issuer: issuer}
+}
+
+// Status reports the enrollment of user.
+func (s *MFAService) Status(ctx context.Context, user *pgModel.User) (*MFAStatus, error) {
+	required, err := s.Required(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	st := &MFAStatus{Required: required}
+	m, err := s.mfaRepo.Get(ctx, user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.EnabledAt != nil {
+		st.Enabled, st.EnabledAt = true, m.EnabledAt
+		if st.RecoveryCodesLeft, err = s.mfaRepo.CountRecoveryCodes(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// Enabled reports whether the user has a confirmed enrollment.
+func (s *MFAService) Enabled(ctx context.Context, userID string) (bool, error) {
+	if s.mfaRepo == nil {
+		return false, nil
+	}
+	m, err := s.mfaRepo.Get(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return m.EnabledAt != nil, nil
+}
+
+// Required reports whether the role of user enforces MFA.
+func (s *MFAService) Required(ctx context.Context, user *pgModel.User) (bool, error) {
+	if s.roleRepo == nil || user.RoleID == "" {
+		return false, nil
+	}
+	role, err := s.roleRepo.GetByID(ctx, user.RoleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return role.MFARequired, nil
+}
+
+// Begin creates a new secret for user. Until Confirm succeeds the enrollment is pending and
+// calling Begin again replaces the secret.
+func (s *MFAService) Begin(ctx context.Context, user *pgModel.User) (*MFAEnrollment, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.mfaRepo.SetPending(ctx, user.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	return &MFAEnrollment{Secret: secret, URI: utils.TOTPProvisioningURI(s.issuer, user.Username, secret)}, nil
+}
+
+// Confirm enables the pending enrollment once the user proves the authenticator works, and
+// returns the recovery codes (shown only this once).
+func (s *MFAService) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	m, err := s.mfaRepo.Get(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if err := s.useTOTP(ctx, m, code); err != nil {
+		return nil, err
+	}
+	codes, err := s.newRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.Enable(ctx, userID); err != nil {
+		return nil, err
+	}
+	s.log(ctx, userID, userID, "mfa_enabled", nil)
+	return codes, nil
+}
+
+// Verify accepts a current TOTP code or an unused recovery code of an enabled enrollment.
+func (s *MFAService) Verify(ctx context.Context, userID, code string) error {
+	m, err := s.mfaRepo.Get(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && m.EnabledAt == nil) {
+		return ErrMFANotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.useTOTP(ctx, m, code); err == nil || !errors.Is(err, ErrInvalidMFACode) {
+		return err
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	left, _ := s.mfaRepo.CountRecoveryCodes(ctx, userID)
+	s.log(ctx, userID, userID, "mfa_recovery_code_used", map[string]interface{}{"recovery_codes_left": left})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code after checking code.
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, err := s.newRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.log(ctx, userID, userID, "mfa_recovery_codes_regenerated", nil)
+	return codes, nil
+}
+
+// Disable removes the user's own enrollment after checking code. Not allowed while the role
+// requires MFA.
+func (s *MFAService) Disable(ctx context.Context, user *pgModel.User, code string) error {
+	required, err := s.Required(ctx, user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequiredByRole
+	}
+	if err := s.Verify(ctx, user.ID, code); err != nil {
+		return err
+	}
+	if err := s.mfaRepo.Delete(ctx, user.ID); err != nil {
+		return err
+	}
+	s.log(ctx, user.ID, user.ID, "mfa_disabled", nil)
+	return nil
+}
+
+// Reset removes the enrollment of another user (lost authenticator and recovery codes). If the
+// role requires MFA the user enrolls again at the next login.
+func (s *MFAService) Reset(ctx context.Context, caller Caller, userID string) error {
+	if _, err := s.mfaRepo.Get(ctx, userID); errors.Is(err, sql.ErrNoRows) {
+		return ErrMFANotEnabled
+	} else if err != nil {
+		return err
+	}
+	if err := s.mfaRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+	s.log(ctx, caller.UserID, userID, "mfa_reset", nil)
+	return nil
+}
+
+func (s *MFAService) useTOTP(ctx context.Context, m *pgModel.UserMFA, code string) error {
+	step, ok := utils.ValidateTOTP(m.Secret, code, time.Now())
+	if !ok || step <= m.LastUsedStep {
+		return ErrInvalidMFACode
+	}
+	fresh, err := s.mfaRepo.UseStep(ctx, m.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *MFAService) newRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	plain := make([]string, 0, RecoveryCodeCount)
+	rows := make([]*pgModel.MFARecoveryCode, 0, RecoveryCodeCount)
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		c := strings.ToLower(enc.EncodeToString(b))[:10]
+		plain = append(plain, c[:5]+"-"+c[5:])
+		rows = append(rows, &pgModel.MFARecoveryCode{ID: uuid.New().String(), UserID: userID, CodeHash: hashToken(c)})
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, rows); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
+
+// normalizeRecoveryCode accepts codes typed with or without the dash, in any case.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// log writes an MFA change of userID to activity_logs (best-effort).
+func (s *MFAService) log(ctx context.Context, actorID, userID, event string, metadata map[string]interface{}) {
+	if s.activityRepo == nil {
+		return
+	}
+	_ = s.activityRepo.Create(ctx, &pgModel.ActivityLog{
+		ID:         uuid.New().String(),
+		EntityType: "user",
+		EntityID:   userID,
+		EventType:  event,
+		ActorID:    &actorID,
+		Metadata:   metadata,
+		CreatedAt:  time.Now(),
+	})
+}
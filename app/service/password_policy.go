@@ -0,0 +1,116 @@
+package service
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Password policy defaults, overridable with PASSWORD_MIN_LENGTH, PASSWORD_MIN_CLASSES and
+// PASSWORD_HISTORY.
+const (
+	DefaultPasswordMinLength  = 10
+	DefaultPasswordMinClasses = 3 // of lowercase, uppercase, digits, symbols
+	DefaultPasswordHistory    = 5 // the current password and the 4 before it cannot be reused
+	// PasswordMaxBytes is the bcrypt input limit; longer passwords would be truncated silently.
+	PasswordMaxBytes = 72
+)
+
+var ErrPasswordReused = &CustomError{"password_reused", "the password was used recently, choose another one", 400}
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = func() map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, line := range strings.Split(commonPasswordList, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			set[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return set
+}()
+
+// PasswordPolicy is checked by AuthService wherever a password is set.
+type PasswordPolicy struct {
+	MinLength  int
+	MinClasses int
+	History    int // how many recent passwords (the current one included) cannot be reused, 0 = no check
+}
+
+// PasswordPolicyFromEnv reads the policy from the environment.
+func PasswordPolicyFromEnv() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:  intEnv("PASSWORD_MIN_LENGTH", DefaultPasswordMinLength),
+		MinClasses: intEnv("PASSWORD_MIN_CLASSES", DefaultPasswordMinClasses),
+		History:    intEnv("PASSWORD_HISTORY", DefaultPasswordHistory),
+	}
+}
+
+func intEnv(key string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n >= 0 {
+		return n
+	}
+	return fallback
+}
+
+// Check returns a weak_password error listing every rule password breaks. username is refused
+// as part of the password.
+func (p PasswordPolicy) Check(password, username string) error {
+	var problems []string
+	if n := len([]rune(password)); n < p.MinLength {
+		problems = append(problems, fmt.Sprintf("password is too short (at least %d characters)", p.MinLength))
+	}
+	if len(password) > PasswordMaxBytes {
+		problems = append(problems, fmt.Sprintf("password is too long (at most %d bytes)", PasswordMaxBytes))
+	}
+	if p.MinClasses > 1 && passwordClasses(password) < p.MinClasses {
+		problems = append(problems, fmt.Sprintf("password needs at least %d of: lowercase letters, uppercase letters, digits, symbols", p.MinClasses))
+	}
+	lower := strings.ToLower(password)
+	if isCommonPassword(lower) {
+		problems = append(problems, "password is too common")
+	}
+	if u := strings.ToLower(strings.TrimSpace(username)); len(u) >= 3 && strings.Contains(lower, u) {
+		problems = append(problems, "password contains the username")
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return &CustomError{"weak_password", strings.Join(problems, "; "), 400}
+}
+
+func passwordClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// isCommonPassword matches the blocklist as is and with trailing digits and symbols removed
+// ("Password2024!" is "password").
+func isCommonPassword(lower string) bool {
+	if _, ok := commonPasswords[lower]; ok {
+		return true
+	}
+	stem := strings.TrimRightFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) })
+	if stem == "" || stem == lower {
+		return false
+	}
+	_, ok := commonPasswords[stem]
+	return ok
+}
@@ -0,0 +1,139 @@
+package service
+
+import "strings"
+
+// Permission names checked by routes and services. Every name used in a RequirePermission guard
+// or a HasPermission call must be declared here and listed in PermissionManifest, so that
+// the seed command creates it and grants it to the default roles.
+const (
+	PermUserRead       = "user:read"
+	PermUserCreate     = "user:create"
+	PermUserUpdate     = "user:update"
+	PermUserDelete     = "user:delete"
+	PermUserAssignRole = "user:assign_role"
+	// PermUserRevokeSessions lets a role force-logout another user everywhere.
+	PermUserRevokeSessions = "user:revoke_sessions"
+	// PermUserResetMFA lets a role remove the two-factor enrollment of another user.
+	PermUserResetMFA = "user:reset_mfa"
+	// PermUserUnlock lets a role lift the lockout after failed logins.
+	PermUserUnlock = "user:unlock"
+
+	PermRoleRead   = "role:read"
+	PermRoleCreate = "role:create"
+	PermRoleUpdate = "role:update"
+	PermRoleDelete = "role:delete"
+	// PermRoleGrant lets a role attach and detach permissions of any role.
+	PermRoleGrant = "role:grant"
+
+	PermStudentManage = "student:manage"
+
+	PermAchievementCreate = "achievement:create"
+	PermAchievementUpdate = "achievement:update"
+	PermAchievementDelete = "achievement:delete"
+	PermAchievementSubmit = "achievement:submit"
+	PermAchievementVerify = "achievement:verify"
+	// PermReadAllAchievements lets a role see every student's achievements (admin).
+	PermReadAllAchievements = "achievement:read_all"
+	// PermVerifyAnyAchievement lets a role verify/reject achievements of students it does not advise (admin override).
+	PermVerifyAnyAchievement = "achievement:verify_any"
+
+	PermAchievementTypeManage = "achievement_type:manage"
+
+	PermReportView = "report:view"
+
+	PermOutboxRead   = "outbox:read"
+	PermOutboxManage = "outbox:manage"
+)
+
+// Default role names
+const (
+	RoleAdmin     = "Admin"
+	RoleMahasiswa = "Mahasiswa"
+	RoleDosenWali = "Dosen Wali"
+)
+
+// RoleSpec declares a default role.
+type RoleSpec struct {
+	Name        string
+	Description string
+}
+
+// PermissionSpec declares one permission and the default roles that are granted it.
+type PermissionSpec struct {
+	Name        string
+	Description string
+	Roles       []string
+}
+
+// Resource is the part of the name before the colon (e.g. "achievement").
+func (p PermissionSpec) Resource() string {
+	resource, _, _ := strings.Cut(p.Name, ":")
+	return resource
+}
+
+// Action is the part of the name after the colon (e.g. "create").
+func (p PermissionSpec) Action() string {
+	_, action, _ := strings.Cut(p.Name, ":")
+	return action
+}
+
+// DefaultRoles lists the roles the seed command creates.
+var DefaultRoles = []RoleSpec{
+	{Name: RoleAdmin, Description: "Administrator sistem, akses penuh"},
+	{Name: RoleMahasiswa, Description: "Mahasiswa, mengelola prestasi sendiri"},
+	{Name: RoleDosenWali, Description: "Dosen wali, memverifikasi prestasi mahasiswa bimbingan"},
+}
+
+// PermissionManifest is the declarative list of permissions and their default grants.
+// Admin is granted everything regardless of Roles.
+var PermissionManifest = []PermissionSpec{
+	{Name: PermUserRead, Description: "Melihat daftar dan detail user"},
+	{Name: PermUserCreate, Description: "Membuat user baru"},
+	{Name: PermUserUpdate, Description: "Mengubah data user"},
+	{Name: PermUserDelete, Description: "Menghapus user"},
+	{Name: PermUserAssignRole, Description: "Mengganti role user"},
+	{Name: PermUserRevokeSessions, Description: "Memaksa logout user dari semua perangkat"},
+	{Name: PermUserResetMFA, Description: "Mereset autentikasi dua faktor user"},
+	{Name: PermUserUnlock, Description: "Membuka kunci akun setelah terlalu banyak login gagal"},
+
+	{Name: PermRoleRead, Description: "Melihat role, permission dan pemegang role"},
+	{Name: PermRoleCreate, Description: "Membuat role baru"},
+	{Name: PermRoleUpdate, Description: "Mengubah nama dan deskripsi role"},
+	{Name: PermRoleDelete, Description: "Menghapus role yang tidak dipakai"},
+	{Name: PermRoleGrant, Description: "Menambah atau mencabut permission sebuah role"},
+
+	{Name: PermStudentManage, Description: "Mengatur data mahasiswa dan dosen wali"},
+
+	{Name: PermAchievementCreate, Description: "Membuat draft prestasi", Roles: []string{RoleMahasiswa}},
+	{Name: PermAchievementUpdate, Description: "Mengubah draft prestasi dan lampiran", Roles: []string{RoleMahasiswa}},
+	{Name: PermAchievementDelete, Description: "Menghapus draft prestasi", Roles: []string{RoleMahasiswa}},
+	{Name: PermAchievementSubmit, Description: "Mengajukan prestasi untuk diverifikasi", Roles: []string{RoleMahasiswa}},
+	{Name: PermAchievementVerify, Description: "Memverifikasi atau menolak prestasi mahasiswa bimbingan", Roles: []string{RoleDosenWali}},
+	{Name: PermReadAllAchievements, Description: "Melihat prestasi semua mahasiswa"},
+	{Name: PermVerifyAnyAchievement, Description: "Memverifikasi prestasi mahasiswa yang bukan bimbingan"},
+
+	{Name: PermAchievementTypeManage, Description: "Mengelola jenis prestasi dan skemanya"},
+
+	{Name: PermReportView, Description: "Melihat laporan dan statistik prestasi", Roles: []string{RoleMahasiswa, RoleDosenWali}},
+
+	{Name: PermOutboxRead, Description: "Melihat status outbox sinkronisasi"},
+	{Name: PermOutboxManage, Description: "Mengulang entri outbox yang gagal"},
+}
+
+// ManifestGrants returns the permission names granted to role by the manifest.
+func ManifestGrants(role string) []string {
+	out := []string{}
+	for _, p := range PermissionManifest {
+		if role == RoleAdmin {
+			out = append(out, p.Name)
+			continue
+		}
+		for _, r := range p.Roles {
+			if r == role {
+				out = append(out, p.Name)
+				break
+			}
+		}
+	}
+	return out
+}
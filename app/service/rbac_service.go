@@ -2,15 +2,38 @@ package service
 
 import (
 	"context"
+	"log"
+	"sync"
+	"time"
 
 	pgRepo "UAS_BACKEND/app/repository/postgre"
+
+	"github.com/lib/pq"
 )
 
-// RBACService checks role permissions
+// RBACInvalidateChannel is the Postgres NOTIFY channel fired by the role_permissions trigger;
+// the payload is the role id whose grants changed.
+const RBACInvalidateChannel = "rbac_invalidate"
+
+// DefaultPermissionCacheTTL bounds how stale a cached role can get when no invalidation arrives.
+const DefaultPermissionCacheTTL = time.Minute
+
+type rolePermissions struct {
+	names    map[string]bool
+	loadedAt time.Time
+}
+
+// RBACService checks role permissions. Each role's permission set is cached in memory for ttl;
+// Invalidate drops it earlier (called on every grant change, and from Listen for other instances).
 type RBACService struct {
 	rolePermRepo pgRepo.RolePermissionRepository
 	permRepo     pgRepo.PermissionRepository
 	roleRepo     pgRepo.RoleRepository
+
+	ttl   time.Duration
+	mu    sync.RWMutex
+	cache map[string]*rolePermissions
+	gen   uint64 // bumped by Invalidate so loads that started earlier are not cached
 }
 
 func NewRBACService(rp pgRepo.RolePermissionRepository, pr pgRepo.PermissionRepository, rr pgRepo.RoleRepository) *RBACService {
@@ -18,19 +41,100 @@ func NewRBACService(rp pgRepo.RolePermissionRepository, pr pgRepo.PermissionRepo
 		rolePermRepo: rp,
 		permRepo:     pr,
 		roleRepo:     rr,
+		ttl:          DefaultPermissionCacheTTL,
+		cache:        map[string]*rolePermissions{},
 	}
 }
 
+// SetCacheTTL changes the cache lifetime; ttl <= 0 disables caching.
+func (s *RBACService) SetCacheTTL(ttl time.Duration) {
+	s.mu.Lock()
+	s.ttl = ttl
+	s.gen++
+	s.cache = map[string]*rolePermissions{}
+	s.mu.Unlock()
+}
+
 // HasPermissionByRoleID returns true if the role has permission name (e.g. "achievement:verify")
 func (s *RBACService) HasPermissionByRoleID(ctx context.Context, roleID string, permName string) (bool, error) {
-	perms, err := s.rolePermRepo.ListByRole(ctx, roleID)
+	names, err := s.permissionsOf(ctx, roleID)
 	if err != nil {
 		return false, err
 	}
+	return names[permName], nil
+}
+
+// Invalidate drops the cached permissions of roleID, or of every role when roleID is empty.
+func (s *RBACService) Invalidate(roleID string) {
+	s.mu.Lock()
+	s.gen++
+	if roleID == "" {
+		s.cache = map[string]*rolePermissions{}
+	} else {
+		delete(s.cache, roleID)
+	}
+	s.mu.Unlock()
+}
+
+func (s *RBACService) permissionsOf(ctx context.Context, roleID string) (map[string]bool, error) {
+	s.mu.RLock()
+	ttl, gen := s.ttl, s.gen
+	entry := s.cache[roleID]
+	s.mu.RUnlock()
+	if entry != nil && time.Since(entry.loadedAt) < ttl {
+		return entry.names, nil
+	}
+
+	perms, err := s.rolePermRepo.ListByRole(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(perms))
 	for _, p := range perms {
-		if p.Name == permName {
-			return true, nil
+		names[p.Name] = true
+	}
+	if ttl > 0 {
+		s.mu.Lock()
+		if s.gen == gen {
+			s.cache[roleID] = &rolePermissions{names: names, loadedAt: time.Now()}
+		}
+		s.mu.Unlock()
+	}
+	return names, nil
+}
+
+// Listen subscribes to RBACInvalidateChannel so grant changes made by other instances (or directly
+// in the database) are seen immediately. It blocks until ctx is cancelled; while the connection is
+// down the cache is cleared and TTL expiry is the only bound on staleness.
+func (s *RBACService) Listen(ctx context.Context, dsn string) error {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("rbac listener: %v", err)
+		}
+		if ev == pq.ListenerEventReconnected || ev == pq.ListenerEventConnectionAttemptFailed {
+			// notifications may have been missed
+			s.Invalidate("")
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(RBACInvalidateChannel); err != nil {
+		return err
+	}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			if n == nil { // reconnected
+				s.Invalidate("")
+				continue
+			}
+			s.Invalidate(n.Extra)
+		case <-ping.C:
+			_ = listener.Ping()
 		}
 	}
-	return false, nil
 }
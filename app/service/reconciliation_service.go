@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sort"
+	"time"
+
+	mongoModel "UAS_BACKEND/app/model/mongo"
+	pgModel "UAS_BACKEND/app/model/postgre"
+	mongoRepo "UAS_BACKEND/app/repository/mongo"
+	pgRepo "UAS_BACKEND/app/repository/postgre"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of inconsistencies found between achievement_references and the achievements collection
+const (
+	FindingMissingDocument = "missing_document"       // live reference, no document at all
+	FindingDeletedDocument = "deleted_document"       // live reference, document soft-deleted
+	FindingLiveDocument    = "live_document"          // deleted reference, document not soft-deleted
+	FindingOrphanDocument  = "orphan_document"        // live document without any reference
+	FindingStudentMismatch = "student_mismatch"       // reference.student_id != document.studentId
+	FindingInvalidObjectID = "invalid_mongo_id"       // mongo_achievement_id is not an ObjectID
+	FindingSkippedInFlight = "skipped_outbox_pending" // outbox still has work for this reference
+)
+
+// ReconcileFinding is one inconsistency and what was (or would be) done about it.
+type ReconcileFinding struct {
+	Kind         string `json:"kind"`
+	ReferenceID  string `json:"reference_id,omitempty"`
+	DocumentID   string `json:"document_id,omitempty"`
+	RefStatus    string `json:"reference_status,omitempty"`
+	RefStudentID string `json:"reference_student_id,omitempty"`
+	DocStudentID string `json:"document_student_id,omitempty"`
+	Action       string `json:"action"`
+	Repaired     bool   `json:"repaired"`
+	Error        string `json:"error,omitempty"`
+}
+
+// ReconcileReport summarizes one reconciliation run.
+type ReconcileReport struct {
+	RunID             string             `json:"run_id"`
+	DryRun            bool               `json:"dry_run"`
+	StartedAt         time.Time          `json:"started_at"`
+	FinishedAt        time.Time          `json:"finished_at"`
+	ReferencesScanned int                `json:"references_scanned"`
+	DocumentsScanned  int                `json:"documents_scanned"`
+	Counts            map[string]int     `json:"counts"`
+	Repaired          int                `json:"repaired"`
+	RepairErrors      int                `json:"repair_errors"`
+	Findings          []ReconcileFinding `json:"findings"`
+}
+
+// ReconciliationService cross-checks achievement references against their Mongo documents.
+// Postgres is the source of truth: documents are restored, soft deleted or re-owned to match
+// their reference, and a reference is only marked deleted when its document is gone entirely.
+type ReconciliationService struct {
+	db               *sql.DB
+	achievementRefPG pgRepo.AchievementRefRepository
+	achievementMongo mongoRepo.AchievementRepository
+	outboxRepo       pgRepo.OutboxRepository
+	activityRepo     pgRepo.ActivityLogRepository
+}
+
+func NewReconciliationService(
+	db *sql.DB,
+	achievementRefPG pgRepo.AchievementRefRepository,
+	achievementMongo mongoRepo.AchievementRepository,
+	outboxRepo pgRepo.OutboxRepository,
+	activityRepo pgRepo.ActivityLogRepository,
+) *ReconciliationService {
+	return &ReconciliationService{
+		db:               db,
+		achievementRefPG: achievementRefPG,
+		achievementMongo: achievementMongo,
+		outboxRepo:       outboxRepo,
+		activityRepo:     activityRepo,
+	}
+}
+
+// Run scans both stores. With apply=false nothing is changed (dry run); with apply=true every finding
+// that has a repair is fixed. The summary is written to the activity log in both modes.
+func (s *ReconciliationService) Run(ctx context.Context, apply bool) (*ReconcileReport, error) {
+	report := &ReconcileReport{
+		RunID:     uuid.New().String(),
+		DryRun:    !apply,
+		StartedAt: time.Now(),
+		Counts:    map[string]int{},
+		Findings:  []ReconcileFinding{},
+	}
+
+	// references and in-flight aggregates first: a document created after this point belongs to a
+	// reference we do not know about yet, and repair re-checks it before touching anything
+	refs, err := s.achievementRefPG.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.ReferencesScanned = len(refs)
+
+	inFlight := map[string]bool{}
+	if s.outboxRepo != nil {
+		if inFlight, err = s.outboxRepo.UnfinishedAggregates(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	docs := map[string]*mongoModel.Achievement{}
+	err = s.achievementMongo.Iterate(ctx, func(a *mongoModel.Achievement) error {
+		docs[a.ID.Hex()] = a
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.DocumentsScanned = len(docs)
+
+	referenced := map[string]bool{}
+	for _, ref := range refs {
+		referenced[ref.MongoAchievementID] = true
+		if inFlight[ref.ID] {
+			report.add(ReconcileFinding{Kind: FindingSkippedInFlight, ReferenceID: ref.ID, DocumentID: ref.MongoAchievementID,
+				RefStatus: ref.Status, Action: "none"})
+			continue
+		}
+		for _, f := range checkReference(ref, docs[ref.MongoAchievementID]) {
+			if apply {
+				s.repair(ctx, &f)
+			}
+			report.add(f)
+		}
+	}
+
+	// live documents nobody points to
+	orphanIDs := make([]string, 0)
+	for id, doc := range docs {
+		if !referenced[id] && doc.DeletedAt == nil {
+			orphanIDs = append(orphanIDs, id)
+		}
+	}
+	sort.Strings(orphanIDs)
+	for _, id := range orphanIDs {
+		f := ReconcileFinding{Kind: FindingOrphanDocument, DocumentID: id, DocStudentID: docs[id].StudentID, Action: "soft_delete_document"}
+		if apply {
+			s.repair(ctx, &f)
+		}
+		report.add(f)
+	}
+
+	report.FinishedAt = time.Now()
+	s.writeSummary(ctx, report)
+	return report, nil
+}
+
+// RunEvery runs reconciliation every interval until ctx is cancelled.
+func (s *ReconciliationService) RunEvery(ctx context.Context, interval time.Duration, apply bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		report, err := s.Run(ctx, apply)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("reconcile: %v", err)
+			}
+			continue
+		}
+		if len(report.Findings) > 0 {
+			log.Printf("reconcile: run %s found %d issue(s) %v, repaired %d", report.RunID, len(report.Findings), report.Counts, report.Repaired)
+		}
+	}
+}
+
+// checkReference compares one reference with its document (nil when absent).
+func checkReference(ref *pgModel.AchievementReference, doc *mongoModel.Achievement) []ReconcileFinding {
+	base := ReconcileFinding{ReferenceID: ref.ID, DocumentID: ref.MongoAchievementID, RefStatus: ref.Status, RefStudentID: ref.StudentID}
+	deletedRef := ref.Status == StatusDeleted
+
+	if _, err := primitive.ObjectIDFromHex(ref.MongoAchievementID); err != nil {
+		if deletedRef {
+			return nil
+		}
+		base.Kind, base.Action = FindingInvalidObjectID, "mark_reference_deleted"
+		return []ReconcileFinding{base}
+	}
+
+	var out []ReconcileFinding
+	switch {
+	case doc == nil && !deletedRef:
+		base.Kind, base.Action = FindingMissingDocument, "mark_reference_deleted"
+		return []ReconcileFinding{base}
+	case doc == nil:
+		return nil
+	case doc.DeletedAt != nil && !deletedRef:
+		f := base
+		f.Kind, f.Action = FindingDeletedDocument, "restore_document"
+		out = append(out, f)
+	case doc.DeletedAt == nil && deletedRef:
+		f := base
+		f.Kind, f.Action = FindingLiveDocument, "soft_delete_document"
+		out = append(out, f)
+	}
+
+	if doc.StudentID != ref.StudentID {
+		f := base
+		f.Kind, f.Action, f.DocStudentID = FindingStudentMismatch, "set_document_student", doc.StudentID
+		out = append(out, f)
+	}
+	return out
+}
+
+// repair applies the finding's action; failures are recorded on the finding, not returned.
+// The reference row is locked and the document re-read first, and nothing is done when the
+// finding no longer holds (action "none"): the scan ran without locks and may be stale.
+func (s *ReconciliationService) repair(ctx context.Context, f *ReconcileFinding) {
+	err := pgRepo.RunInTx(ctx, s.db, func(tx *sql.Tx) error {
+		refs := s.achievementRefPG.WithTx(tx)
+		var ref *pgModel.AchievementReference
+		var err error
+		if f.ReferenceID != "" {
+			ref, err = refs.GetByIDForUpdate(ctx, f.ReferenceID)
+		} else if ref, err = refs.GetByMongoID(ctx, f.DocumentID); err == nil && ref != nil {
+			ref, err = refs.GetByIDForUpdate(ctx, ref.ID)
+		}
+		if err == sql.ErrNoRows {
+			ref, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+		if ref != nil && s.outboxRepo != nil {
+			pending, err := s.outboxRepo.WithTx(tx).ListUnfinished(ctx, ref.ID, outboxOperations)
+			if err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				f.Action = "none"
+				return nil
+			}
+		}
+
+		oid, _ := primitive.ObjectIDFromHex(f.DocumentID)
+		var doc *mongoModel.Achievement
+		if f.Kind != FindingInvalidObjectID {
+			if doc, err = s.achievementMongo.GetIncludingDeleted(ctx, oid); err != nil {
+				return err
+			}
+		}
+		if !stillHolds(f, ref, doc) {
+			f.Action = "none"
+			return nil
+		}
+
+		switch f.Action {
+		case "mark_reference_deleted":
+			return refs.UpdateStatus(ctx, ref.ID, StatusDeleted, nil)
+		case "restore_document":
+			return s.achievementMongo.Restore(ctx, oid)
+		case "soft_delete_document":
+			return s.achievementMongo.SoftDelete(ctx, oid)
+		case "set_document_student":
+			return s.achievementMongo.Update(ctx, oid, map[string]interface{}{"studentId": ref.StudentID})
+		}
+		return nil
+	})
+	if err != nil {
+		f.Error = err.Error()
+		return
+	}
+	f.Repaired = f.Action != "none"
+}
+
+// stillHolds reports whether finding f is still true for the current reference and document.
+func stillHolds(f *ReconcileFinding, ref *pgModel.AchievementReference, doc *mongoModel.Achievement) bool {
+	if f.Kind == FindingOrphanDocument {
+		return ref == nil && doc != nil && doc.DeletedAt == nil
+	}
+	if ref == nil || ref.MongoAchievementID != f.DocumentID {
+		return false
+	}
+	for _, current := range checkReference(ref, doc) {
+		if current.Kind == f.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ReconcileReport) add(f ReconcileFinding) {
+	r.Counts[f.Kind]++
+	if f.Repaired {
+		r.Repaired++
+	}
+	if f.Error != "" {
+		r.RepairErrors++
+	}
+	r.Findings = append(r.Findings, f)
+}
+
+// writeSummary stores the run totals in the activity log (best-effort, findings are not included).
+func (s *ReconciliationService) writeSummary(ctx context.Context, r *ReconcileReport) {
+	if s.activityRepo == nil {
+		return
+	}
+	counts := make(map[string]interface{}, len(r.Counts))
+	for k, v := range r.Counts {
+		counts[k] = v
+	}
+	_ = s.activityRepo.Create(ctx, &pgModel.ActivityLog{
+		ID:         uuid.New().String(),
+		EntityType: "reconciliation",
+		EntityID:   r.RunID,
+		EventType:  "reconciliation_run",
+		Current:    counts,
+		Metadata: map[string]interface{}{
+			"dry_run":            r.DryRun,
+			"references_scanned": r.ReferencesScanned,
+			"documents_scanned":  r.DocumentsScanned,
+			"repaired":           r.Repaired,
+			"repair_errors":      r.RepairErrors,
+			"duration_ms":        r.FinishedAt.Sub(r.StartedAt).Milliseconds(),
+		},
+		CreatedAt: r.FinishedAt,
+	})
+}
@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"sort"
 
+	pgModel "UAS_BACKEND/app/model/postgre"
 	pgRepo "UAS_BACKEND/app/repository/postgre"
 )
 
@@ -13,6 +16,7 @@ type ReportService struct {
 	studentRepo        pgRepo.StudentRepository
 	lecturerRepo       pgRepo.LecturerRepository
 	activityLogRepo    pgRepo.ActivityLogRepository // <-- Tambahkan ini
+	access             *AccessScope
 }
 
 // Update Constructor: Tambahkan parameter activityLogRepo
@@ -21,12 +25,14 @@ func NewReportService(
 	studentRepo pgRepo.StudentRepository,
 	lecturerRepo pgRepo.LecturerRepository,
 	activityLogRepo pgRepo.ActivityLogRepository, // <-- Tambahkan parameter
+	access *AccessScope,
 ) *ReportService {
 	return &ReportService{
 		achievementRefRepo: achievementRefRepo,
 		studentRepo:        studentRepo,
 		lecturerRepo:       lecturerRepo,
 		activityLogRepo:    activityLogRepo, // <-- Assign
+		access:             access,
 	}
 }
 
@@ -44,10 +50,25 @@ type TopStudentData struct {
 	AchievementCount int    `json:"achievement_count"`
 }
 
-// GetAllAchievementsStatistics returns overall statistics for all achievements
-func (s *ReportService) GetAllAchievementsStatistics(ctx context.Context) (*AchievementStatistics, error) {
+// GetAllAchievementsStatistics returns overall statistics for the achievements the caller may see
+// (admin: all, lecturer: advisees, student: own)
+func (s *ReportService) GetAllAchievementsStatistics(ctx context.Context, caller Caller) (*AchievementStatistics, error) {
+	viewer, err := s.access.Resolve(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+	studentIDs, err := s.access.VisibleStudentIDs(ctx, viewer)
+	if err != nil {
+		return nil, err
+	}
+
 	// 1. Ambil semua data (untuk skala besar, sebaiknya gunakan Query COUNT/GROUP BY di repository)
-	refs, err := s.achievementRefRepo.ListAll(ctx)
+	var refs []*pgModel.AchievementReference
+	if studentIDs == nil {
+		refs, err = s.achievementRefRepo.ListAll(ctx)
+	} else {
+		refs, err = s.achievementRefRepo.ListByStudents(ctx, studentIDs)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -119,9 +140,17 @@ func (s *ReportService) GetAllAchievementsStatistics(ctx context.Context) (*Achi
 }
 
 // GetStudentStatistics returns statistics for a specific student
-func (s *ReportService) GetStudentStatistics(ctx context.Context, studentID string) (map[string]interface{}, error) {
+func (s *ReportService) GetStudentStatistics(ctx context.Context, caller Caller, studentID string) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
+	viewer, err := s.access.Resolve(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.access.RequireStudent(ctx, viewer, studentID); err != nil {
+		return nil, err
+	}
+
 	// Get student basic info
 	student, err := s.studentRepo.GetByID(ctx, studentID)
 	if err != nil {
@@ -171,7 +200,22 @@ func (s *ReportService) GetStudentStatistics(ctx context.Context, studentID stri
 }
 
 // GetAchievementHistory retrieves activity logs for a specific achievement reference
-func (s *ReportService) GetAchievementHistory(ctx context.Context, refID string) (map[string]interface{}, error) {
+func (s *ReportService) GetAchievementHistory(ctx context.Context, caller Caller, refID string) (map[string]interface{}, error) {
+	viewer, err := s.access.Resolve(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := s.achievementRefRepo.GetByID(ctx, refID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := s.access.RequireStudent(ctx, viewer, ref.StudentID); err != nil {
+		return nil, err
+	}
+
 	// Panggil repository activity log
 	logs, err := s.activityLogRepo.ListByEntity(ctx, "achievement_reference", refID, 100, 0)
 	if err != nil {
@@ -184,15 +228,3 @@ func (s *ReportService) GetAchievementHistory(ctx context.Context, refID string)
 		"history":   logs,
 	}, nil
 }
-
-var ErrNotFound = &CustomError{"resource_not_found", "resource not found", 404}
-
-type CustomError struct {
-	Code    string
-	Message string
-	Status  int
-}
-
-func (e *CustomError) Error() string {
-	return e.Message
-}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	pgModel "UAS_BACKEND/app/model/postgre"
+	pgRepo "UAS_BACKEND/app/repository/postgre"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrRoleExists      = &CustomError{"role_exists", "a role with this name already exists", 409}
+	ErrRoleInUse       = &CustomError{"role_in_use", "role is still assigned to users", 409}
+	ErrRoleProtected   = &CustomError{"role_protected", "default roles cannot be renamed or deleted", 409}
+	ErrRoleSelfLockout = &CustomError{"self_lockout", "you cannot revoke role management from your own role", 409}
+)
+
+// RoleDetail is a role with the permissions granted to it.
+type RoleDetail struct {
+	*pgModel.Role
+	Permissions []*pgModel.Permission `json:"permissions"`
+}
+
+// RoleMember is the public view of a user holding a role.
+type RoleMember struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	FullName string `json:"full_name"`
+	IsActive bool   `json:"is_active"`
+}
+
+// RoleService manages roles and their permission grants. Every change is written to activity_logs.
+type RoleService struct {
+	roleRepo     pgRepo.RoleRepository
+	permRepo     pgRepo.PermissionRepository
+	rolePermRepo pgRepo.RolePermissionRepository
+	userRepo     pgRepo.UserRepository
+	activityRepo pgRepo.ActivityLogRepository
+	rbac         *RBACService
+}
+
+func NewRoleService(
+	roleRepo pgRepo.RoleRepository,
+	permRepo pgRepo.PermissionRepository,
+	rolePermRepo pgRepo.RolePermissionRepository,
+	userRepo pgRepo.UserRepository,
+	activityRepo pgRepo.ActivityLogRepository,
+	rbac *RBACService,
+) *RoleService {
+	return &RoleService{
+		roleRepo:     roleRepo,
+		permRepo:     permRepo,
+		rolePermRepo: rolePermRepo,
+		userRepo:     userRepo,
+		activityRepo: activityRepo,
+		rbac:         rbac,
+	}
+}
+
+func (s *RoleService) List(ctx context.Context) ([]*pgModel.Role, error) {
+	list, err := s.roleRepo.ListAll(ctx)
+	if list == nil {
+		list = []*pgModel.Role{}
+	}
+	return list, err
+}
+
+func (s *RoleService) ListPermissions(ctx context.Context) ([]*pgModel.Permission, error) {
+	list, err := s.permRepo.ListAll(ctx)
+	if list == nil {
+		list = []*pgModel.Permission{}
+	}
+	return list, err
+}
+
+func (s *RoleService) Get(ctx context.Context, id string) (*RoleDetail, error) {
+	role, err := s.loadRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := s.rolePermRepo.ListByRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if perms == nil {
+		perms = []*pgModel.Permission{}
+	}
+	return &RoleDetail{Role: role, Permissions: perms}, nil
+}
+
+// Members lists the users holding the role.
+func (s *RoleService) Members(ctx context.Context, id string) ([]RoleMember, error) {
+	if _, err := s.loadRole(ctx, id); err != nil {
+		return nil, err
+	}
+	users, err := s.userRepo.ListByRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]RoleMember, 0, len(users))
+	for _, u := range users {
+		out = append(out, RoleMember{ID: u.ID, Username: u.Username, Email: u.Email, FullName: u.FullName, IsActive: u.IsActive})
+	}
+	return out, nil
+}
+
+func (s *RoleService) Create(ctx context.Context, caller Caller, name, description string) (*pgModel.Role, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, NewValidationError("invalid_role", "name is required")
+	}
+	role := &pgModel.Role{ID: uuid.New().String(), Name: name, Description: strings.TrimSpace(description)}
+	if err := s.roleRepo.Create(ctx, role); err != nil {
+		return nil, roleWriteError(err)
+	}
+	s.log(ctx, caller, role.ID, "created", nil, roleSnapshot(role), nil)
+	return role, nil
+}
+
+func (s *RoleService) Update(ctx context.Context, caller Caller, id, name, description string) (*pgModel.Role, error) {
+	role, err := s.loadRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	previous := roleSnapshot(role)
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, NewValidationError("invalid_role", "name is required")
+	}
+	if name != role.Name && isDefaultRole(role.Name) {
+		return nil, ErrRoleProtected
+	}
+	role.Name = name
+	role.Description = strings.TrimSpace(description)
+	if err := s.roleRepo.Update(ctx, role); err != nil {
+		return nil, roleWriteError(err)
+	}
+	s.log(ctx, caller, role.ID, "updated", previous, roleSnapshot(role), nil)
+	return role, nil
+}
+
+// SetMFARequired makes TOTP mandatory (or optional again) for members of the role. Members
+// without an enrollment are asked to enroll at their next login.
+func (s *RoleService) SetMFARequired(ctx context.Context, caller Caller, id string, required bool) (*pgModel.Role, error) {
+	role, err := s.loadRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	previous := roleSnapshot(role)
+	if err := s.roleRepo.SetMFARequired(ctx, id, required); err != nil {
+		return nil, roleWriteError(err)
+	}
+	role.MFARequired = required
+	s.log(ctx, caller, role.ID, "mfa_requirement_changed", previous, roleSnapshot(role), nil)
+	return role, nil
+}
+
+// Delete removes a role that no user holds; default roles cannot be deleted.
+func (s *RoleService) Delete(ctx context.Context, caller Caller, id string) error {
+	role, err := s.loadRole(ctx, id)
+	if err != nil {
+		return err
+	}
+	if isDefaultRole(role.Name) {
+		return ErrRoleProtected
+	}
+	members, err := s.userRepo.ListByRole(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(members) > 0 {
+		return ErrRoleInUse
+	}
+	if err := s.roleRepo.Delete(ctx, id); err != nil {
+		return roleWriteError(err)
+	}
+	s.invalidate(id)
+	s.log(ctx, caller, id, "deleted", roleSnapshot(role), nil, nil)
+	return nil
+}
+
+// Grant attaches the permission (by name) to the role. Granting twice is a no-op and is not logged again.
+func (s *RoleService) Grant(ctx context.Context, caller Caller, roleID, permName string) error {
+	role, perm, held, err := s.loadGrant(ctx, roleID, permName)
+	if err != nil || held {
+		return err
+	}
+	if err := s.rolePermRepo.Assign(ctx, role.ID, perm.ID); err != nil {
+		return err
+	}
+	s.invalidate(role.ID)
+	s.log(ctx, caller, role.ID, "permission_granted", nil, nil, map[string]interface{}{"permission": perm.Name})
+	return nil
+}
+
+// Revoke detaches the permission from the role. Callers cannot revoke PermRoleGrant from their own role.
+func (s *RoleService) Revoke(ctx context.Context, caller Caller, roleID, permName string) error {
+	role, perm, held, err := s.loadGrant(ctx, roleID, permName)
+	if err != nil || !held {
+		return err
+	}
+	if perm.Name == PermRoleGrant && role.ID == caller.RoleID {
+		return ErrRoleSelfLockout
+	}
+	if err := s.rolePermRepo.Remove(ctx, role.ID, perm.ID); err != nil {
+		return err
+	}
+	s.invalidate(role.ID)
+	s.log(ctx, caller, role.ID, "permission_revoked", nil, nil, map[string]interface{}{"permission": perm.Name})
+	return nil
+}
+
+func (s *RoleService) loadRole(ctx context.Context, id string) (*pgModel.Role, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	role, err := s.roleRepo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return role, err
+}
+
+// loadGrant resolves the role and permission and reports whether the role already holds it.
+func (s *RoleService) loadGrant(ctx context.Context, roleID, permName string) (*pgModel.Role, *pgModel.Permission, bool, error) {
+	role, err := s.loadRole(ctx, roleID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	perm, err := s.permRepo.GetByName(ctx, permName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, false, NewValidationError("unknown_permission", "unknown permission "+permName)
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+	current, err := s.rolePermRepo.ListByRole(ctx, role.ID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	for _, p := range current {
+		if p.ID == perm.ID {
+			return role, perm, true, nil
+		}
+	}
+	return role, perm, false, nil
+}
+
+// invalidate drops the role from this instance's permission cache; other instances are told by
+// the role_permissions trigger (see RBACService.Listen).
+func (s *RoleService) invalidate(roleID string) {
+	if s.rbac != nil {
+		s.rbac.Invalidate(roleID)
+	}
+}
+
+// log writes a role change to activity_logs (best-effort).
+func (s *RoleService) log(ctx context.Context, caller Caller, roleID, event string, previous, current, metadata map[string]interface{}) {
+	if s.activityRepo == nil {
+		return
+	}
+	_ = s.activityRepo.Create(ctx, &pgModel.ActivityLog{
+		ID:         uuid.New().String(),
+		EntityType: "role",
+		EntityID:   roleID,
+		EventType:  event,
+		ActorID:    &caller.UserID,
+		ActorRole:  &caller.RoleID,
+		Previous:   previous,
+		Current:    current,
+		Metadata:   metadata,
+		CreatedAt:  time.Now(),
+	})
+}
+
+func roleSnapshot(r *pgModel.Role) map[string]interface{} {
+	return map[string]interface{}{"name": r.Name, "description": r.Description, "mfa_required": r.MFARequired}
+}
+
+func isDefaultRole(name string) bool {
+	for _, r := range DefaultRoles {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// roleWriteError maps repository errors of role writes to API errors.
+func roleWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+		return ErrRoleExists
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,411 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	mongoModel "UAS_BACKEND/app/model/mongo"
+	pgModel "UAS_BACKEND/app/model/postgre"
+	pgRepo "UAS_BACKEND/app/repository/postgre"
+
+	"github.com/google/uuid"
+)
+
+// SeedOptions controls what the seed command writes besides roles and permissions.
+type SeedOptions struct {
+	// AdminPassword creates the "admin" user when it does not exist yet (empty = skip,
+	// unless Demo is set, in which case DemoPassword is used).
+	AdminPassword string
+
+	Demo                   bool
+	DemoPassword           string // password of every generated demo account
+	Lecturers              int
+	Students               int
+	AchievementsPerStudent int
+	RandSeed               int64 // same seed, same names and achievements
+}
+
+// SeedReport counts what a seed run created or updated.
+type SeedReport struct {
+	Roles        int `json:"roles"`
+	Permissions  int `json:"permissions"`
+	Grants       int `json:"grants"`
+	Users        int `json:"users_created"`
+	Lecturers    int `json:"lecturers_created"`
+	Students     int `json:"students_created"`
+	Achievements int `json:"achievements_created"`
+}
+
+// SeedService writes the permission manifest and (optionally) demo data.
+// Every step is idempotent: existing rows are updated or reused, never duplicated.
+type SeedService struct {
+	roleRepo     pgRepo.RoleRepository
+	permRepo     pgRepo.PermissionRepository
+	rolePermRepo pgRepo.RolePermissionRepository
+	userRepo     pgRepo.UserRepository
+	studentRepo  pgRepo.StudentRepository
+	lecturerRepo pgRepo.LecturerRepository
+	refRepo      pgRepo.AchievementRefRepository
+	achievements *AchievementService
+	auth         *AuthService // password policy and hashing
+}
+
+func NewSeedService(
+	roleRepo pgRepo.RoleRepository,
+	permRepo pgRepo.PermissionRepository,
+	rolePermRepo pgRepo.RolePermissionRepository,
+	userRepo pgRepo.UserRepository,
+	studentRepo pgRepo.StudentRepository,
+	lecturerRepo pgRepo.LecturerRepository,
+	refRepo pgRepo.AchievementRefRepository,
+	achievements *AchievementService,
+	auth *AuthService,
+) *SeedService {
+	return &SeedService{
+		roleRepo:     roleRepo,
+		permRepo:     permRepo,
+		rolePermRepo: rolePermRepo,
+		userRepo:     userRepo,
+		studentRepo:  studentRepo,
+		lecturerRepo: lecturerRepo,
+		refRepo:      refRepo,
+		achievements: achievements,
+		auth:         auth,
+	}
+}
+
+// Run upserts the default roles and the permission manifest, grants the manifest permissions
+// (grants added by hand are kept), then creates the admin account and demo data if asked to.
+func (s *SeedService) Run(ctx context.Context, opts SeedOptions) (*SeedReport, error) {
+	report := &SeedReport{}
+
+	// seed passwords follow the same policy as every other password; fail before writing anything
+	if opts.AdminPassword != "" {
+		if err := s.auth.CheckPasswordPolicy(opts.AdminPassword, "admin"); err != nil {
+			return nil, fmt.Errorf("admin password: %w", err)
+		}
+	}
+	if opts.Demo && opts.DemoPassword != "" {
+		if err := s.auth.CheckPasswordPolicy(opts.DemoPassword, ""); err != nil {
+			return nil, fmt.Errorf("demo password: %w", err)
+		}
+	}
+
+	roleIDs := map[string]string{}
+	for _, spec := range DefaultRoles {
+		role := &pgModel.Role{ID: uuid.New().String(), Name: spec.Name, Description: spec.Description}
+		if err := s.roleRepo.Upsert(ctx, role); err != nil {
+			return nil, fmt.Errorf("role %s: %w", spec.Name, err)
+		}
+		roleIDs[spec.Name] = role.ID
+		report.Roles++
+	}
+
+	permIDs := map[string]string{}
+	for _, spec := range PermissionManifest {
+		perm := &pgModel.Permission{
+			ID:          uuid.New().String(),
+			Name:        spec.Name,
+			Resource:    spec.Resource(),
+			Action:      spec.Action(),
+			Description: spec.Description,
+		}
+		if err := s.permRepo.Upsert(ctx, perm); err != nil {
+			return nil, fmt.Errorf("permission %s: %w", spec.Name, err)
+		}
+		permIDs[spec.Name] = perm.ID
+		report.Permissions++
+	}
+
+	for _, spec := range DefaultRoles {
+		for _, name := range ManifestGrants(spec.Name) {
+			if err := s.rolePermRepo.Assign(ctx, roleIDs[spec.Name], permIDs[name]); err != nil {
+				return nil, fmt.Errorf("grant %s to %s: %w", name, spec.Name, err)
+			}
+			report.Grants++
+		}
+	}
+
+	adminPassword := opts.AdminPassword
+	if adminPassword == "" && opts.Demo {
+		adminPassword = opts.DemoPassword
+	}
+	if adminPassword != "" {
+		_, created, err := s.ensureUser(ctx, "admin", "admin@kampus.ac.id", "Administrator", roleIDs[RoleAdmin], adminPassword)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			report.Users++
+		}
+	}
+
+	if opts.Demo {
+		if err := s.seedDemo(ctx, opts, roleIDs, report); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// ensureUser returns the user with username, creating it when missing. An existing user is
+// returned untouched (its password and role are not reset).
+func (s *SeedService) ensureUser(ctx context.Context, username, email, fullName, roleID, password string) (*pgModel.User, bool, error) {
+	u, err := s.userRepo.GetByUsername(ctx, username)
+	if err == nil {
+		return u, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+	hash, err := s.auth.HashNewPassword(username, password)
+	if err != nil {
+		return nil, false, err
+	}
+	u = &pgModel.User{
+		ID:           uuid.New().String(),
+		Username:     username,
+		Email:        email,
+		PasswordHash: hash,
+		FullName:     fullName,
+		RoleID:       roleID,
+		IsActive:     true,
+	}
+	if err := s.userRepo.Create(ctx, u); err != nil {
+		return nil, false, fmt.Errorf("user %s: %w", username, err)
+	}
+	return u, true, nil
+}
+
+// ---- demo data ----
+
+var (
+	demoFirstNames = []string{
+		"Adi", "Ayu", "Bagus", "Citra", "Dewi", "Dimas", "Eka", "Fajar", "Gita", "Hendra",
+		"Indah", "Joko", "Kartika", "Lestari", "Made", "Nadia", "Putri", "Rizky", "Sari", "Taufik",
+		"Utami", "Wahyu", "Yoga", "Zahra",
+	}
+	demoLastNames = []string{
+		"Pratama", "Saputra", "Wulandari", "Hidayat", "Nugroho", "Kusuma", "Santoso", "Permata",
+		"Siregar", "Wijaya", "Lubis", "Setiawan", "Rahmawati", "Gunawan", "Harahap", "Susanto",
+	}
+	demoLecturerTitles = []string{"Dr.", "Prof. Dr.", ""}
+	demoDepartments    = []string{"Teknik Informatika", "Sistem Informasi", "Teknik Elektro", "Manajemen"}
+	demoPrograms       = []struct{ Code, Name string }{
+		{"11", "S1 Teknik Informatika"}, {"12", "S1 Sistem Informasi"},
+		{"21", "S1 Teknik Elektro"}, {"31", "S1 Manajemen"},
+	}
+	demoLevels = []string{"lokal", "regional", "nasional", "internasional"}
+	demoCities = []string{"Jakarta", "Bandung", "Surabaya", "Yogyakarta", "Malang", "Denpasar", "Makassar"}
+)
+
+type demoAccount struct {
+	user   *pgModel.User
+	caller Caller
+}
+
+func (s *SeedService) seedDemo(ctx context.Context, opts SeedOptions, roleIDs map[string]string, report *SeedReport) error {
+	if opts.DemoPassword == "" {
+		return errors.New("demo data needs a password for the generated accounts")
+	}
+	rng := rand.New(rand.NewSource(opts.RandSeed))
+
+	lecturers := make([]*pgModel.Lecturer, 0, opts.Lecturers)
+	lecturerAccounts := map[string]demoAccount{} // lecturers.id -> account
+	for i := 1; i <= opts.Lecturers; i++ {
+		first, last := pick(rng, demoFirstNames), pick(rng, demoLastNames)
+		name := strings.TrimSpace(pick(rng, demoLecturerTitles) + " " + first + " " + last)
+		username := fmt.Sprintf("dosen%02d", i)
+		u, created, err := s.ensureUser(ctx, username, username+"@kampus.ac.id", name, roleIDs[RoleDosenWali], opts.DemoPassword)
+		if err != nil {
+			return err
+		}
+		if created {
+			report.Users++
+		}
+
+		l, err := s.lecturerRepo.GetByUserID(ctx, u.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			l = &pgModel.Lecturer{
+				ID:         uuid.New().String(),
+				UserID:     u.ID,
+				LecturerID: fmt.Sprintf("DSN%03d", i),
+				Department: pick(rng, demoDepartments),
+			}
+			if err = s.lecturerRepo.Create(ctx, l); err == nil {
+				report.Lecturers++
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("lecturer %s: %w", username, err)
+		}
+		lecturers = append(lecturers, l)
+		lecturerAccounts[l.ID] = demoAccount{user: u, caller: Caller{UserID: u.ID, RoleID: u.RoleID}}
+	}
+
+	for i := 1; i <= opts.Students; i++ {
+		first, last := pick(rng, demoFirstNames), pick(rng, demoLastNames)
+		username := fmt.Sprintf("mhs%03d", i)
+		u, created, err := s.ensureUser(ctx, username, username+"@student.kampus.ac.id", first+" "+last, roleIDs[RoleMahasiswa], opts.DemoPassword)
+		if err != nil {
+			return err
+		}
+		if created {
+			report.Users++
+		}
+
+		st, err := s.studentRepo.GetByUserID(ctx, u.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			program := demoPrograms[rng.Intn(len(demoPrograms))]
+			year := 2020 + rng.Intn(5)
+			st = &pgModel.Student{
+				ID:           uuid.New().String(),
+				UserID:       u.ID,
+				StudentID:    fmt.Sprintf("%d%s%04d", year, program.Code, i),
+				Program:      program.Name,
+				AcademicYear: fmt.Sprintf("%d/%d", year, year+1),
+			}
+			if len(lecturers) > 0 {
+				advisor := lecturers[(i-1)%len(lecturers)].ID
+				st.AdvisorID = &advisor
+			}
+			if err = s.studentRepo.Create(ctx, st); err == nil {
+				report.Students++
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("student %s: %w", username, err)
+		}
+
+		if s.achievements == nil || opts.AchievementsPerStudent <= 0 {
+			continue
+		}
+		existing, err := s.refRepo.ListByStudent(ctx, st.ID)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			continue // already has achievements from an earlier run
+		}
+		student := demoAccount{user: u, caller: Caller{UserID: u.ID, RoleID: u.RoleID}}
+		var advisor *demoAccount
+		if st.AdvisorID != nil {
+			if acc, ok := lecturerAccounts[*st.AdvisorID]; ok {
+				advisor = &acc
+			}
+		}
+		for n := 0; n < opts.AchievementsPerStudent; n++ {
+			if err := s.seedAchievement(ctx, rng, student, advisor); err != nil {
+				return fmt.Errorf("achievement for %s: %w", username, err)
+			}
+			report.Achievements++
+		}
+	}
+	return nil
+}
+
+// seedAchievement creates one draft through AchievementService and walks it to a random status,
+// so the demo data carries the same revisions and activity log entries as real usage.
+func (s *SeedService) seedAchievement(ctx context.Context, rng *rand.Rand, student demoAccount, advisor *demoAccount) error {
+	doc := demoAchievement(rng, student.user.FullName)
+	ref, err := s.achievements.CreateDraft(ctx, student.user.ID, doc)
+	if err != nil {
+		return err
+	}
+
+	// roughly: 20% draft, 20% submitted, 45% verified, 15% rejected
+	roll := rng.Intn(100)
+	if roll < 20 {
+		return nil
+	}
+	if err := s.achievements.Submit(ctx, ref.ID, student.caller); err != nil {
+		return err
+	}
+	if roll < 40 || advisor == nil {
+		return nil
+	}
+	if roll < 85 {
+		return s.achievements.Verify(ctx, ref.ID, advisor.caller)
+	}
+	return s.achievements.Reject(ctx, ref.ID, advisor.caller, "Bukti pendukung kurang jelas, mohon unggah sertifikat yang terbaca.")
+}
+
+func demoAchievement(rng *rand.Rand, fullName string) *mongoModel.Achievement {
+	day := time.Now().AddDate(0, 0, -rng.Intn(900)).Format("2006-01-02")
+	level := pick(rng, demoLevels)
+
+	switch rng.Intn(5) {
+	case 0:
+		event := pick(rng, []string{"Gemastik", "Hackathon Nasional", "Kompetisi Robot Indonesia", "Olimpiade Sains Nasional", "Business Plan Competition"})
+		rank := pick(rng, []string{"Juara 1", "Juara 2", "Juara 3", "Harapan 1", "Finalis"})
+		return &mongoModel.Achievement{
+			Title: rank + " " + event, Type: "competition", Category: "lomba", Level: level,
+			Tags: []string{"kompetisi", strings.ToLower(strings.Fields(event)[0])},
+			Details: map[string]interface{}{
+				"competitionName": event,
+				"organizer":       pick(rng, []string{"Kemendikbudristek", "Puspresnas", "APTIKOM", "BEM Universitas"}),
+				"rank":            rank,
+				"eventDate":       day,
+				"location":        pick(rng, demoCities),
+				"participants":    10 + rng.Intn(300),
+			},
+		}
+	case 1:
+		title := pick(rng, []string{"Deteksi Dini Penyakit Padi", "Optimasi Rute Distribusi", "Analisis Sentimen Ulasan Produk", "Sistem Rekomendasi Wisata"})
+		return &mongoModel.Achievement{
+			Title: "Publikasi: " + title, Type: "publication", Category: "karya tulis", Level: level,
+			Tags: []string{"publikasi", "riset"},
+			Details: map[string]interface{}{
+				"publicationType": pick(rng, []string{"journal", "conference"}),
+				"authors":         []string{fullName, "Dr. " + pick(rng, demoFirstNames) + " " + pick(rng, demoLastNames)},
+				"publisher":       pick(rng, []string{"Jurnal Teknologi Informasi", "Prosiding SNATIKA", "Jurnal Sistem Cerdas"}),
+				"doi":             fmt.Sprintf("10.%d/demo.%d", 10000+rng.Intn(89999), rng.Intn(1000000)),
+				"publicationDate": day,
+			},
+		}
+	case 2:
+		org := pick(rng, []string{"Himpunan Mahasiswa Informatika", "BEM Fakultas", "UKM Robotika", "Paduan Suara Mahasiswa"})
+		return &mongoModel.Achievement{
+			Title: "Pengurus " + org, Type: "organization", Category: "organisasi", Level: "lokal",
+			Tags: []string{"organisasi"},
+			Details: map[string]interface{}{
+				"organizationName": org,
+				"position":         pick(rng, []string{"Ketua", "Wakil Ketua", "Sekretaris", "Bendahara", "Kepala Divisi"}),
+				"periodStart":      day,
+			},
+		}
+	case 3:
+		cert := pick(rng, []string{"AWS Certified Cloud Practitioner", "Cisco CCNA", "Oracle Certified Associate", "TOEFL ITP 550"})
+		return &mongoModel.Achievement{
+			Title: cert, Type: "certification", Category: "sertifikasi", Level: "internasional",
+			Tags: []string{"sertifikasi"},
+			Details: map[string]interface{}{
+				"certificationName": cert,
+				"issuedBy":          strings.Fields(cert)[0],
+				"certificateNumber": fmt.Sprintf("CERT-%06d", rng.Intn(1000000)),
+				"issueDate":         day,
+			},
+		}
+	default:
+		program := pick(rng, []string{"Beasiswa Unggulan", "IISMA", "Kampus Mengajar", "Pertukaran Mahasiswa Merdeka"})
+		return &mongoModel.Achievement{
+			Title: program, Type: "academic", Category: "akademik", Level: level,
+			Tags: []string{"akademik", "beasiswa"},
+			Details: map[string]interface{}{
+				"programName": program,
+				"institution": pick(rng, []string{"Kemendikbudristek", "LPDP", "Universitas Gadjah Mada", "Universitas Indonesia"}),
+				"period":      fmt.Sprintf("%d", 2021+rng.Intn(4)),
+				"score":       3 + float64(rng.Intn(100))/100,
+			},
+		}
+	}
+}
+
+func pick(rng *rand.Rand, items []string) string {
+	return items[rng.Intn(len(items))]
+}
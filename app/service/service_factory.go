@@ -7,46 +7,83 @@ import (
 
 	mongoRepo "UAS_BACKEND/app/repository/mongo"
 	pgRepo "UAS_BACKEND/app/repository/postgre"
+	"UAS_BACKEND/utils"
 )
 
 // Repos set of repo interfaces needed to create services
 type Repos struct {
-	UserRepo           pgRepo.UserRepository
-	RoleRepo           pgRepo.RoleRepository
-	PermissionRepo     pgRepo.PermissionRepository
-	RolePermissionRepo pgRepo.RolePermissionRepository
-	StudentRepo        pgRepo.StudentRepository
-	LecturerRepo       pgRepo.LecturerRepository
-	AchievementRefRepo pgRepo.AchievementRefRepository
-	AchievementRepo    mongoRepo.AchievementRepository
-	ActivityLogRepo    pgRepo.ActivityLogRepository // Pastikan ini ada
-	TokenRepo          TokenRepository
+	UserRepo                pgRepo.UserRepository
+	RoleRepo                pgRepo.RoleRepository
+	PermissionRepo          pgRepo.PermissionRepository
+	RolePermissionRepo      pgRepo.RolePermissionRepository
+	StudentRepo             pgRepo.StudentRepository
+	LecturerRepo            pgRepo.LecturerRepository
+	AchievementRefRepo      pgRepo.AchievementRefRepository
+	AchievementRepo         mongoRepo.AchievementRepository
+	AchievementTypeRepo     mongoRepo.AchievementTypeRepository
+	AchievementRevisionRepo mongoRepo.AchievementRevisionRepository
+	ActivityLogRepo         pgRepo.ActivityLogRepository // Pastikan ini ada
+	OutboxRepo              pgRepo.OutboxRepository
+	TokenRepo               TokenRepository
+	RefreshTokenRepo        pgRepo.RefreshTokenRepository
+	SessionRepo             pgRepo.SessionRepository
+	MFARepo                 pgRepo.MFARepository
+	LoginThrottleRepo       pgRepo.LoginThrottleRepository
+	UserTokenRepo           pgRepo.UserTokenRepository
+	PasswordHistoryRepo     pgRepo.PasswordHistoryRepository
 }
 
 type Services struct {
-	Achievement *AchievementService
-	User        *UserService
-	Auth        *AuthService
-	RBAC        *RBACService
-	Student     *StudentService
-	Lecturer    *LecturerService
-	Report      *ReportService
+	Achievement     *AchievementService
+	AchievementType *AchievementTypeService
+	User            *UserService
+	Auth            *AuthService
+	RBAC            *RBACService
+	Role            *RoleService
+	Session         *SessionService
+	LoginThrottle   *LoginThrottle
+	MFA             *MFAService
+	AccountEmail    *AccountEmailService
+	Student         *StudentService
+	Lecturer        *LecturerService
+	Report          *ReportService
+	Outbox          *AchievementOutbox
+	Reconciliation  *ReconciliationService
+	Seed            *SeedService
+	Keys            *utils.JWTKeySet // JWT signing and verification keys, published as JWKS
+	Files           utils.FileStore  // attachment storage (local disk or S3-compatible)
 }
 
-func NewServices(db *sql.DB, mongoDB *mongodriver.Database, repos *Repos) *Services {
+func NewServices(db *sql.DB, mongoDB *mongodriver.Database, repos *Repos, keys *utils.JWTKeySet, mailer utils.Mailer, files utils.FileStore, links *utils.LinkSigner) *Services {
 	// ... (kode lain tetap sama)
 
+	rbacSvc := NewRBACService(repos.RolePermissionRepo, repos.PermissionRepo, repos.RoleRepo)
+	roleSvc := NewRoleService(repos.RoleRepo, repos.PermissionRepo, repos.RolePermissionRepo, repos.UserRepo, repos.ActivityLogRepo, rbacSvc)
+	access := NewAccessScope(repos.StudentRepo, repos.LecturerRepo, rbacSvc)
+	achTypeSvc := NewAchievementTypeService(repos.AchievementTypeRepo)
+	outbox := NewAchievementOutbox(db, repos.OutboxRepo, repos.AchievementRefRepo, repos.AchievementRepo, repos.AchievementRevisionRepo, files)
+
 	achSvc := NewAchievementService(
 		repos.AchievementRepo,
 		repos.AchievementRefRepo,
 		repos.StudentRepo,
 		repos.UserRepo,
 		repos.ActivityLogRepo,
+		achTypeSvc,
+		access,
+		repos.AchievementRevisionRepo,
+		db,
+		outbox,
+		files,
+		links,
 	)
 
-	userSvc := NewUserService(repos.UserRepo)
-	authSvc := NewAuthService(repos.UserRepo, repos.TokenRepo)
-	rbacSvc := NewRBACService(repos.RolePermissionRepo, repos.PermissionRepo, repos.RoleRepo)
+	sessionSvc := NewSessionService(repos.SessionRepo, repos.RefreshTokenRepo)
+	mfaSvc := NewMFAService(repos.MFARepo, repos.RoleRepo, repos.ActivityLogRepo)
+	throttle := NewLoginThrottle(repos.LoginThrottleRepo, repos.ActivityLogRepo)
+	authSvc := NewAuthService(repos.UserRepo, repos.TokenRepo, repos.RefreshTokenRepo, sessionSvc, mfaSvc, throttle, keys, repos.PasswordHistoryRepo, repos.ActivityLogRepo)
+	accountEmailSvc := NewAccountEmailService(repos.UserRepo, repos.UserTokenRepo, repos.ActivityLogRepo, authSvc, sessionSvc, mailer)
+	userSvc := NewUserService(repos.UserRepo, sessionSvc, accountEmailSvc)
 	studentSvc := NewStudentService(repos.StudentRepo)
 	lecturerSvc := NewLecturerService(repos.LecturerRepo)
 
@@ -56,15 +93,41 @@ func NewServices(db *sql.DB, mongoDB *mongodriver.Database, repos *Repos) *Servi
 		repos.StudentRepo,
 		repos.LecturerRepo,
 		repos.ActivityLogRepo, // <-- Masukkan dependency ActivityLogRepo
+		access,
+	)
+
+	reconcileSvc := NewReconciliationService(db, repos.AchievementRefRepo, repos.AchievementRepo, repos.OutboxRepo, repos.ActivityLogRepo)
+
+	seedSvc := NewSeedService(
+		repos.RoleRepo,
+		repos.PermissionRepo,
+		repos.RolePermissionRepo,
+		repos.UserRepo,
+		repos.StudentRepo,
+		repos.LecturerRepo,
+		repos.AchievementRefRepo,
+		achSvc,
+		authSvc,
 	)
 
 	return &Services{
-		Achievement: achSvc,
-		User:        userSvc,
-		Auth:        authSvc,
-		RBAC:        rbacSvc,
-		Student:     studentSvc,
-		Lecturer:    lecturerSvc,
-		Report:      reportSvc,
+		Achievement:     achSvc,
+		AchievementType: achTypeSvc,
+		User:            userSvc,
+		Auth:            authSvc,
+		RBAC:            rbacSvc,
+		Role:            roleSvc,
+		Session:         sessionSvc,
+		LoginThrottle:   throttle,
+		MFA:             mfaSvc,
+		AccountEmail:    accountEmailSvc,
+		Student:         studentSvc,
+		Lecturer:        lecturerSvc,
+		Report:          reportSvc,
+		Outbox:          outbox,
+		Reconciliation:  reconcileSvc,
+		Seed:            seedSvc,
+		Keys:            keys,
+		Files:           files,
 	}
 }
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	pgModel "UAS_BACKEND/app/model/postgre"
+	pgRepo "UAS_BACKEND/app/repository/postgre"
+
+	"github.com/google/uuid"
+)
+
+// Session revocation reasons
+const (
+	SessionLogout          = "logout"
+	SessionRevoked         = "revoked"          // by the user from the session list
+	SessionForceLogout     = "force_logout"     // by an admin
+	SessionRoleChanged     = "role_changed"     // the access token's role claim is stale
+	SessionDeactivated     = "deactivated"      // the account was disabled
+	SessionPasswordReset   = "password_reset"   // the password was reset through the emailed link
+	SessionPasswordChanged = "password_changed" // by the user (other sessions) or an admin (all)
+)
+
+const (
+	// sessionCheckTTL is how long a positive IsActive answer is reused; a revocation made on
+	// another instance takes at most this long to reject access tokens here.
+	sessionCheckTTL = 15 * time.Second
+	// sessionTouchInterval limits last_seen_at writes to one per session per interval.
+	sessionTouchInterval = time.Minute
+)
+
+// SessionService records where users are logged in and revokes logins. A session is revoked
+// together with its refresh token family, and access tokens carrying its id stop working.
+type SessionService struct {
+	sessionRepo pgRepo.SessionRepository
+	refreshRepo pgRepo.RefreshTokenRepository
+
+	mu     sync.Mutex
+	active map[string]time.Time // session id -> last positive check
+}
+
+func NewSessionService(sessionRepo pgRepo.SessionRepository, refreshRepo pgRepo.RefreshTokenRepository) *SessionService {
+	return &SessionService{
+		sessionRepo: sessionRepo,
+		refreshRepo: refreshRepo,
+		active:      map[string]time.Time{},
+	}
+}
+
+// Start records a new login and returns its id.
+func (s *SessionService) Start(ctx context.Context, userID string, meta pgModel.SessionMeta) (string, error) {
+	sess := &pgModel.Session{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Device:    meta.Device,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+	}
+	if sess.Device == "" {
+		sess.Device = deviceFromUserAgent(meta.UserAgent)
+	}
+	if len(sess.Device) > 100 {
+		sess.Device = sess.Device[:100]
+	}
+	if s.sessionRepo == nil {
+		return sess.ID, nil
+	}
+	return sess.ID, s.sessionRepo.Create(ctx, sess)
+}
+
+// IsActive reports whether access tokens of the session are still accepted, and updates
+// last_seen_at now and then.
+func (s *SessionService) IsActive(ctx context.Context, sessionID, ip string) (bool, error) {
+	if s.sessionRepo == nil {
+		return true, nil
+	}
+	s.mu.Lock()
+	checked, ok := s.active[sessionID]
+	s.mu.Unlock()
+	if ok && time.Since(checked) < sessionCheckTTL {
+		return true, nil
+	}
+
+	sess, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if sess.RevokedAt != nil {
+		s.forget(sessionID)
+		return false, nil
+	}
+	s.mu.Lock()
+	if len(s.active) >= 10000 {
+		for id, at := range s.active {
+			if time.Since(at) >= sessionCheckTTL {
+				delete(s.active, id)
+			}
+		}
+	}
+	s.active[sessionID] = time.Now()
+	s.mu.Unlock()
+	_ = s.sessionRepo.Touch(ctx, sessionID, ip, sessionTouchInterval)
+	return true, nil
+}
+
+// Seen updates last_seen_at (used on refresh, which does not go through IsActive).
+func (s *SessionService) Seen(ctx context.Context, sessionID, ip string) {
+	if s.sessionRepo != nil {
+		_ = s.sessionRepo.Touch(ctx, sessionID, ip, sessionTouchInterval)
+	}
+}
+
+// List returns the active sessions of the user; currentID marks the caller's own session.
+func (s *SessionService) List(ctx context.Context, userID, currentID string) ([]*pgModel.Session, error) {
+	list, err := s.sessionRepo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sess := range list {
+		sess.Current = sess.ID == currentID
+	}
+	return list, nil
+}
+
+// RevokeOwn revokes one session of userID; sessions of other users are reported as not found.
+func (s *SessionService) RevokeOwn(ctx context.Context, userID, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrNotFound
+	}
+	sess, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && sess.UserID != userID) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.Revoke(ctx, sessionID, SessionRevoked)
+}
+
+// Revoke ends one session and its refresh tokens.
+func (s *SessionService) Revoke(ctx context.Context, sessionID, reason string) error {
+	s.forget(sessionID)
+	if s.sessionRepo != nil {
+		if _, err := s.sessionRepo.Revoke(ctx, sessionID, reason); err != nil {
+			return err
+		}
+	}
+	if s.refreshRepo != nil {
+		return s.refreshRepo.RevokeFamily(ctx, sessionID, reason)
+	}
+	return nil
+}
+
+// RevokeUser ends every session of the user (force logout everywhere) and returns how many were active.
+func (s *SessionService) RevokeUser(ctx context.Context, userID, reason string) (int64, error) {
+	if s.sessionRepo == nil {
+		return 0, nil
+	}
+	list, err := s.sessionRepo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	for _, sess := range list {
+		s.forget(sess.ID)
+	}
+	n, err := s.sessionRepo.RevokeUser(ctx, userID, reason)
+	if err != nil {
+		return 0, err
+	}
+	if s.refreshRepo != nil {
+		if err := s.refreshRepo.RevokeUser(ctx, userID, reason); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// RevokeOthers ends every session of the user except keepID and returns how many it ended.
+func (s *SessionService) RevokeOthers(ctx context.Context, userID, keepID, reason string) (int64, error) {
+	if s.sessionRepo == nil {
+		return 0, nil
+	}
+	list, err := s.sessionRepo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, sess := range list {
+		if sess.ID == keepID {
+			continue
+		}
+		if err := s.Revoke(ctx, sess.ID, reason); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (s *SessionService) forget(sessionID string) {
+	s.mu.Lock()
+	delete(s.active, sessionID)
+	s.mu.Unlock()
+}
+
+// deviceFromUserAgent gives a short "Browser on OS" label for sessions without a device name.
+func deviceFromUserAgent(ua string) string {
+	if ua == "" {
+		return "Unknown device"
+	}
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "okhttp"), strings.Contains(ua, "Dart/"):
+		browser = "Mobile app"
+	case strings.Contains(ua, "curl/"), strings.Contains(ua, "PostmanRuntime"):
+		browser = strings.SplitN(ua, "/", 2)[0]
+	}
+	osName := ""
+	switch {
+	case strings.Contains(ua, "Android"):
+		osName = "Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		osName = "iOS"
+	case strings.Contains(ua, "Windows"):
+		osName = "Windows"
+	case strings.Contains(ua, "Mac OS X"):
+		osName = "macOS"
+	case strings.Contains(ua, "Linux"):
+		osName = "Linux"
+	}
+	if osName == "" {
+		return browser
+	}
+	return browser + " on " + osName
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"sync"
 	"time"
 
 	pgModel "UAS_BACKEND/app/model/postgre"
@@ -11,15 +13,67 @@ import (
 	"github.com/google/uuid"
 )
 
+// userActiveTTL is how long a positive IsActive answer is reused by the JWT middleware.
+const userActiveTTL = 15 * time.Second
+
 type UserService struct {
-	userRepo pgRepo.UserRepository
+	userRepo     pgRepo.UserRepository
+	sessions     *SessionService
+	accountEmail *AccountEmailService // sends the verification mail of new accounts
+
+	mu     sync.Mutex
+	active map[string]time.Time // user id -> last positive check
 }
 
-func NewUserService(userRepo pgRepo.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+func NewUserService(userRepo pgRepo.UserRepository, sessions *SessionService, accountEmail *AccountEmailService) *UserService {
+	return &UserService{userRepo: userRepo, sessions: sessions, accountEmail: accountEmail, active: map[string]time.Time{}}
 }
 
-// Register creates a new user (password hashing done in AuthService)
+// IsActive reports whether the account exists and is not disabled (checked on every request by
+// the JWT middleware; positive answers are cached for a few seconds).
+func (s *UserService) IsActive(ctx context.Context, userID string) (bool, error) {
+	if s.userRepo == nil {
+		return true, nil
+	}
+	s.mu.Lock()
+	checked, ok := s.active[userID]
+	s.mu.Unlock()
+	if ok && time.Since(checked) < userActiveTTL {
+		return true, nil
+	}
+
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !u.IsActive {
+		s.forget(userID)
+		return false, nil
+	}
+	s.mu.Lock()
+	if len(s.active) >= 10000 {
+		for id, at := range s.active {
+			if time.Since(at) >= userActiveTTL {
+				delete(s.active, id)
+			}
+		}
+	}
+	s.active[userID] = time.Now()
+	s.mu.Unlock()
+	return true, nil
+}
+
+func (s *UserService) forget(userID string) {
+	s.mu.Lock()
+	delete(s.active, userID)
+	s.mu.Unlock()
+}
+
+// Register creates a new user (password hashing done in AuthService) and mails the email
+// verification link in the background.
 func (s *UserService) Register(ctx context.Context, u *pgModel.User) error {
 	// simple validations
 	if u.Username == "" || u.Email == "" || u.PasswordHash == "" {
@@ -27,9 +81,16 @@ func (s *UserService) Register(ctx context.Context, u *pgModel.User) error {
 	}
 	u.ID = uuid.New().String()
 	u.IsActive = true
+	u.EmailVerifiedAt = nil
 	u.CreatedAt = time.Now()
 	u.UpdatedAt = time.Now()
-	return s.userRepo.Create(ctx, u)
+	if err := s.userRepo.Create(ctx, u); err != nil {
+		return err
+	}
+	if s.accountEmail != nil {
+		s.accountEmail.SendVerificationAsync(u)
+	}
+	return nil
 }
 
 func (s *UserService) GetByID(ctx context.Context, id string) (*pgModel.User, error) {
@@ -40,12 +101,32 @@ func (s *UserService) GetByUsername(ctx context.Context, username string) (*pgMo
 	return s.userRepo.GetByUsername(ctx, username)
 }
 
+// Update saves the user; deactivating an active account also ends all of its sessions.
 func (s *UserService) Update(ctx context.Context, u *pgModel.User) error {
+	stored, err := s.userRepo.GetByID(ctx, u.ID)
+	if err != nil {
+		return err
+	}
 	u.UpdatedAt = time.Now()
-	return s.userRepo.Update(ctx, u)
+	if err := s.userRepo.Update(ctx, u); err != nil {
+		return err
+	}
+	switch {
+	case stored.IsActive && !u.IsActive:
+		s.forget(u.ID)
+		_, err := s.sessions.RevokeUser(ctx, u.ID, SessionDeactivated)
+		return err
+	case stored.RoleID != u.RoleID:
+		// access tokens carry the role; make the user log in again to pick up the new one
+		s.forget(u.ID)
+		_, err := s.sessions.RevokeUser(ctx, u.ID, SessionRoleChanged)
+		return err
+	}
+	return nil
 }
 
 func (s *UserService) Delete(ctx context.Context, id string) error {
+	s.forget(id)
 	return s.userRepo.Delete(ctx, id)
 }
 
@@ -57,5 +138,10 @@ func (s *UserService) UpdateRole(ctx context.Context, userID string, roleID stri
 	if userID == "" || roleID == "" {
 		return errors.New("user_id and role_id are required")
 	}
-	return s.userRepo.UpdateRole(ctx, userID, roleID)
+	if err := s.userRepo.UpdateRole(ctx, userID, roleID); err != nil {
+		return err
+	}
+	// access tokens carry the role; make the user log in again to pick up the new one
+	_, err := s.sessions.RevokeUser(ctx, userID, SessionRoleChanged)
+	return err
 }
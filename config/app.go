@@ -11,6 +11,8 @@ import (
 // Optionally pass a custom logger writer (from InitLogger) to wire into fiber logger.
 func NewFiberApp(logWriter ...interface{}) *fiber.App {
 	app := fiber.New(fiber.Config{
+		// multipart uploads: the largest attachment plus room for the form encoding
+		BodyLimit: (Get().AttachmentMaxFileMB + 1) << 20,
 		// you can set ReadTimeout/WriteTimeout here if you want
 		// ReadTimeout:  5 * time.Second,
 		// WriteTimeout: 10 * time.Second,
@@ -1,14 +1,21 @@
 package config
 
 import (
+	"errors"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/joho/godotenv"
 )
 
+// DefaultJWTSecret is the development fallback for JWT_SECRET; Validate rejects it in production.
+const DefaultJWTSecret = "dev-secret"
+
 type Config struct {
+	AppEnv      string // "production" enables the startup checks in Validate
 	AppPort     string
 	DBDriver    string
 	PostgresDsn string
@@ -16,6 +23,46 @@ type Config struct {
 	JWTSecret   string
 	LogPath     string
 	LogLevel    string
+
+	AutoMigrate        bool // apply pending migrations on startup
+	OutboxPollInterval string
+	ReconcileInterval  string // empty disables the scheduled reconciliation
+	ReconcileApply     bool   // scheduled runs repair instead of only reporting
+
+	RBACCacheTTL string // lifetime of cached role permissions, "0" disables the cache
+	RBACListen   bool   // LISTEN on rbac_invalidate to drop cache entries changed by other instances
+
+	JWTAlg              string   // RS256 (default), EdDSA, or HS256 with JWT_SECRET
+	JWTPrivateKeyFile   string   // PEM of the active signing key
+	JWTKeyID            string   // kid of the active key, default is the key thumbprint
+	JWTPreviousKeyFiles []string // comma separated PEM files that still verify tokens after a rotation
+
+	MailDriver      string // "log" (default, development) or "smtp"
+	SMTPHost        string
+	SMTPPort        string
+	SMTPUsername    string // empty disables SMTP AUTH
+	SMTPPassword    string
+	SMTPImplicitTLS bool // TLS from the first byte (port 465) instead of STARTTLS
+	MailFrom        string
+
+	StorageDriver     string // "local" (default) or "s3"
+	StorageLocalDir   string // root directory of the local driver
+	StorageLocalURL   string // URL prefix the app serves local presigned links under
+	StorageSigningKey string // HMAC key of local file links and attachment download links; random per start when empty
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKey       string
+	S3SecretKey       string
+	S3PathStyle       bool // bucket in the path (MinIO) instead of the host name
+
+	AttachmentMaxFileMB  int // per uploaded file; also sets the request body limit
+	AttachmentMaxTotalMB int // all attachments of one achievement together
+	AttachmentMaxCount   int // attachments per achievement
+
+	ScanDriver        string // "none" (default, every file counts as clean) or "clamd"
+	ClamdAddress      string // tcp://host:3310 or unix:///path/to/clamd.sock
+	ScanRetryInterval string // how often attachments left pending are scanned again
 }
 
 // singleton config
@@ -33,13 +80,54 @@ func LoadEnv() error {
 		_ = godotenv.Load()
 
 		c := &Config{
+			AppEnv:      getEnv("APP_ENV", "development"),
 			AppPort:     getEnv("APP_PORT", "3000"),
 			DBDriver:    getEnv("DB_DRIVER", "mongo"), // mongo or postgres
 			PostgresDsn: getEnv("POSTGRES_DSN", ""),
 			MongoURI:    getEnv("MONGO_URI", ""),
-			JWTSecret:   getEnv("JWT_SECRET", "dev-secret"),
+			JWTSecret:   getEnv("JWT_SECRET", DefaultJWTSecret),
 			LogPath:     getEnv("LOG_PATH", "logs/app.log"),
 			LogLevel:    getEnv("LOG_LEVEL", "info"),
+
+			AutoMigrate:        getEnv("AUTO_MIGRATE", "true") == "true",
+			OutboxPollInterval: getEnv("OUTBOX_POLL_INTERVAL", "5s"),
+			ReconcileInterval:  getEnv("RECONCILE_INTERVAL", ""),
+			ReconcileApply:     getEnv("RECONCILE_APPLY", "false") == "true",
+
+			RBACCacheTTL: getEnv("RBAC_CACHE_TTL", "1m"),
+			RBACListen:   getEnv("RBAC_LISTEN", "false") == "true",
+
+			JWTAlg:              getEnv("JWT_ALG", "RS256"),
+			JWTPrivateKeyFile:   getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			JWTKeyID:            getEnv("JWT_KEY_ID", ""),
+			JWTPreviousKeyFiles: splitList(getEnv("JWT_PREVIOUS_KEY_FILES", "")),
+
+			MailDriver:      getEnv("MAIL_DRIVER", "log"),
+			SMTPHost:        getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:        getEnv("SMTP_PORT", "587"),
+			SMTPUsername:    getEnv("SMTP_USERNAME", ""),
+			SMTPPassword:    getEnv("SMTP_PASSWORD", ""),
+			SMTPImplicitTLS: getEnv("SMTP_IMPLICIT_TLS", "false") == "true",
+			MailFrom:        getEnv("MAIL_FROM", "UAS Prestasi <no-reply@localhost>"),
+
+			StorageDriver:     getEnv("STORAGE_DRIVER", "local"),
+			StorageLocalDir:   getEnv("STORAGE_LOCAL_DIR", "./uploads"),
+			StorageLocalURL:   getEnv("STORAGE_LOCAL_URL", "/api/v1/files"),
+			StorageSigningKey: getEnv("STORAGE_SIGNING_KEY", ""),
+			S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+			S3Region:          getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:          getEnv("S3_BUCKET", ""),
+			S3AccessKey:       getEnv("S3_ACCESS_KEY", ""),
+			S3SecretKey:       getEnv("S3_SECRET_KEY", ""),
+			S3PathStyle:       getEnv("S3_PATH_STYLE", "true") == "true",
+
+			AttachmentMaxFileMB:  getEnvInt("ATTACHMENT_MAX_FILE_MB", 10),
+			AttachmentMaxTotalMB: getEnvInt("ATTACHMENT_MAX_TOTAL_MB", 50),
+			AttachmentMaxCount:   getEnvInt("ATTACHMENT_MAX_COUNT", 10),
+
+			ScanDriver:        getEnv("SCAN_DRIVER", "none"),
+			ClamdAddress:      getEnv("CLAMD_ADDRESS", "tcp://localhost:3310"),
+			ScanRetryInterval: getEnv("SCAN_RETRY_INTERVAL", "1m"),
 		}
 		cfg = c
 	})
@@ -56,6 +144,40 @@ func Get() *Config {
 	return cfg
 }
 
+// IsProduction reports whether APP_ENV is "production".
+func (c *Config) IsProduction() bool {
+	return strings.EqualFold(c.AppEnv, "production")
+}
+
+// Validate refuses settings that are only acceptable in development: the default JWT secret,
+// throwaway signing keys generated at startup and a random key for file and download links.
+func (c *Config) Validate() error {
+	if !c.IsProduction() {
+		return nil
+	}
+	if c.JWTAlg == "HS256" {
+		if c.JWTSecret == DefaultJWTSecret || len(c.JWTSecret) < 32 {
+			return errors.New("APP_ENV=production: JWT_SECRET is the default or shorter than 32 characters")
+		}
+	} else if c.JWTPrivateKeyFile == "" {
+		return errors.New("APP_ENV=production: JWT_PRIVATE_KEY_FILE is required for " + c.JWTAlg)
+	}
+	if len(c.StorageSigningKey) < 32 {
+		return errors.New("APP_ENV=production: STORAGE_SIGNING_KEY of at least 32 characters is required (signs file and download links)")
+	}
+	return nil
+}
+
+func splitList(v string) []string {
+	out := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func getEnv(key, fallback string) string {
 	v := os.Getenv(key)
 	if v == "" {
@@ -63,3 +185,12 @@ func getEnv(key, fallback string) string {
 	}
 	return v
 }
+
+// getEnvInt returns fallback when key is unset or not a positive integer.
+func getEnvInt(key string, fallback int) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
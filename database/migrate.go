@@ -0,0 +1,364 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"UAS_BACKEND/database/migrations"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Stores a migration can target
+const (
+	StorePostgres = "postgres"
+	StoreMongo    = "mongo"
+)
+
+// migrationLockKey is the pg_advisory_lock key that serializes concurrent migration runs.
+const migrationLockKey = 724011
+
+var sqlMigrationName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// MigrationStatus describes one known migration and whether it has been applied.
+type MigrationStatus struct {
+	Store     string     `json:"store"`
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+type sqlMigration struct {
+	version  int
+	name     string
+	up, down string
+}
+
+// Migrator applies the embedded migrations to Postgres (tracked in the schema_migrations table)
+// and to Mongo (tracked in the schema_migrations collection). Either store may be nil.
+type Migrator struct {
+	pg    *sql.DB
+	mongo *mongo.Database
+}
+
+func NewMigrator(pg *sql.DB, mongoDB *mongo.Database) *Migrator {
+	return &Migrator{pg: pg, mongo: mongoDB}
+}
+
+// Up applies every pending migration of both stores, oldest first.
+func (m *Migrator) Up(ctx context.Context) error {
+	if m.pg != nil {
+		if err := m.postgresUp(ctx); err != nil {
+			return fmt.Errorf("postgres: %w", err)
+		}
+	}
+	if m.mongo != nil {
+		if err := m.mongoUp(ctx); err != nil {
+			return fmt.Errorf("mongo: %w", err)
+		}
+	}
+	return nil
+}
+
+// Down rolls back the last steps applied migrations of one store.
+func (m *Migrator) Down(ctx context.Context, store string, steps int) error {
+	if steps <= 0 {
+		return nil
+	}
+	switch store {
+	case StorePostgres:
+		if m.pg == nil {
+			return fmt.Errorf("postgres is not connected")
+		}
+		return m.postgresDown(ctx, steps)
+	case StoreMongo:
+		if m.mongo == nil {
+			return fmt.Errorf("mongo is not connected")
+		}
+		return m.mongoDown(ctx, steps)
+	}
+	return fmt.Errorf("unknown store %q (use %s or %s)", store, StorePostgres, StoreMongo)
+}
+
+// Status lists every known migration of the connected stores.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	out := []MigrationStatus{}
+	if m.pg != nil {
+		list, err := loadSQLMigrations()
+		if err != nil {
+			return nil, err
+		}
+		applied, err := m.postgresApplied(ctx, m.pg)
+		if err != nil {
+			return nil, err
+		}
+		for _, mg := range list {
+			st := MigrationStatus{Store: StorePostgres, Version: mg.version, Name: mg.name}
+			if at, ok := applied[mg.version]; ok {
+				at := at
+				st.AppliedAt = &at
+			}
+			out = append(out, st)
+		}
+	}
+	if m.mongo != nil {
+		applied, err := m.mongoApplied(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, mg := range migrations.Mongo {
+			st := MigrationStatus{Store: StoreMongo, Version: mg.Version, Name: mg.Name}
+			if at, ok := applied[mg.Version]; ok {
+				at := at
+				st.AppliedAt = &at
+			}
+			out = append(out, st)
+		}
+	}
+	return out, nil
+}
+
+// ---- postgres ----
+
+func loadSQLMigrations() ([]*sqlMigration, error) {
+	entries, err := fs.ReadDir(migrations.Postgres, "postgres")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*sqlMigration{}
+	for _, e := range entries {
+		match := sqlMigrationName.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %q", e.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		body, err := fs.ReadFile(migrations.Postgres, "postgres/"+e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mg := byVersion[version]
+		if mg == nil {
+			mg = &sqlMigration{version: version, name: match[2]}
+			byVersion[version] = mg
+		} else if mg.name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mg.name, match[2])
+		}
+		if match[3] == "up" {
+			mg.up = string(body)
+		} else {
+			mg.down = string(body)
+		}
+	}
+
+	list := make([]*sqlMigration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", mg.version, mg.name)
+		}
+		list = append(list, mg)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].version < list[j].version })
+	return list, nil
+}
+
+type pgQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (m *Migrator) postgresApplied(ctx context.Context, q pgQuerier) (map[int]time.Time, error) {
+	if _, err := q.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return nil, err
+	}
+	rows, err := q.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int]time.Time{}
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		out[v] = at
+	}
+	return out, rows.Err()
+}
+
+// withPostgresLock runs fn on a single connection holding the migration advisory lock.
+func (m *Migrator) withPostgresLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.pg.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+	return fn(conn)
+}
+
+func (m *Migrator) postgresUp(ctx context.Context) error {
+	list, err := loadSQLMigrations()
+	if err != nil {
+		return err
+	}
+	return m.withPostgresLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.postgresApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range list {
+			if _, ok := applied[mg.version]; ok {
+				continue
+			}
+			err := runInConnTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, mg.up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mg.version, mg.name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("%04d_%s up: %w", mg.version, mg.name, err)
+			}
+			log.Printf("migrate: postgres %04d_%s applied", mg.version, mg.name)
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) postgresDown(ctx context.Context, steps int) error {
+	list, err := loadSQLMigrations()
+	if err != nil {
+		return err
+	}
+	return m.withPostgresLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.postgresApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(list) - 1; i >= 0 && steps > 0; i-- {
+			mg := list[i]
+			if _, ok := applied[mg.version]; !ok {
+				continue
+			}
+			if mg.down == "" {
+				return fmt.Errorf("%04d_%s has no down file", mg.version, mg.name)
+			}
+			err := runInConnTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, mg.down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version=$1`, mg.version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("%04d_%s down: %w", mg.version, mg.name, err)
+			}
+			log.Printf("migrate: postgres %04d_%s rolled back", mg.version, mg.name)
+			steps--
+		}
+		return nil
+	})
+}
+
+func runInConnTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ---- mongo ----
+
+func (m *Migrator) mongoApplied(ctx context.Context) (map[int]time.Time, error) {
+	cur, err := m.mongo.Collection("schema_migrations").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := map[int]time.Time{}
+	for cur.Next(ctx) {
+		var doc struct {
+			Version   int       `bson:"_id"`
+			AppliedAt time.Time `bson:"appliedAt"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		out[doc.Version] = doc.AppliedAt
+	}
+	return out, cur.Err()
+}
+
+func (m *Migrator) mongoUp(ctx context.Context) error {
+	applied, err := m.mongoApplied(ctx)
+	if err != nil {
+		return err
+	}
+	col := m.mongo.Collection("schema_migrations")
+	for _, mg := range migrations.Mongo {
+		if _, ok := applied[mg.Version]; ok {
+			continue
+		}
+		if err := mg.Up(ctx, m.mongo); err != nil {
+			return fmt.Errorf("%04d_%s up: %w", mg.Version, mg.Name, err)
+		}
+		_, err := col.UpdateOne(ctx, bson.M{"_id": mg.Version},
+			bson.M{"$set": bson.M{"name": mg.Name, "appliedAt": time.Now()}},
+			options.Update().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+		log.Printf("migrate: mongo %04d_%s applied", mg.Version, mg.Name)
+	}
+	return nil
+}
+
+func (m *Migrator) mongoDown(ctx context.Context, steps int) error {
+	applied, err := m.mongoApplied(ctx)
+	if err != nil {
+		return err
+	}
+	col := m.mongo.Collection("schema_migrations")
+	for i := len(migrations.Mongo) - 1; i >= 0 && steps > 0; i-- {
+		mg := migrations.Mongo[i]
+		if _, ok := applied[mg.Version]; !ok {
+			continue
+		}
+		if err := mg.Down(ctx, m.mongo); err != nil {
+			return fmt.Errorf("%04d_%s down: %w", mg.Version, mg.Name, err)
+		}
+		if _, err := col.DeleteOne(ctx, bson.M{"_id": mg.Version}); err != nil {
+			return err
+		}
+		log.Printf("migrate: mongo %04d_%s rolled back", mg.Version, mg.Name)
+		steps--
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Package migrations holds the versioned schema of both stores: embedded SQL files for
+// Postgres (postgres/NNNN_name.up.sql + .down.sql) and index migrations for MongoDB.
+// Versions only ever grow; a released migration is never edited, a new one is added instead.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//go:embed postgres/*.sql
+var Postgres embed.FS
+
+// MongoMigration is one versioned change to the Mongo database (indexes, collections, backfills).
+type MongoMigration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+// Mongo lists the Mongo migrations in version order.
+var Mongo = []MongoMigration{
+	{
+		Version: 1,
+		Name:    "achievement_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("achievements").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "studentId", Value: 1}},
+					Options: options.Index().SetName("studentId_1"),
+				},
+				{
+					Keys:    bson.D{{Key: "createdAt", Value: -1}},
+					Options: options.Index().SetName("createdAt_-1"),
+				},
+				{
+					Keys:    bson.D{{Key: "tags", Value: 1}},
+					Options: options.Index().SetName("tags_1"),
+				},
+				{
+					Keys:    bson.D{{Key: "type", Value: 1}, {Key: "level", Value: 1}, {Key: "category", Value: 1}},
+					Options: options.Index().SetName("type_1_level_1_category_1"),
+				},
+				{
+					Keys: bson.D{{Key: "$**", Value: "text"}},
+					Options: options.Index().SetName("achievement_text").
+						SetWeights(bson.D{{Key: "title", Value: 10}, {Key: "tags", Value: 5}}),
+				},
+			})
+			return err
+		},
+		Down: dropIndexes("achievements", "studentId_1", "createdAt_-1", "tags_1", "type_1_level_1_category_1", "achievement_text"),
+	},
+	{
+		Version: 2,
+		Name:    "achievement_type_unique_type",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("achievement_types").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "type", Value: 1}},
+				Options: options.Index().SetName("type_1").SetUnique(true),
+			})
+			return err
+		},
+		Down: dropIndexes("achievement_types", "type_1"),
+	},
+	{
+		Version: 3,
+		Name:    "achievement_revision_unique_number",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("achievement_revisions").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "referenceId", Value: 1}, {Key: "number", Value: 1}},
+				Options: options.Index().SetName("referenceId_1_number_1").SetUnique(true),
+			})
+			return err
+		},
+		Down: dropIndexes("achievement_revisions", "referenceId_1_number_1"),
+	},
+	{
+		Version: 4,
+		Name:    "attachment_ids",
+		// attachments uploaded before ids existed get one so they can be deleted or replaced
+		Up: backfillAttachmentIDs,
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return nil // the ids are harmless to keep
+		},
+	},
+}
+
+// backfillAttachmentIDs gives every attachment without an id a random one. The update only applies
+// while the array is unchanged, so a concurrent write is picked up by running the migration again.
+func backfillAttachmentIDs(ctx context.Context, db *mongo.Database) error {
+	col := db.Collection("achievements")
+	filter := bson.M{"attachments": bson.M{"$elemMatch": bson.M{"id": bson.M{"$exists": false}}}}
+	cur, err := col.Find(ctx, filter, options.Find().SetProjection(bson.M{"attachments": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var doc struct {
+			ID          interface{} `bson:"_id"`
+			Attachments []bson.D    `bson:"attachments"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			return err
+		}
+		updated := make([]bson.D, len(doc.Attachments))
+		for i, a := range doc.Attachments {
+			updated[i] = a
+			if !hasKey(a, "id") {
+				updated[i] = append(bson.D{{Key: "id", Value: uuid.New().String()}}, a...)
+			}
+		}
+		// bson.D keeps the field order, so the filter matches the stored array exactly
+		_, err := col.UpdateOne(ctx,
+			bson.M{"_id": doc.ID, "attachments": doc.Attachments},
+			bson.M{"$set": bson.M{"attachments": updated}})
+		if err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// dropIndexes returns a Down step that drops the named indexes, ignoring ones that do not exist.
+func dropIndexes(collection string, names ...string) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		for _, name := range names {
+			if _, err := db.Collection(collection).Indexes().DropOne(ctx, name); err != nil {
+				var cmdErr mongo.CommandError
+				if errors.As(err, &cmdErr) && (cmdErr.Code == 27 || cmdErr.Code == 26) { // IndexNotFound, NamespaceNotFound
+					continue
+				}
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func hasKey(d bson.D, key string) bool {
+	for _, e := range d {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
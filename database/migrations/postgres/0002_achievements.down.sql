@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS activity_logs;
+DROP TABLE IF EXISTS achievement_references;
@@ -0,0 +1,35 @@
+-- Achievement references (the document itself lives in MongoDB) and the activity log
+
+CREATE TABLE achievement_references (
+    id UUID PRIMARY KEY,
+    student_id UUID NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    mongo_achievement_id VARCHAR(24) NOT NULL UNIQUE, -- ObjectId.Hex()
+    status VARCHAR(20) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'submitted', 'verified', 'rejected', 'revision', 'deleted')),
+    submitted_at TIMESTAMPTZ,
+    verified_at TIMESTAMPTZ,
+    verified_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    rejection_note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_achievement_references_student_id ON achievement_references (student_id);
+CREATE INDEX idx_achievement_references_status ON achievement_references (status);
+CREATE INDEX idx_achievement_references_created_at ON achievement_references (created_at, id);
+CREATE INDEX idx_achievement_references_updated_at ON achievement_references (updated_at, id);
+
+CREATE TABLE activity_logs (
+    id UUID PRIMARY KEY,
+    entity_type VARCHAR(50) NOT NULL, -- achievement_reference, reconciliation, ...
+    entity_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    actor_id UUID, -- no FK: the log outlives deleted users
+    actor_role VARCHAR(64),
+    previous JSONB,
+    current JSONB,
+    metadata JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_activity_logs_entity ON activity_logs (entity_type, entity_id, created_at DESC);
@@ -0,0 +1,24 @@
+-- Transactional outbox for achievement writes (Postgres -> MongoDB)
+-- Each row is a Mongo operation committed together with the achievement_references change it belongs to.
+
+CREATE TABLE achievement_outbox (
+    id UUID PRIMARY KEY,
+    seq BIGSERIAL NOT NULL UNIQUE,
+    aggregate_id UUID NOT NULL, -- achievement_references.id
+    operation VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'done', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    processed_at TIMESTAMPTZ
+);
+
+-- worker scan: due pending entries in order
+CREATE INDEX idx_achievement_outbox_pending
+    ON achievement_outbox (next_attempt_at, seq) WHERE status = 'pending';
+
+-- per-aggregate ordering check
+CREATE INDEX idx_achievement_outbox_aggregate
+    ON achievement_outbox (aggregate_id, seq) WHERE status <> 'done';
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS role_permissions_notify ON role_permissions;
+DROP FUNCTION IF EXISTS notify_rbac_invalidate();
@@ -0,0 +1,18 @@
+-- Tell every app instance (LISTEN rbac_invalidate) which role's grants changed, so their
+-- in-memory permission cache is dropped whatever made the change: API, seed or plain SQL.
+
+CREATE FUNCTION notify_rbac_invalidate() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM pg_notify('rbac_invalidate', OLD.role_id::text);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM pg_notify('rbac_invalidate', NEW.role_id::text);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER role_permissions_notify
+    AFTER INSERT OR UPDATE OR DELETE ON role_permissions
+    FOR EACH ROW EXECUTE FUNCTION notify_rbac_invalidate();
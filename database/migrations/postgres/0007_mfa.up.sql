@@ -0,0 +1,20 @@
+-- TOTP two-factor authentication (RFC 6238) and per-role enforcement
+
+ALTER TABLE roles ADD COLUMN mfa_required BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,          -- base32 TOTP secret
+    enabled_at TIMESTAMPTZ,               -- NULL while enrollment is not confirmed
+    last_used_step BIGINT NOT NULL DEFAULT 0, -- time step of the last accepted code (replay protection)
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE mfa_recovery_codes (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL, -- hex sha256
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, code_hash)
+);
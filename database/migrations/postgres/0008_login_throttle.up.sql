@@ -0,0 +1,8 @@
+-- Failed login counting and progressive lockout per username
+
+CREATE TABLE login_throttle (
+    username VARCHAR(50) PRIMARY KEY, -- lower-cased; also usernames that do not exist
+    failed_count INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ,
+    locked_until TIMESTAMPTZ
+);
@@ -0,0 +1,16 @@
+-- Email verification and single-use account tokens (password reset, email verification)
+
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+CREATE TABLE user_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(30) NOT NULL, -- password_reset, email_verify
+    token_hash CHAR(64) NOT NULL UNIQUE, -- hex sha256
+    email VARCHAR(100) NOT NULL DEFAULT '', -- address the token was sent to
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_user_tokens_user_purpose ON user_tokens (user_id, purpose) WHERE used_at IS NULL;
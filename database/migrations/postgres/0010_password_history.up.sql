@@ -0,0 +1,10 @@
+-- Previous password hashes, so a password change cannot reuse a recent password
+
+CREATE TABLE password_history (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL, -- bcrypt hash that was replaced
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_password_history_user ON password_history (user_id, created_at DESC);
@@ -0,0 +1 @@
+ALTER TABLE achievement_references DROP COLUMN IF EXISTS revision_count;
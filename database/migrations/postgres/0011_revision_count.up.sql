@@ -0,0 +1,5 @@
+-- Revision numbers are allocated under the achievement_references row lock, so a submit never
+-- reuses the number of a revision whose Mongo insert is still queued in the outbox.
+-- Existing rows start at 0; the first allocation catches up with the revisions already in Mongo.
+
+ALTER TABLE achievement_references ADD COLUMN revision_count INTEGER NOT NULL DEFAULT 0;
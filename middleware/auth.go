@@ -1,10 +1,11 @@
 package middleware
 
 import (
+	"context"
 	"strings"
 	"time"
 
-	"UAS_BACKEND/config"
+	"UAS_BACKEND/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
@@ -12,16 +13,23 @@ import (
 
 // Key names for locals
 const (
-	LocalsUserID = "user_id"
-	LocalsRoleID = "role_id"
+	LocalsUserID    = "user_id"
+	LocalsRoleID    = "role_id"
+	LocalsSessionID = "session_id"
 )
 
-// NewJWTMiddleware returns a Fiber middleware that validates JWT and sets c.Locals("user_id", id)
-func NewJWTMiddleware() fiber.Handler {
-	secret := config.Get().JWTSecret
-	if secret == "" {
-		secret = "dev-secret"
-	}
+// SessionChecker reports whether the session (JWT "sid" claim) is still active.
+type SessionChecker func(ctx context.Context, sessionID string, ip string) (bool, error)
+
+// UserChecker reports whether the account (JWT "sub" claim) exists and is active.
+type UserChecker func(ctx context.Context, userID string) (bool, error)
+
+// NewJWTMiddleware returns a Fiber middleware that validates JWT and sets c.Locals("user_id", id).
+// Signatures are checked against keys, the key named by the token's "kid" header (see /.well-known/jwks.json).
+// When checkSession is not nil, tokens of revoked sessions are rejected; tokens without a
+// "sid" claim (issued before sessions existed) are accepted until they expire.
+// When checkUser is not nil, tokens of disabled or deleted accounts are rejected.
+func NewJWTMiddleware(keys *utils.JWTKeySet, checkSession SessionChecker, checkUser UserChecker) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		auth := c.Get("Authorization")
 		if auth == "" {
@@ -32,13 +40,7 @@ func NewJWTMiddleware() fiber.Handler {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid authorization header"})
 		}
 		tokenStr := parts[1]
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			// ensure signing method
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fiber.ErrUnauthorized
-			}
-			return []byte(secret), nil
-		}, jwt.WithLeeway(5*time.Second))
+		token, err := keys.Parse(tokenStr, jwt.MapClaims{}, jwt.WithLeeway(5*time.Second))
 		if err != nil || !token.Valid {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token"})
 		}
@@ -46,13 +48,38 @@ func NewJWTMiddleware() fiber.Handler {
 		if !ok {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token claims"})
 		}
+		// only access tokens; tokens issued before "typ" existed have none
+		if typ, ok := claims["typ"].(string); ok && typ != "access" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token"})
+		}
 		// expected claims: sub (user id), role
 		if sub, ok := claims["sub"].(string); ok && sub != "" {
+			if checkUser != nil {
+				active, err := checkUser(c.Context(), sub)
+				if err != nil {
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "account check failed"})
+				}
+				if !active {
+					return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "account is disabled"})
+				}
+			}
 			c.Locals(LocalsUserID, sub)
 		}
 		if role, ok := claims["role"].(string); ok && role != "" {
 			c.Locals(LocalsRoleID, role)
 		}
+		if sid, ok := claims["sid"].(string); ok && sid != "" {
+			if checkSession != nil {
+				active, err := checkSession(c.Context(), sid, c.IP())
+				if err != nil {
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "session check failed"})
+				}
+				if !active {
+					return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "session has been revoked"})
+				}
+			}
+			c.Locals(LocalsSessionID, sid)
+		}
 		return c.Next()
 	}
 }
@@ -1,12 +1,15 @@
 package middleware
 
 import (
+	"context"
+
 	"github.com/gofiber/fiber/v2"
 )
 
 // PermissionChecker adalah fungsi yang mengecek apakah role memiliki permission.
 // Implementasikan wrapper yang memanggil RBACService.HasPermissionByRoleID di tempat wiring.
-type PermissionChecker func(roleID string, permission string) (bool, error)
+// ctx adalah context request (c.Context()), bukan context.Background().
+type PermissionChecker func(ctx context.Context, roleID string, permission string) (bool, error)
 
 // RequirePermission returns a middleware that checks permission string (e.g. "achievement:verify")
 func RequirePermission(check PermissionChecker, permission string) fiber.Handler {
@@ -15,7 +18,7 @@ func RequirePermission(check PermissionChecker, permission string) fiber.Handler
 		if !ok || role == "" {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "role not found in token"})
 		}
-		okPerm, err := check(role, permission)
+		okPerm, err := check(c.Context(), role, permission)
 		if err != nil {
 			// optionally log error
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "permission check failed"})
@@ -2,8 +2,11 @@ package route
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"net/http"
+	"net/url"
 	"time"
 
 	mongoModel "UAS_BACKEND/app/model/mongo"
@@ -29,15 +32,67 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 		return context.WithTimeout(c.Context(), 10*time.Second)
 	}
 
+	// Helper untuk identitas pemanggil (dari JWT) yang dipakai service untuk scoping data
+	callerOf := func(c *fiber.Ctx) service.Caller {
+		userID, _ := c.Locals(middleware.LocalsUserID).(string)
+		roleID, _ := c.Locals(middleware.LocalsRoleID).(string)
+		return service.Caller{UserID: userID, RoleID: roleID}
+	}
+
 	// Wrapper untuk RBAC Permission Checker agar sesuai signature middleware
-	rbacCheck := func(roleID string, permission string) (bool, error) {
-		// Gunakan context background karena pengecekan permission biasanya cepat/cached
-		return s.RBAC.HasPermissionByRoleID(context.Background(), roleID, permission)
+	// (permission per role di-cache di RBACService, lihat RBAC_CACHE_TTL)
+	rbacCheck := func(ctx context.Context, roleID string, permission string) (bool, error) {
+		return s.RBAC.HasPermissionByRoleID(ctx, roleID, permission)
 	}
 
+	// JWT middleware; signature dicek dengan key set (kid), token dari session yang sudah dicabut
+	// (logout, force logout) dan token milik akun nonaktif ditolak
+	jwtAuth := middleware.NewJWTMiddleware(s.Keys, s.Session.IsActive, s.User.IsActive)
+
+	// GET /.well-known/jwks.json
+	// Public key (aktif + sebelumnya) agar service kampus lain bisa memverifikasi access token tanpa secret
+	app.Get("/.well-known/jwks.json", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(s.Keys.JWKS())
+	})
+
 	// API Group Base
 	api := app.Group("/api/v1")
 
+	// GET /files/* (Link bertanda tangan dari FileStore lokal, lihat STORAGE_LOCAL_URL; tanpa bearer token.
+	// Driver s3 memakai presigned URL milik bucket sehingga route ini tidak dipasang.)
+	if local, ok := s.Files.(*utils.LocalFileStore); ok {
+		api.Get("/files/*", func(c *fiber.Ctx) error {
+			key, err := url.PathUnescape(c.Params("*"))
+			if err != nil {
+				return utils.JSONError(c, fiber.StatusNotFound, "File not found")
+			}
+			if err := local.VerifySignature(key, c.Query("expires"), c.Query("signature")); err != nil {
+				return utils.JSONError(c, fiber.StatusForbidden, err.Error())
+			}
+			body, info, err := local.Get(c.Context(), key)
+			if err != nil {
+				return utils.JSONError(c, fiber.StatusNotFound, "File not found")
+			}
+			c.Set(fiber.HeaderContentType, info.ContentType)
+			c.Set(fiber.HeaderCacheControl, "private, no-store")
+			return c.SendStream(body, int(info.Size))
+		})
+	}
+
+	// GET /downloads/achievements/:id/attachments/:attachmentId?expires=&signature=
+	// Link unduhan lampiran bertanda tangan (dibuat lewat POST .../link); tanpa bearer token
+	api.Get("/downloads/achievements/:id/attachments/:attachmentId", func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		attachment, err := s.Achievement.GetAttachmentByLink(ctx, c.Params("id"), c.Params("attachmentId"), c.Query("expires"), c.Query("signature"))
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return sendAttachment(c, s.Files, attachment)
+	})
+
 	// =========================================================================
 	// 5.1 AUTHENTICATION
 	// =========================================================================
@@ -53,32 +108,164 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 
-		token, user, err := s.Auth.Login(ctx, req.Username, req.Password)
+		res, err := s.Auth.Login(ctx, req.Username, req.Password, pgModel.SessionMeta{
+			Device:    req.Device,
+			IP:        c.IP(),
+			UserAgent: c.Get(fiber.HeaderUserAgent),
+		})
 		if err != nil {
-			return utils.JSONError(c, fiber.StatusUnauthorized, err.Error())
+			// 401 invalid_credentials, 403 account_disabled, 429 account_locked
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
 		}
+		return utils.JSONSuccess(c, fiber.StatusOK, loginResponse(res))
+	})
 
-		return utils.JSONSuccess(c, fiber.StatusOK, fiber.Map{
-			"token": token,
-			"user":  user,
+	// POST /auth/login/mfa
+	// Langkah kedua login: {"mfa_token": "...", "code": "123456"} (kode TOTP atau recovery code).
+	// Untuk enrollment wajib, code adalah kode pertama dari authenticator (lihat /auth/login/mfa/enroll).
+	authGroup.Post("/login/mfa", middleware.LoginRateLimiter(), func(c *fiber.Ctx) error {
+		var req struct {
+			MFAToken string `json:"mfa_token"`
+			Code     string `json:"code"`
+			Device   string `json:"device"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		res, err := s.Auth.LoginMFA(ctx, req.MFAToken, req.Code, pgModel.SessionMeta{
+			Device:    req.Device,
+			IP:        c.IP(),
+			UserAgent: c.Get(fiber.HeaderUserAgent),
 		})
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, loginResponse(res))
+	})
+
+	// POST /auth/login/mfa/enroll
+	// Role mewajibkan MFA tetapi user belum enroll: {"mfa_token": "..."} -> secret + otpauth URI (QR)
+	authGroup.Post("/login/mfa/enroll", middleware.LoginRateLimiter(), func(c *fiber.Ctx) error {
+		var req struct {
+			MFAToken string `json:"mfa_token"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		enrollment, err := s.Auth.BeginMFAEnrollment(ctx, req.MFAToken)
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, enrollment)
+	})
+
+	// POST /auth/forgot-password
+	// Body: {"login": "email atau username"}. Respons selalu sama agar tidak membocorkan akun mana yang ada;
+	// link reset (sekali pakai, kedaluwarsa) dikirim lewat email bila akun ada dan aktif.
+	authGroup.Post("/forgot-password", middleware.LoginRateLimiter(), func(c *fiber.Ctx) error {
+		var req struct {
+			Login string `json:"login"`
+			Email string `json:"email"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		if req.Login == "" {
+			req.Login = req.Email
+		}
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		meta := pgModel.SessionMeta{IP: c.IP(), UserAgent: c.Get(fiber.HeaderUserAgent)}
+		if err := s.AccountEmail.ForgotPassword(ctx, req.Login, meta); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "If the account exists, a password reset link has been sent to its email")
+	})
+
+	// POST /auth/reset-password
+	// Body: {"token": "...", "new_password": "..."}; semua session user diakhiri setelah password diganti
+	authGroup.Post("/reset-password", middleware.LoginRateLimiter(), func(c *fiber.Ctx) error {
+		var req struct {
+			Token       string `json:"token"`
+			NewPassword string `json:"new_password"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		meta := pgModel.SessionMeta{IP: c.IP(), UserAgent: c.Get(fiber.HeaderUserAgent)}
+		if err := s.AccountEmail.ResetPassword(ctx, req.Token, req.NewPassword, meta); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Password has been reset, please log in again")
+	})
+
+	// POST /auth/verify-email
+	// Body: {"token": "..."} dari link verifikasi yang dikirim saat akun dibuat
+	authGroup.Post("/verify-email", middleware.LoginRateLimiter(), func(c *fiber.Ctx) error {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		if err := s.AccountEmail.VerifyEmail(ctx, req.Token); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Email verified")
+	})
+
+	// POST /auth/verify-email/resend (Kirim ulang link verifikasi ke email user ini)
+	authGroup.Post("/verify-email/resend", jwtAuth, func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		if err := s.AccountEmail.ResendVerification(ctx, callerOf(c).UserID); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Verification email sent")
 	})
 
 	// POST /auth/refresh
-	authGroup.Post("/refresh", middleware.NewJWTMiddleware(), func(c *fiber.Ctx) error {
-		userID := c.Locals(middleware.LocalsUserID).(string)
+	// Body: {"refresh_token": "..."}; tidak butuh access token (boleh sudah expired).
+	// Refresh token hanya berlaku sekali: response berisi pasangan token baru.
+	authGroup.Post("/refresh", func(c *fiber.Ctx) error {
+		var req struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 
-		newToken, err := s.Auth.Refresh(ctx, userID)
+		pair, err := s.Auth.Refresh(ctx, req.RefreshToken, c.IP())
 		if err != nil {
-			return utils.JSONError(c, fiber.StatusUnauthorized, err.Error())
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
 		}
-		return utils.JSONSuccess(c, fiber.StatusOK, fiber.Map{"token": newToken})
+		return utils.JSONSuccess(c, fiber.StatusOK, fiber.Map{
+			"token":         pair.AccessToken,
+			"access_token":  pair.AccessToken,
+			"refresh_token": pair.RefreshToken,
+			"token_type":    pair.TokenType,
+			"expires_in":    pair.ExpiresIn,
+		})
 	})
 
 	// POST /auth/logout
-	authGroup.Post("/logout", middleware.NewJWTMiddleware(), func(c *fiber.Ctx) error {
+	authGroup.Post("/logout", jwtAuth, func(c *fiber.Ctx) error {
 		// Ambil token mentah dari header untuk diblacklist
 		authHeader := c.Get("Authorization")
 		if len(authHeader) < 7 {
@@ -96,7 +283,7 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 	})
 
 	// GET /auth/profile
-	authGroup.Get("/profile", middleware.NewJWTMiddleware(), func(c *fiber.Ctx) error {
+	authGroup.Get("/profile", jwtAuth, func(c *fiber.Ctx) error {
 		userID := c.Locals(middleware.LocalsUserID).(string)
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
@@ -108,14 +295,145 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 		return utils.JSONSuccess(c, fiber.StatusOK, user)
 	})
 
+	// PUT /auth/password
+	// Body: {"current_password": "...", "new_password": "..."}; session lain milik user ini diakhiri,
+	// session yang dipakai request ini tetap aktif
+	authGroup.Put("/password", jwtAuth, func(c *fiber.Ctx) error {
+		var req struct {
+			CurrentPassword string `json:"current_password"`
+			NewPassword     string `json:"new_password"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		sessionID, _ := c.Locals(middleware.LocalsSessionID).(string)
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		meta := pgModel.SessionMeta{IP: c.IP(), UserAgent: c.Get(fiber.HeaderUserAgent)}
+		if err := s.Auth.ChangePassword(ctx, callerOf(c).UserID, sessionID, req.CurrentPassword, req.NewPassword, meta); err != nil {
+			// 400 invalid_current_password / weak_password / password_reused, 429 account_locked
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Password changed")
+	})
+
+	// GET /auth/sessions (Daftar login aktif milik user ini, "current" = session token ini)
+	authGroup.Get("/sessions", jwtAuth, func(c *fiber.Ctx) error {
+		userID := c.Locals(middleware.LocalsUserID).(string)
+		sessionID, _ := c.Locals(middleware.LocalsSessionID).(string)
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		list, err := s.Session.List(ctx, userID, sessionID)
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, list)
+	})
+
+	// DELETE /auth/sessions/:id (Logout dari satu perangkat)
+	authGroup.Delete("/sessions/:id", jwtAuth, func(c *fiber.Ctx) error {
+		userID := c.Locals(middleware.LocalsUserID).(string)
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		if err := s.Session.RevokeOwn(ctx, userID, c.Params("id")); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Session revoked")
+	})
+
+	type mfaCodeRequest struct {
+		Code string `json:"code"`
+	}
+
+	// GET /auth/mfa (Status autentikasi dua faktor milik user ini)
+	authGroup.Get("/mfa", jwtAuth, func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		user, err := s.User.GetByID(ctx, callerOf(c).UserID)
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusNotFound, "User not found")
+		}
+		status, err := s.MFA.Status(ctx, user)
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, status)
+	})
+
+	// POST /auth/mfa/enroll (Mulai enrollment TOTP: secret + otpauth URI untuk QR code)
+	authGroup.Post("/mfa/enroll", jwtAuth, func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		user, err := s.User.GetByID(ctx, callerOf(c).UserID)
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusNotFound, "User not found")
+		}
+		enrollment, err := s.MFA.Begin(ctx, user)
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, enrollment)
+	})
+
+	// POST /auth/mfa/confirm (Aktifkan MFA dengan kode pertama; recovery code hanya ditampilkan sekali)
+	authGroup.Post("/mfa/confirm", jwtAuth, func(c *fiber.Ctx) error {
+		var req mfaCodeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid body")
+		}
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		codes, err := s.MFA.Confirm(ctx, callerOf(c).UserID, req.Code)
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, fiber.Map{"recovery_codes": codes})
+	})
+
+	// POST /auth/mfa/recovery-codes (Buat ulang recovery code, kode lama tidak berlaku)
+	authGroup.Post("/mfa/recovery-codes", jwtAuth, func(c *fiber.Ctx) error {
+		var req mfaCodeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid body")
+		}
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		codes, err := s.MFA.RegenerateRecoveryCodes(ctx, callerOf(c).UserID, req.Code)
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, fiber.Map{"recovery_codes": codes})
+	})
+
+	// DELETE /auth/mfa (Matikan MFA; butuh kode, ditolak bila role mewajibkan MFA)
+	authGroup.Delete("/mfa", jwtAuth, func(c *fiber.Ctx) error {
+		var req mfaCodeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid body")
+		}
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		user, err := s.User.GetByID(ctx, callerOf(c).UserID)
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusNotFound, "User not found")
+		}
+		if err := s.MFA.Disable(ctx, user, req.Code); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Two-factor authentication disabled")
+	})
+
 	// =========================================================================
 	// 5.2 USERS (ADMIN)
 	// =========================================================================
 	// Group ini dilindungi Auth & RBAC (misal permission: 'user:manage')
-	userGroup := api.Group("/users", middleware.NewJWTMiddleware())
+	userGroup := api.Group("/users", jwtAuth)
 
 	// GET /users
-	userGroup.Get("/", middleware.RequirePermission(rbacCheck, "user:read"), func(c *fiber.Ctx) error {
+	userGroup.Get("/", middleware.RequirePermission(rbacCheck, service.PermUserRead), func(c *fiber.Ctx) error {
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 		users, err := s.User.ListAll(ctx)
@@ -126,7 +444,7 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 	})
 
 	// GET /users/:id
-	userGroup.Get("/:id", middleware.RequirePermission(rbacCheck, "user:read"), func(c *fiber.Ctx) error {
+	userGroup.Get("/:id", middleware.RequirePermission(rbacCheck, service.PermUserRead), func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
@@ -139,16 +457,24 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 	})
 
 	// POST /users
-	userGroup.Post("/", middleware.RequirePermission(rbacCheck, "user:create"), func(c *fiber.Ctx) error {
-		var u pgModel.User
-		if err := c.BodyParser(&u); err != nil {
+	userGroup.Post("/", middleware.RequirePermission(rbacCheck, service.PermUserCreate), func(c *fiber.Ctx) error {
+		var req pgModel.CreateUserRequest
+		if err := c.BodyParser(&req); err != nil {
 			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
 		}
 
-		// Hash password manual disini atau di service (di code sebelumnya service hash password tidak dipanggil di Register)
-		// Kita asumsikan hash dilakukan di Handler atau Service memanggil utils.HashPassword
-		hashed, _ := s.Auth.HashPassword(u.PasswordHash) // field ini biasanya menampung plain text saat request
-		u.PasswordHash = hashed
+		// password dikirim plain text; dicek terhadap password policy lalu di-hash
+		hashed, err := s.Auth.HashNewPassword(req.Username, req.Password)
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
+		}
+		u := pgModel.User{
+			Username:     req.Username,
+			Email:        req.Email,
+			PasswordHash: hashed,
+			FullName:     req.FullName,
+			RoleID:       req.RoleID,
+		}
 
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
@@ -160,25 +486,60 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 	})
 
 	// PUT /users/:id
-	userGroup.Put("/:id", middleware.RequirePermission(rbacCheck, "user:update"), func(c *fiber.Ctx) error {
+	// Partial update: field yang tidak dikirim tetap memakai nilai tersimpan (is_active juga).
+	userGroup.Put("/:id", middleware.RequirePermission(rbacCheck, service.PermUserUpdate), func(c *fiber.Ctx) error {
 		id := c.Params("id")
-		var u pgModel.User
-		if err := c.BodyParser(&u); err != nil {
+		var req pgModel.UpdateUserRequest
+		if err := c.BodyParser(&req); err != nil {
 			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
 		}
-		u.ID = id
 
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 
-		if err := s.User.Update(ctx, &u); err != nil {
+		u, err := s.User.GetByID(ctx, id)
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusNotFound, "User not found")
+		}
+
+		if req.Username != nil {
+			u.Username = *req.Username
+		}
+		if req.Email != nil {
+			u.Email = *req.Email
+		}
+		if req.FullName != nil {
+			u.FullName = *req.FullName
+		}
+		if req.RoleID != nil {
+			u.RoleID = *req.RoleID
+		}
+		if req.IsActive != nil {
+			u.IsActive = *req.IsActive
+		}
+
+		// password baru (plain text) dicek policy + riwayat sebelum apa pun disimpan, dan baru
+		// diterapkan setelah field lain tersimpan, supaya request yang gagal tidak setengah jalan.
+		// Semua session user diakhiri.
+		newPassword := req.Password != nil && *req.Password != ""
+		if newPassword {
+			if err := s.Auth.CheckNewPasswordFor(ctx, u, *req.Password); err != nil {
+				return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+			}
+		}
+		if err := s.User.Update(ctx, u); err != nil {
 			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
 		}
+		if newPassword {
+			if err := s.Auth.AdminSetPassword(ctx, callerOf(c), id, *req.Password); err != nil {
+				return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+			}
+		}
 		return utils.JSONSuccess(c, fiber.StatusOK, "User updated")
 	})
 
 	// PUT /users/:id/role (Assign Role)
-	userGroup.Put("/:id/role", middleware.RequirePermission(rbacCheck, "user:assign_role"), func(c *fiber.Ctx) error {
+	userGroup.Put("/:id/role", middleware.RequirePermission(rbacCheck, service.PermUserAssignRole), func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		var req struct {
 			RoleID string `json:"role_id"`
@@ -196,8 +557,55 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 		return utils.JSONSuccess(c, fiber.StatusOK, "Role updated")
 	})
 
+	// GET /users/:id/sessions (Admin: login aktif seorang user)
+	userGroup.Get("/:id/sessions", middleware.RequirePermission(rbacCheck, service.PermUserRead), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		list, err := s.Session.List(ctx, c.Params("id"), "")
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, list)
+	})
+
+	// DELETE /users/:id/sessions (Admin: force logout di semua perangkat)
+	userGroup.Delete("/:id/sessions", middleware.RequirePermission(rbacCheck, service.PermUserRevokeSessions), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		n, err := s.Session.RevokeUser(ctx, c.Params("id"), service.SessionForceLogout)
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, fiber.Map{"revoked": n})
+	})
+
+	// POST /users/:id/unlock (Admin: buka kunci akun setelah terlalu banyak login gagal)
+	userGroup.Post("/:id/unlock", middleware.RequirePermission(rbacCheck, service.PermUserUnlock), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		user, err := s.User.GetByID(ctx, c.Params("id"))
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusNotFound, "User not found")
+		}
+		previous, err := s.LoginThrottle.Unlock(ctx, callerOf(c), user)
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, previous)
+	})
+
+	// DELETE /users/:id/mfa (Admin: reset MFA user yang kehilangan authenticator dan recovery code)
+	userGroup.Delete("/:id/mfa", middleware.RequirePermission(rbacCheck, service.PermUserResetMFA), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		if err := s.MFA.Reset(ctx, callerOf(c), c.Params("id")); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Two-factor authentication reset")
+	})
+
 	// DELETE /users/:id
-	userGroup.Delete("/:id", middleware.RequirePermission(rbacCheck, "user:delete"), func(c *fiber.Ctx) error {
+	userGroup.Delete("/:id", middleware.RequirePermission(rbacCheck, service.PermUserDelete), func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
@@ -208,11 +616,143 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 		return utils.JSONSuccess(c, fiber.StatusOK, "User deleted")
 	})
 
+	// =========================================================================
+	// 5.3 ROLES & PERMISSIONS (ADMIN)
+	// =========================================================================
+	// Setiap perubahan dicatat di activity_logs (entity_type=role)
+	roleGroup := api.Group("/roles", jwtAuth)
+
+	type roleRequest struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	// GET /permissions (Daftar semua permission yang dikenal sistem)
+	api.Get("/permissions", jwtAuth, middleware.RequirePermission(rbacCheck, service.PermRoleRead), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		list, err := s.Role.ListPermissions(ctx)
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, list)
+	})
+
+	// GET /roles
+	roleGroup.Get("/", middleware.RequirePermission(rbacCheck, service.PermRoleRead), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		list, err := s.Role.List(ctx)
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, list)
+	})
+
+	// GET /roles/:id (Role + permission yang dimiliki)
+	roleGroup.Get("/:id", middleware.RequirePermission(rbacCheck, service.PermRoleRead), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		role, err := s.Role.Get(ctx, c.Params("id"))
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, role)
+	})
+
+	// GET /roles/:id/users (User yang memegang role ini)
+	roleGroup.Get("/:id/users", middleware.RequirePermission(rbacCheck, service.PermRoleRead), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		list, err := s.Role.Members(ctx, c.Params("id"))
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, list)
+	})
+
+	// POST /roles
+	roleGroup.Post("/", middleware.RequirePermission(rbacCheck, service.PermRoleCreate), func(c *fiber.Ctx) error {
+		var req roleRequest
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid body")
+		}
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		role, err := s.Role.Create(ctx, callerOf(c), req.Name, req.Description)
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusCreated, role)
+	})
+
+	// PUT /roles/:id
+	roleGroup.Put("/:id", middleware.RequirePermission(rbacCheck, service.PermRoleUpdate), func(c *fiber.Ctx) error {
+		var req roleRequest
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid body")
+		}
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		role, err := s.Role.Update(ctx, callerOf(c), c.Params("id"), req.Name, req.Description)
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, role)
+	})
+
+	// PUT /roles/:id/mfa (Wajibkan MFA untuk role ini): {"required": true}
+	roleGroup.Put("/:id/mfa", middleware.RequirePermission(rbacCheck, service.PermRoleUpdate), func(c *fiber.Ctx) error {
+		var req struct {
+			Required bool `json:"required"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid body")
+		}
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		role, err := s.Role.SetMFARequired(ctx, callerOf(c), c.Params("id"), req.Required)
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, role)
+	})
+
+	// DELETE /roles/:id (Hanya role non-default yang tidak dipakai user)
+	roleGroup.Delete("/:id", middleware.RequirePermission(rbacCheck, service.PermRoleDelete), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		if err := s.Role.Delete(ctx, callerOf(c), c.Params("id")); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Role deleted")
+	})
+
+	// PUT /roles/:id/permissions/:permission (Attach permission, contoh: achievement:verify)
+	roleGroup.Put("/:id/permissions/:permission", middleware.RequirePermission(rbacCheck, service.PermRoleGrant), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		if err := s.Role.Grant(ctx, callerOf(c), c.Params("id"), c.Params("permission")); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Permission granted")
+	})
+
+	// DELETE /roles/:id/permissions/:permission (Detach permission)
+	roleGroup.Delete("/:id/permissions/:permission", middleware.RequirePermission(rbacCheck, service.PermRoleGrant), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		if err := s.Role.Revoke(ctx, callerOf(c), c.Params("id"), c.Params("permission")); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Permission revoked")
+	})
+
 	// =========================================================================
 	// 5.5 STUDENTS & LECTURERS
 	// =========================================================================
-	studentGroup := api.Group("/students", middleware.NewJWTMiddleware())
-	lecturerGroup := api.Group("/lecturers", middleware.NewJWTMiddleware())
+	studentGroup := api.Group("/students", jwtAuth)
+	lecturerGroup := api.Group("/lecturers", jwtAuth)
 
 	// GET /students (List)
 	studentGroup.Get("/", func(c *fiber.Ctx) error {
@@ -240,7 +780,7 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 	})
 
 	// PUT /students/:id/advisor (Set Advisor) - Admin Only
-	studentGroup.Put("/:id/advisor", middleware.RequirePermission(rbacCheck, "student:manage"), func(c *fiber.Ctx) error {
+	studentGroup.Put("/:id/advisor", middleware.RequirePermission(rbacCheck, service.PermStudentManage), func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		var req struct {
 			AdvisorID string `json:"advisor_id"`
@@ -284,23 +824,43 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 	// =========================================================================
 	// 5.4 ACHIEVEMENTS (CORE)
 	// =========================================================================
-	achGroup := api.Group("/achievements", middleware.NewJWTMiddleware())
+	achGroup := api.Group("/achievements", jwtAuth)
 
 	// GET /achievements (List All - Filtered by Service logic)
 	// Permission: Admin atau Lecturer (lihat semua/bimbingan), Student (lihat punya sendiri biasanya via endpoint profile)
+	// Query: status, student_id, advisor_id, type, level, category, tag, q,
+	// submitted_from, submitted_to, verified_from, verified_to, sort, page, limit, cursor
 	achGroup.Get("/", func(c *fiber.Ctx) error {
-		// TODO: Parse query params for filtering
+		query := service.AchievementQuery{
+			Status:        c.Query("status"),
+			StudentID:     c.Query("student_id"),
+			AdvisorID:     c.Query("advisor_id"),
+			Type:          c.Query("type"),
+			Level:         c.Query("level"),
+			Category:      c.Query("category"),
+			Tag:           c.Query("tag"),
+			Search:        c.Query("q"),
+			SubmittedFrom: c.Query("submitted_from"),
+			SubmittedTo:   c.Query("submitted_to"),
+			VerifiedFrom:  c.Query("verified_from"),
+			VerifiedTo:    c.Query("verified_to"),
+			Sort:          c.Query("sort"),
+			Page:          utils.GetQueryInt(c, "page", 1),
+			Limit:         utils.GetQueryInt(c, "limit", 20),
+			Cursor:        c.Query("cursor"),
+		}
+
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
-		list, err := s.Achievement.GetAllAchievements(ctx, nil)
+		page, err := s.Achievement.GetAllAchievements(ctx, callerOf(c), query)
 		if err != nil {
-			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
 		}
-		return utils.JSONSuccess(c, fiber.StatusOK, list)
+		return utils.JSONSuccess(c, fiber.StatusOK, page)
 	})
 
 	// POST /achievements (Create Draft - Mahasiswa)
-	achGroup.Post("/", middleware.RequirePermission(rbacCheck, "achievement:create"), func(c *fiber.Ctx) error {
+	achGroup.Post("/", middleware.RequirePermission(rbacCheck, service.PermAchievementCreate), func(c *fiber.Ctx) error {
 		userID := c.Locals(middleware.LocalsUserID).(string)
 		var doc mongoModel.Achievement
 		if err := c.BodyParser(&doc); err != nil {
@@ -312,7 +872,7 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 
 		result, err := s.Achievement.CreateDraft(ctx, userID, &doc)
 		if err != nil {
-			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
 		}
 		return utils.JSONSuccess(c, fiber.StatusCreated, result)
 	})
@@ -323,111 +883,247 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 
-		mongoData, pgRef, err := s.Achievement.GetDetail(ctx, id)
+		caller := callerOf(c)
+		mongoData, pgRef, err := s.Achievement.GetDetail(ctx, caller, id)
 		if err != nil {
-			return utils.JSONError(c, fiber.StatusNotFound, err.Error())
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusNotFound), err.Error())
+		}
+		transitions, err := s.Achievement.AllowedTransitions(ctx, caller, pgRef)
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
 		}
 		return utils.JSONSuccess(c, fiber.StatusOK, fiber.Map{
-			"reference": pgRef,
-			"detail":    mongoData,
+			"reference":           pgRef,
+			"detail":              mongoData,
+			"allowed_transitions": transitions,
 		})
 	})
 
 	// PUT /achievements/:id (Update Draft - Mahasiswa)
-	achGroup.Put("/:id", middleware.RequirePermission(rbacCheck, "achievement:update"), func(c *fiber.Ctx) error {
-		// ... isi handler update ...
-		return utils.JSONSuccess(c, fiber.StatusOK, "Draft updated")
+	// Body: JSON Merge Patch (RFC 7396) of the Mongo document, e.g. {"title": "...", "details": {"rank": null}}
+	achGroup.Put("/:id", middleware.RequirePermission(rbacCheck, service.PermAchievementUpdate), func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		userID := c.Locals(middleware.LocalsUserID).(string)
+
+		var patch map[string]interface{}
+		if err := c.BodyParser(&patch); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid body: expected a JSON object")
+		}
+
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		mongoData, pgRef, err := s.Achievement.UpdateDraft(ctx, id, userID, patch)
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, fiber.Map{
+			"reference": pgRef,
+			"detail":    mongoData,
+		})
 	})
 
 	// DELETE /achievements/:id (Delete Draft - Mahasiswa)
-	achGroup.Delete("/:id", middleware.RequirePermission(rbacCheck, "achievement:delete"), func(c *fiber.Ctx) error {
+	achGroup.Delete("/:id", middleware.RequirePermission(rbacCheck, service.PermAchievementDelete), func(c *fiber.Ctx) error {
 		id := c.Params("id")
-		userID := c.Locals(middleware.LocalsUserID).(string)
 
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 
-		if err := s.Achievement.DeleteDraft(ctx, id, userID); err != nil {
-			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+		if err := s.Achievement.DeleteDraft(ctx, id, callerOf(c)); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
 		}
 		return utils.JSONSuccess(c, fiber.StatusOK, "Draft deleted")
 	})
 
 	// POST /achievements/:id/submit (Submit for Verification - Mahasiswa)
-	achGroup.Post("/:id/attachments", middleware.RequirePermission(rbacCheck, "achievement:update"), func(c *fiber.Ctx) error {
+	achGroup.Post("/:id/attachments", middleware.RequirePermission(rbacCheck, service.PermAchievementUpdate), func(c *fiber.Ctx) error {
 		refID := c.Params("id")
 		userID := c.Locals(middleware.LocalsUserID).(string)
 
-		// 1. Ambil File
+		// 1. Ambil File (batas ukuran, jumlah dan tipe dicek service, lihat ATTACHMENT_MAX_*)
 		file, err := c.FormFile("file")
+		if err != nil {
+			return errorResponse(c, service.ErrFileRequired, fiber.StatusBadRequest)
+		}
+
+		src, err := file.Open()
 		if err != nil {
 			return utils.JSONError(c, fiber.StatusBadRequest, "File upload failed: "+err.Error())
 		}
+		defer src.Close()
+
+		// 2. Panggil Service (file disimpan ke FileStore, Mongo menyimpan storage key)
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
 
-		// 2. Simpan File (Pastikan folder ada)
-		uniqueName := fmt.Sprintf("%d-%s", time.Now().Unix(), file.Filename)
-		savePath := fmt.Sprintf("./uploads/%s", uniqueName)
+		attachment, err := s.Achievement.AddAttachment(ctx, refID, userID, service.AttachmentUpload{
+			FileName:    file.Filename,
+			ContentType: file.Header.Get("Content-Type"),
+			Size:        file.Size,
+			Body:        src,
+		})
+		if err != nil {
+			// 400 empty_file, 403 not_owner, 409 invalid_status/too_many_attachments,
+			// 413 file_too_large/attachment_quota_exceeded, 415 unsupported_file_type
+			return errorResponse(c, err, fiber.StatusInternalServerError)
+		}
+
+		return utils.JSONSuccess(c, fiber.StatusOK, attachment)
+	})
+
+	// PUT /achievements/:id/attachments/:attachmentId (Ganti file lampiran - Mahasiswa, draft/revisi)
+	// Form field "file" seperti upload; id lampiran tetap, file lama dihapus setelah Mongo diperbarui
+	achGroup.Put("/:id/attachments/:attachmentId", middleware.RequirePermission(rbacCheck, service.PermAchievementUpdate), func(c *fiber.Ctx) error {
+		userID := c.Locals(middleware.LocalsUserID).(string)
 
-		if _, err := os.Stat("./uploads"); os.IsNotExist(err) {
-			os.Mkdir("./uploads", 0755)
+		file, err := c.FormFile("file")
+		if err != nil {
+			return errorResponse(c, service.ErrFileRequired, fiber.StatusBadRequest)
+		}
+		src, err := file.Open()
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "File upload failed: "+err.Error())
 		}
+		defer src.Close()
+
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
 
-		if err := c.SaveFile(file, savePath); err != nil {
-			return utils.JSONError(c, fiber.StatusInternalServerError, "Cannot save file")
+		attachment, err := s.Achievement.ReplaceAttachment(ctx, c.Params("id"), c.Params("attachmentId"), userID, service.AttachmentUpload{
+			FileName:    file.Filename,
+			ContentType: file.Header.Get("Content-Type"),
+			Size:        file.Size,
+			Body:        src,
+		})
+		if err != nil {
+			return errorResponse(c, err, fiber.StatusInternalServerError)
 		}
+		return utils.JSONSuccess(c, fiber.StatusOK, attachment)
+	})
+
+	// DELETE /achievements/:id/attachments/:attachmentId (Hapus lampiran - Mahasiswa, draft/revisi)
+	achGroup.Delete("/:id/attachments/:attachmentId", middleware.RequirePermission(rbacCheck, service.PermAchievementUpdate), func(c *fiber.Ctx) error {
+		userID := c.Locals(middleware.LocalsUserID).(string)
+
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
 
-		// 3. Siapkan Data
-		attachmentData := mongoModel.Attachment{
-			FileName: file.Filename,
-			URL:      "/uploads/" + uniqueName,
-			MimeType: file.Header.Get("Content-Type"),
-			Size:     file.Size,
+		if err := s.Achievement.DeleteAttachment(ctx, c.Params("id"), c.Params("attachmentId"), userID); err != nil {
+			return errorResponse(c, err, fiber.StatusInternalServerError)
 		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Attachment deleted")
+	})
 
-		// 4. Panggil Service
+	// GET /achievements/:id/attachments/:attachmentId (Unduh lampiran; scoping sama dengan detail)
+	// Mendukung header Range (satu rentang) untuk resume dan preview PDF
+	achGroup.Get("/:id/attachments/:attachmentId", func(c *fiber.Ctx) error {
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 
-		if err := s.Achievement.AddAttachment(ctx, refID, userID, attachmentData); err != nil {
-			os.Remove(savePath) // Hapus file jika gagal simpan DB
-			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+		attachment, err := s.Achievement.GetAttachment(ctx, callerOf(c), c.Params("id"), c.Params("attachmentId"))
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
 		}
+		return sendAttachment(c, s.Files, attachment)
+	})
+
+	// POST /achievements/:id/attachments/:attachmentId/link
+	// Link unduhan berumur pendek (DOWNLOAD_LINK_TTL) untuk <a href>, <img> atau email, tanpa bearer token
+	achGroup.Post("/:id/attachments/:attachmentId/link", func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
 
-		return utils.JSONSuccess(c, fiber.StatusOK, attachmentData)
+		link, err := s.Achievement.CreateAttachmentLink(ctx, callerOf(c), c.Params("id"), c.Params("attachmentId"))
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, link)
 	})
 
-	achGroup.Post("/:id/submit", middleware.RequirePermission(rbacCheck, "achievement:submit"), func(c *fiber.Ctx) error {
+	achGroup.Post("/:id/submit", middleware.RequirePermission(rbacCheck, service.PermAchievementSubmit), func(c *fiber.Ctx) error {
 		id := c.Params("id")
-		userID := c.Locals(middleware.LocalsUserID).(string)
 
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 
-		// Logic validasi "Hanya Mahasiswa" terjadi di dalam fungsi s.Achievement.Submit ini
-		if err := s.Achievement.Submit(ctx, id, userID); err != nil {
-			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+		// Logic validasi "Hanya Mahasiswa" terjadi di state machine (transition "submit")
+		if err := s.Achievement.Submit(ctx, id, callerOf(c)); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
 		}
 		return utils.JSONSuccess(c, fiber.StatusOK, "Achievement submitted successfully")
 	})
 
+	// POST /achievements/:id/revise (Rejected -> Revision, Mahasiswa)
+	achGroup.Post("/:id/revise", middleware.RequirePermission(rbacCheck, service.PermAchievementUpdate), func(c *fiber.Ctx) error {
+		id := c.Params("id")
+
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		if err := s.Achievement.StartRevision(ctx, id, callerOf(c)); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Achievement moved to revision")
+	})
+
+	// GET /achievements/:id/revisions (Snapshot setiap submit)
+	achGroup.Get("/:id/revisions", func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		list, err := s.Achievement.ListRevisions(ctx, callerOf(c), c.Params("id"))
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, list)
+	})
+
+	// GET /achievements/:id/revisions/diff?from=&to= (default: revisi terakhir vs revisi yang ditolak)
+	achGroup.Get("/:id/revisions/diff", func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		diff, err := s.Achievement.DiffRevisions(ctx, callerOf(c), c.Params("id"), utils.GetQueryInt(c, "from", 0), utils.GetQueryInt(c, "to", 0))
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, diff)
+	})
+
+	// GET /achievements/:id/revisions/:number
+	achGroup.Get("/:id/revisions/:number", func(c *fiber.Ctx) error {
+		number, err := c.ParamsInt("number")
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid revision number")
+		}
+
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		rev, err := s.Achievement.GetRevision(ctx, callerOf(c), c.Params("id"), number)
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, rev)
+	})
+
 	// POST /achievements/:id/verify (Verify - Dosen Wali)
-	achGroup.Post("/:id/verify", middleware.RequirePermission(rbacCheck, "achievement:verify"), func(c *fiber.Ctx) error {
+	achGroup.Post("/:id/verify", middleware.RequirePermission(rbacCheck, service.PermAchievementVerify), func(c *fiber.Ctx) error {
 		id := c.Params("id")
-		verifierID := c.Locals(middleware.LocalsUserID).(string)
 
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 
-		if err := s.Achievement.Verify(ctx, id, verifierID); err != nil {
-			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+		if err := s.Achievement.Verify(ctx, id, callerOf(c)); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
 		}
 		return utils.JSONSuccess(c, fiber.StatusOK, "Achievement verified")
 	})
 
 	// POST /achievements/:id/reject (Reject - Dosen Wali)
-	achGroup.Post("/:id/reject", middleware.RequirePermission(rbacCheck, "achievement:verify"), func(c *fiber.Ctx) error {
+	achGroup.Post("/:id/reject", middleware.RequirePermission(rbacCheck, service.PermAchievementVerify), func(c *fiber.Ctx) error {
 		id := c.Params("id")
-		verifierID := c.Locals(middleware.LocalsUserID).(string)
 
 		var req struct {
 			Note string `json:"note"`
@@ -439,8 +1135,8 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 
-		if err := s.Achievement.Reject(ctx, id, verifierID, req.Note); err != nil {
-			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+		if err := s.Achievement.Reject(ctx, id, callerOf(c), req.Note); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
 		}
 		return utils.JSONSuccess(c, fiber.StatusOK, "Achievement rejected")
 	})
@@ -451,26 +1147,81 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 
-		hist, err := s.Report.GetAchievementHistory(ctx, id)
+		hist, err := s.Report.GetAchievementHistory(ctx, callerOf(c), id)
 		if err != nil {
-			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
 		}
 		return utils.JSONSuccess(c, fiber.StatusOK, hist)
 	})
 
+	// =========================================================================
+	// 5.6 ACHIEVEMENT TYPES (Schema details per jenis prestasi)
+	// =========================================================================
+	typeGroup := api.Group("/achievement-types", jwtAuth)
+
+	// GET /achievement-types (dipakai frontend untuk render form)
+	typeGroup.Get("/", func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		list, err := s.AchievementType.List(ctx)
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, list)
+	})
+
+	// GET /achievement-types/:type
+	typeGroup.Get("/:type", func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+		schema, err := s.AchievementType.Get(ctx, c.Params("type"))
+		if err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, schema)
+	})
+
+	// PUT /achievement-types/:type (Create/Replace Schema - Admin)
+	typeGroup.Put("/:type", middleware.RequirePermission(rbacCheck, service.PermAchievementTypeManage), func(c *fiber.Ctx) error {
+		var schema mongoModel.AchievementTypeSchema
+		if err := c.BodyParser(&schema); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid body")
+		}
+		schema.Type = c.Params("type")
+
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		if err := s.AchievementType.Upsert(ctx, &schema); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, schema)
+	})
+
+	// DELETE /achievement-types/:type (Reset ke default - Admin)
+	typeGroup.Delete("/:type", middleware.RequirePermission(rbacCheck, service.PermAchievementTypeManage), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		if err := s.AchievementType.Delete(ctx, c.Params("type")); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Achievement type schema deleted")
+	})
+
 	// =========================================================================
 	// 5.8 REPORTS & ANALYTICS
 	// =========================================================================
-	reportGroup := api.Group("/reports", middleware.NewJWTMiddleware())
+	reportGroup := api.Group("/reports", jwtAuth)
 
 	// GET /reports/statistics (Global Stats - Admin/Dosen)
-	reportGroup.Get("/statistics", middleware.RequirePermission(rbacCheck, "report:view"), func(c *fiber.Ctx) error {
+	reportGroup.Get("/statistics", middleware.RequirePermission(rbacCheck, service.PermReportView), func(c *fiber.Ctx) error {
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 
-		stats, err := s.Report.GetAllAchievementsStatistics(ctx)
+		stats, err := s.Report.GetAllAchievementsStatistics(ctx, callerOf(c))
 		if err != nil {
-			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
 		}
 		return utils.JSONSuccess(c, fiber.StatusOK, stats)
 	})
@@ -481,10 +1232,148 @@ func RegisterRoutes(app *fiber.App, s *service.Services) {
 		ctx, cancel := timeoutContext(c)
 		defer cancel()
 
-		stats, err := s.Report.GetStudentStatistics(ctx, studentID)
+		stats, err := s.Report.GetStudentStatistics(ctx, callerOf(c), studentID)
 		if err != nil {
-			return utils.JSONError(c, fiber.StatusNotFound, err.Error())
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusNotFound), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, stats)
+	})
+
+	// =========================================================================
+	// 5.9 SYSTEM (OUTBOX MONGO <-> POSTGRES)
+	// =========================================================================
+	systemGroup := api.Group("/system", jwtAuth)
+
+	// GET /system/outbox (Jumlah entry pending / failed, untuk monitoring)
+	systemGroup.Get("/outbox", middleware.RequirePermission(rbacCheck, service.PermOutboxRead), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		stats, err := s.Outbox.Stats(ctx)
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
 		}
 		return utils.JSONSuccess(c, fiber.StatusOK, stats)
 	})
+
+	// GET /system/outbox/failed?limit=
+	systemGroup.Get("/outbox/failed", middleware.RequirePermission(rbacCheck, service.PermOutboxRead), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		list, err := s.Outbox.ListFailed(ctx, utils.GetQueryInt(c, "limit", 50))
+		if err != nil {
+			return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, list)
+	})
+
+	// POST /system/outbox/:id/retry (Jalankan ulang entry yang failed)
+	systemGroup.Post("/outbox/:id/retry", middleware.RequirePermission(rbacCheck, service.PermOutboxManage), func(c *fiber.Ctx) error {
+		ctx, cancel := timeoutContext(c)
+		defer cancel()
+
+		if err := s.Outbox.Retry(ctx, c.Params("id")); err != nil {
+			return utils.JSONError(c, service.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+		return utils.JSONSuccess(c, fiber.StatusOK, "Outbox entry requeued")
+	})
+}
+
+// loginResponse is the body of /auth/login and /auth/login/mfa: the token pair, or the
+// mfa_token when a second factor is still needed.
+func loginResponse(res *service.LoginResult) fiber.Map {
+	if res.MFARequired {
+		return fiber.Map{
+			"mfa_required":            true,
+			"mfa_enrollment_required": res.MFAEnrollmentRequired,
+			"mfa_token":               res.MFAToken,
+			"expires_in":              int64(service.MFATokenTTL.Seconds()),
+		}
+	}
+	body := fiber.Map{
+		"token":         res.AccessToken, // sama dengan access_token, dipertahankan untuk client lama
+		"access_token":  res.AccessToken,
+		"refresh_token": res.RefreshToken,
+		"token_type":    res.TokenType,
+		"expires_in":    res.ExpiresIn,
+		"user":          res.User,
+	}
+	if res.RecoveryCodes != nil {
+		body["recovery_codes"] = res.RecoveryCodes
+	}
+	return body
+}
+
+// errorResponse is utils.JSONError plus the "code" of a service.CustomError, for endpoints whose
+// clients act on the reason (e.g. upload rejections).
+func errorResponse(c *fiber.Ctx, err error, fallback int) error {
+	var ce *service.CustomError
+	if !errors.As(err, &ce) {
+		return utils.JSONError(c, fallback, err.Error())
+	}
+	return c.Status(service.ErrorStatus(ce, fallback)).JSON(fiber.Map{
+		"status":  "error",
+		"code":    ce.Code,
+		"message": ce.Message,
+	})
+}
+
+// sendAttachment streams an attachment from the FileStore with its original file name, honouring a
+// single "Range: bytes=" request (206, or 416 when the range is outside the file).
+func sendAttachment(c *fiber.Ctx, files utils.FileStore, attachment *mongoModel.Attachment) error {
+	if files == nil {
+		return utils.JSONError(c, fiber.StatusInternalServerError, "file storage is not configured")
+	}
+	// the body is streamed after the handler returns, so no timeoutContext here
+	ctx := c.Context()
+	info, err := files.Stat(ctx, attachment.StorageKey)
+	if errors.Is(err, utils.ErrFileNotFound) {
+		return utils.JSONError(c, fiber.StatusNotFound, service.ErrAttachmentNotFound.Error())
+	}
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	lastModified := ""
+	if !info.ModTime.IsZero() {
+		lastModified = info.ModTime.UTC().Format(http.TimeFormat)
+	}
+	rangeHeader := c.Get(fiber.HeaderRange)
+	if ifRange := c.Get(fiber.HeaderIfRange); ifRange != "" && ifRange != lastModified {
+		rangeHeader = "" // file changed since the client's first part: send it whole
+	}
+	offset, length, partial, err := utils.ParseByteRange(rangeHeader, info.Size)
+	if err != nil {
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", info.Size))
+		return utils.JSONError(c, fiber.StatusRequestedRangeNotSatisfiable, err.Error())
+	}
+
+	var body io.ReadCloser
+	if partial {
+		body, _, err = files.GetRange(ctx, attachment.StorageKey, offset, length)
+	} else {
+		body, _, err = files.Get(ctx, attachment.StorageKey)
+	}
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	contentType := attachment.MimeType
+	if contentType == "" {
+		contentType = info.ContentType
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, utils.ContentDisposition("attachment", attachment.FileName))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	if lastModified != "" {
+		c.Set(fiber.HeaderLastModified, lastModified)
+	}
+	if partial {
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, info.Size))
+		c.Status(fiber.StatusPartialContent)
+	}
+	return c.SendStream(body, int(length))
 }
@@ -1,53 +0,0 @@
--- Updated all NIM values to use unique format starting with 434 to avoid conflicts
--- Insert dummy data untuk alumni (50 records)
-INSERT INTO alumni (nim, nama, jurusan, angkatan, tahun_lulus, komisariat, email, no_telepon, alamat, created_at, updated_at) VALUES
-('434231101', 'Ahmad Rizki Pratama', 'Teknik Informatika', 2019, 2023, 'Surabaya', 'ahmad.rizki@email.com', '081234567001', 'Jl. Merdeka No. 1, Surabaya', NOW(), NOW()),
-('434231102', 'Siti Nurhaliza', 'Sistem Informasi', 2019, 2023, 'Jakarta', 'siti.nurhaliza@email.com', '081234567002', 'Jl. Sudirman No. 2, Jakarta', NOW(), NOW()),
-('434231103', 'Budi Santoso', 'Teknik Komputer', 2019, 2023, 'Bandung', 'budi.santoso@email.com', '081234567003', 'Jl. Asia Afrika No. 3, Bandung', NOW(), NOW()),
-('434231104', 'Dewi Sartika', 'Teknik Informatika', 2020, 2024, 'Surabaya', 'dewi.sartika@email.com', '081234567004', 'Jl. Pahlawan No. 4, Surabaya', NOW(), NOW()),
-('434231105', 'Eko Prasetyo', 'Sistem Informasi', 2020, 2024, 'Yogyakarta', 'eko.prasetyo@email.com', '081234567005', 'Jl. Malioboro No. 5, Yogyakarta', NOW(), NOW()),
-('434231106', 'Fitri Handayani', 'Teknik Komputer', 2020, 2024, 'Medan', 'fitri.handayani@email.com', '081234567006', 'Jl. Gatot Subroto No. 6, Medan', NOW(), NOW()),
-('434231107', 'Gunawan Wijaya', 'Teknik Informatika', 2018, 2022, 'Jakarta', 'gunawan.wijaya@email.com', '081234567007', 'Jl. Thamrin No. 7, Jakarta', NOW(), NOW()),
-('434231108', 'Hani Rahmawati', 'Sistem Informasi', 2018, 2022, 'Surabaya', 'hani.rahmawati@email.com', '081234567008', 'Jl. Pemuda No. 8, Surabaya', NOW(), NOW()),
-('434231109', 'Indra Kurniawan', 'Teknik Komputer', 2018, 2022, 'Bandung', 'indra.kurniawan@email.com', '081234567009', 'Jl. Braga No. 9, Bandung', NOW(), NOW()),
-('434231110', 'Joko Widodo', 'Teknik Informatika', 2021, 2025, 'Semarang', 'joko.widodo@email.com', '081234567010', 'Jl. Pandanaran No. 10, Semarang', NOW(), NOW()),
-('434231111', 'Kartika Sari', 'Sistem Informasi', 2021, 2025, 'Surabaya', 'kartika.sari@email.com', '081234567011', 'Jl. Diponegoro No. 11, Surabaya', NOW(), NOW()),
-('434231112', 'Lukman Hakim', 'Teknik Komputer', 2021, 2025, 'Makassar', 'lukman.hakim@email.com', '081234567012', 'Jl. Sultan Hasanuddin No. 12, Makassar', NOW(), NOW()),
-('434231113', 'Maya Sari', 'Teknik Informatika', 2017, 2021, 'Jakarta', 'maya.sari@email.com', '081234567013', 'Jl. Kuningan No. 13, Jakarta', NOW(), NOW()),
-('434231114', 'Nanda Pratama', 'Sistem Informasi', 2017, 2021, 'Surabaya', 'nanda.pratama@email.com', '081234567014', 'Jl. Gubeng No. 14, Surabaya', NOW(), NOW()),
-('434231115', 'Oscar Ramadhan', 'Teknik Komputer', 2017, 2021, 'Palembang', 'oscar.ramadhan@email.com', '081234567015', 'Jl. Sudirman No. 15, Palembang', NOW(), NOW()),
-('434231116', 'Putri Maharani', 'Teknik Informatika', 2022, 2026, 'Denpasar', 'putri.maharani@email.com', '081234567016', 'Jl. Gajah Mada No. 16, Denpasar', NOW(), NOW()),
-('434231117', 'Qori Ananda', 'Sistem Informasi', 2022, 2026, 'Surabaya', 'qori.ananda@email.com', '081234567017', 'Jl. Raya Darmo No. 17, Surabaya', NOW(), NOW()),
-('434231118', 'Rizal Fauzi', 'Teknik Komputer', 2022, 2026, 'Balikpapan', 'rizal.fauzi@email.com', '081234567018', 'Jl. Jenderal Sudirman No. 18, Balikpapan', NOW(), NOW()),
-('434231119', 'Sari Dewi', 'Teknik Informatika', 2016, 2020, 'Jakarta', 'sari.dewi@email.com', '081234567019', 'Jl. Menteng No. 19, Jakarta', NOW(), NOW()),
-('434231120', 'Taufik Hidayat', 'Sistem Informasi', 2016, 2020, 'Surabaya', 'taufik.hidayat@email.com', '081234567020', 'Jl. Wonokromo No. 20, Surabaya', NOW(), NOW()),
-('434231121', 'Umi Kalsum', 'Teknik Komputer', 2016, 2020, 'Padang', 'umi.kalsum@email.com', '081234567021', 'Jl. Imam Bonjol No. 21, Padang', NOW(), NOW()),
-('434231122', 'Vina Panduwinata', 'Teknik Informatika', 2023, 2027, 'Manado', 'vina.panduwinata@email.com', '081234567022', 'Jl. Sam Ratulangi No. 22, Manado', NOW(), NOW()),
-('434231123', 'Wahyu Setiawan', 'Sistem Informasi', 2023, 2027, 'Surabaya', 'wahyu.setiawan@email.com', '081234567023', 'Jl. Ngagel No. 23, Surabaya', NOW(), NOW()),
-('434231124', 'Xenia Putri', 'Teknik Komputer', 2023, 2027, 'Pontianak', 'xenia.putri@email.com', '081234567024', 'Jl. Gajah Mada No. 24, Pontianak', NOW(), NOW()),
-('434231125', 'Yudi Setiawan', 'Teknik Informatika', 2015, 2019, 'Jakarta', 'yudi.setiawan@email.com', '081234567025', 'Jl. Kemang No. 25, Jakarta', NOW(), NOW()),
-('434231126', 'Zahra Amelia', 'Sistem Informasi', 2015, 2019, 'Surabaya', 'zahra.amelia@email.com', '081234567026', 'Jl. Klampis No. 26, Surabaya', NOW(), NOW()),
-('434231127', 'Andi Wijaya', 'Teknik Komputer', 2015, 2019, 'Makassar', 'andi.wijaya@email.com', '081234567027', 'Jl. Veteran No. 27, Makassar', NOW(), NOW()),
-('434231128', 'Bella Safira', 'Teknik Informatika', 2024, 2028, 'Batam', 'bella.safira@email.com', '081234567028', 'Jl. Ahmad Yani No. 28, Batam', NOW(), NOW()),
-('434231129', 'Candra Kirana', 'Sistem Informasi', 2024, 2028, 'Surabaya', 'candra.kirana@email.com', '081234567029', 'Jl. Mayjend Sungkono No. 29, Surabaya', NOW(), NOW()),
-('434231130', 'Dimas Anggara', 'Teknik Komputer', 2024, 2028, 'Solo', 'dimas.anggara@email.com', '081234567030', 'Jl. Slamet Riyadi No. 30, Solo', NOW(), NOW()),
-('434231131', 'Eka Putri', 'Teknik Informatika', 2014, 2018, 'Jakarta', 'eka.putri@email.com', '081234567031', 'Jl. Senayan No. 31, Jakarta', NOW(), NOW()),
-('434231132', 'Fajar Nugraha', 'Sistem Informasi', 2014, 2018, 'Surabaya', 'fajar.nugraha@email.com', '081234567032', 'Jl. Kertajaya No. 32, Surabaya', NOW(), NOW()),
-('434231133', 'Gita Savitri', 'Teknik Komputer', 2014, 2018, 'Yogyakarta', 'gita.savitri@email.com', '081234567033', 'Jl. Kaliurang No. 33, Yogyakarta', NOW(), NOW()),
-('434231134', 'Hendra Gunawan', 'Teknik Informatika', 2013, 2017, 'Bandung', 'hendra.gunawan@email.com', '081234567034', 'Jl. Dago No. 34, Bandung', NOW(), NOW()),
-('434231135', 'Ira Wati', 'Sistem Informasi', 2013, 2017, 'Surabaya', 'ira.wati@email.com', '081234567035', 'Jl. Rungkut No. 35, Surabaya', NOW(), NOW()),
-('434231136', 'Jihan Aulia', 'Teknik Komputer', 2013, 2017, 'Medan', 'jihan.aulia@email.com', '081234567036', 'Jl. Sisingamangaraja No. 36, Medan', NOW(), NOW()),
-('434231137', 'Kevin Aprilio', 'Teknik Informatika', 2012, 2016, 'Jakarta', 'kevin.aprilio@email.com', '081234567037', 'Jl. Casablanca No. 37, Jakarta', NOW(), NOW()),
-('434231138', 'Luna Maya', 'Sistem Informasi', 2012, 2016, 'Surabaya', 'luna.maya@email.com', '081234567038', 'Jl. Mulyosari No. 38, Surabaya', NOW(), NOW()),
-('434231139', 'Mario Teguh', 'Teknik Komputer', 2012, 2016, 'Semarang', 'mario.teguh@email.com', '081234567039', 'Jl. Pemuda No. 39, Semarang', NOW(), NOW()),
-('434231140', 'Nina Zatulini', 'Teknik Informatika', 2011, 2015, 'Palembang', 'nina.zatulini@email.com', '081234567040', 'Jl. Veteran No. 40, Palembang', NOW(), NOW()),
-('434231141', 'Oki Setiana', 'Sistem Informasi', 2011, 2015, 'Surabaya', 'oki.setiana@email.com', '081234567041', 'Jl. Tenggilis No. 41, Surabaya', NOW(), NOW()),
-('434231142', 'Pasha Ungu', 'Teknik Komputer', 2011, 2015, 'Pekanbaru', 'pasha.ungu@email.com', '081234567042', 'Jl. Sudirman No. 42, Pekanbaru', NOW(), NOW()),
-('434231143', 'Queen Latifah', 'Teknik Informatika', 2010, 2014, 'Jakarta', 'queen.latifah@email.com', '081234567043', 'Jl. Pancoran No. 43, Jakarta', NOW(), NOW()),
-('434231144', 'Reza Rahadian', 'Sistem Informasi', 2010, 2014, 'Surabaya', 'reza.rahadian@email.com', '081234567044', 'Jl. Kalijudan No. 44, Surabaya', NOW(), NOW()),
-('434231145', 'Sinta Nuriyah', 'Teknik Komputer', 2010, 2014, 'Banjarmasin', 'sinta.nuriyah@email.com', '081234567045', 'Jl. Lambung Mangkurat No. 45, Banjarmasin', NOW(), NOW()),
-('434231146', 'Tora Sudiro', 'Teknik Informatika', 2009, 2013, 'Denpasar', 'tora.sudiro@email.com', '081234567046', 'Jl. Hayam Wuruk No. 46, Denpasar', NOW(), NOW()),
-('434231147', 'Ussy Sulistiawaty', 'Sistem Informasi', 2009, 2013, 'Surabaya', 'ussy.sulistiawaty@email.com', '081234567047', 'Jl. Manyar No. 47, Surabaya', NOW(), NOW()),
-('434231148', 'Vicky Prasetyo', 'Teknik Komputer', 2009, 2013, 'Samarinda', 'vicky.prasetyo@email.com', '081234567048', 'Jl. Mulawarman No. 48, Samarinda', NOW(), NOW()),
-('434231149', 'Wulan Guritno', 'Teknik Informatika', 2008, 2012, 'Jakarta', 'wulan.guritno@email.com', '081234567049', 'Jl. Pondok Indah No. 49, Jakarta', NOW(), NOW()),
-('434231150', 'Yuki Kato', 'Sistem Informasi', 2008, 2012, 'Surabaya', 'yuki.kato@email.com', '081234567050', 'Jl. Pakuwon No. 50, Surabaya', NOW(), NOW());
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"errors"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// ErrRangeNotSatisfiable is returned by ParseByteRange when the range lies outside the file.
+var ErrRangeNotSatisfiable = errors.New("requested range not satisfiable")
+
+// ParseByteRange interprets a Range header for a file of size bytes and returns the offset and
+// length to send. partial is false when the whole file should be sent: no header, a unit other
+// than bytes, a malformed value or several ranges (which may be ignored per RFC 9110).
+func ParseByteRange(header string, size int64) (offset, length int64, partial bool, err error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, size, false, nil
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, size, false, nil
+	}
+	if first == "" {
+		// suffix range "-N": the last N bytes
+		n, perr := strconv.ParseInt(last, 10, 64)
+		if perr != nil || n < 0 {
+			return 0, size, false, nil
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, false, ErrRangeNotSatisfiable
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, true, nil
+	}
+	start, perr := strconv.ParseInt(first, 10, 64)
+	if perr != nil || start < 0 {
+		return 0, size, false, nil
+	}
+	end := size - 1
+	if last != "" {
+		if end, perr = strconv.ParseInt(last, 10, 64); perr != nil || end < start {
+			return 0, size, false, nil
+		}
+		if end > size-1 {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return 0, 0, false, ErrRangeNotSatisfiable
+	}
+	return start, end - start + 1, true, nil
+}
+
+// ContentDisposition builds an "attachment" (or "inline") header value for filename; names that
+// are not plain ASCII are sent in the RFC 5987 filename* form.
+func ContentDisposition(disposition, filename string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '/' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+	if name == "" {
+		return disposition
+	}
+	if v := mime.FormatMediaType(disposition, map[string]string{"filename": name}); v != "" {
+		return v
+	}
+	return disposition
+}
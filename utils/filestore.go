@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrFileNotFound is returned by FileStore methods when no object has the key.
+var ErrFileNotFound = errors.New("file not found")
+
+// FileInfo describes a stored object.
+type FileInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// FileStore keeps uploaded files (attachment evidence) under slash separated keys such as
+// "achievements/<id>/<name>". LocalFileStore writes to a directory, S3FileStore to any
+// S3-compatible bucket (AWS S3, MinIO).
+type FileStore interface {
+	// Put stores size bytes from r under key, replacing an existing object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object; the caller closes the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, *FileInfo, error)
+	// GetRange opens length bytes starting at offset (both within the object, see Stat).
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, *FileInfo, error)
+	// Delete removes the object; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*FileInfo, error)
+	// PresignGet returns a URL that downloads the object without credentials until ttl has passed.
+	PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// readCloser reads from one reader and closes another (e.g. a LimitReader over a file).
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// ValidateFileKey refuses keys that are empty, absolute or step out of the store ("..").
+func ValidateFileKey(key string) error {
+	if key == "" || len(key) > 512 || strings.HasPrefix(key, "/") || strings.ContainsAny(key, "\\\x00") {
+		return fmt.Errorf("invalid file key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid file key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalFileStore keeps files in a directory. Only suitable for a single instance (or a shared
+// volume); presigned URLs point to URLPrefix, which the app serves after VerifySignature.
+type LocalFileStore struct {
+	dir       string
+	urlPrefix string
+	signer    *LinkSigner
+}
+
+// NewLocalFileStore creates dir if needed; presigned URLs are signed with signer.
+func NewLocalFileStore(dir, urlPrefix string, signer *LinkSigner) (*LocalFileStore, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalFileStore{dir: abs, urlPrefix: strings.TrimRight(urlPrefix, "/"), signer: signer}, nil
+}
+
+func (s *LocalFileStore) path(key string) (string, error) {
+	if err := ValidateFileKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes to a temporary file first, so readers never see a partial file.
+func (s *LocalFileStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after the rename
+
+	n, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if size >= 0 && n != size {
+		return fmt.Errorf("file %s: wrote %d bytes, expected %d", key, n, size)
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *LocalFileStore) Get(ctx context.Context, key string) (io.ReadCloser, *FileInfo, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, localFileInfo(key, st), nil
+}
+
+func (s *LocalFileStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, *FileInfo, error) {
+	rc, info, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f := rc.(*os.File)
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return readCloser{io.LimitReader(f, length), f}, info, nil
+}
+
+func (s *LocalFileStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalFileStore) Stat(ctx context.Context, key string) (*FileInfo, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	st, err := os.Stat(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return localFileInfo(key, st), nil
+}
+
+// PresignGet returns URLPrefix/key?expires=<unix>&signature=<hmac>.
+func (s *LocalFileStore) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if err := ValidateFileKey(key); err != nil {
+		return "", err
+	}
+	expires, signature := s.signer.Sign("file:"+key, time.Now().Add(ttl))
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("signature", signature)
+	return s.urlPrefix + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + q.Encode(), nil
+}
+
+// VerifySignature checks the expires and signature query values of a presigned URL.
+func (s *LocalFileStore) VerifySignature(key, expires, signature string) error {
+	return s.signer.Verify("file:"+key, expires, signature)
+}
+
+func localFileInfo(key string, st os.FileInfo) *FileInfo {
+	ct := mime.TypeByExtension(path.Ext(key))
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	return &FileInfo{Key: key, Size: st.Size(), ContentType: ct, ModTime: st.ModTime()}
+}
@@ -34,19 +34,23 @@ type JWTClaims struct {
 	jwt.RegisteredClaims
 }
 
+// defaultKeySet verifies tokens in ParseAndValidateToken; set once at startup with SetJWTKeySet.
+var defaultKeySet *JWTKeySet
+
+// SetJWTKeySet registers the key set used by ParseAndValidateToken and NewJWTMiddleware.
+func SetJWTKeySet(ks *JWTKeySet) {
+	defaultKeySet = ks
+}
+
 // ParseAndValidateToken verifies and returns *JWTClaims (uses jwt package).
 // Returns typed claims or error.
 func ParseAndValidateToken(tokenString string) (*JWTClaims, error) {
+	if defaultKeySet == nil {
+		return nil, ErrTokenInvalid
+	}
 	claims := &JWTClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		// enforce HMAC signing method
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrTokenBadMethod
-		}
-		// GetJWTSecretBytes is assumed to exist elsewhere in this package.
-		// It should return the HMAC secret as []byte, e.g. []byte(os.Getenv("JWT_SECRET"))
-		return GetJWTSecretBytes(), nil
-	}, jwt.WithLeeway(5*time.Second))
+	// the key set picks the key by "kid" and enforces that key's algorithm
+	token, err := defaultKeySet.Parse(tokenString, claims, jwt.WithLeeway(5*time.Second))
 	if err != nil {
 		// jwt/v5 exposes jwt.ErrTokenExpired which can be matched with errors.Is
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -60,10 +64,6 @@ func ParseAndValidateToken(tokenString string) (*JWTClaims, error) {
 	return claims, nil
 }
 
-func GetJWTSecretBytes() interface{} {
-	panic("unimplemented")
-}
-
 // Helper: extract Bearer token from Authorization header
 func extractTokenFromHeader(auth string) (string, error) {
 	if auth == "" {
@@ -0,0 +1,312 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported JWT algorithms
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+	AlgHS256 = "HS256" // shared secret, development only; never published in the JWKS
+)
+
+// JWTKeyConfig says where the signing keys come from.
+type JWTKeyConfig struct {
+	Alg              string   // RS256, EdDSA or HS256
+	PrivateKeyFile   string   // PEM (PKCS#8, or PKCS#1 for RSA) of the active signing key
+	KeyID            string   // kid of the active key; default is its RFC 7638 thumbprint
+	PreviousKeyFiles []string // PEM public or private keys that still verify (rotation)
+	Secret           string   // HS256 only
+	AllowEphemeral   bool     // generate a throwaway key when PrivateKeyFile is empty
+}
+
+// JWTKey is one verification key, optionally able to sign.
+type JWTKey struct {
+	ID     string
+	Alg    string
+	signer interface{} // *rsa.PrivateKey, ed25519.PrivateKey or []byte; nil for verify-only keys
+	public interface{} // *rsa.PublicKey, ed25519.PublicKey or []byte
+}
+
+// JWTKeySet signs tokens with the active key (kid header set) and verifies tokens signed by
+// any key it holds, so tokens issued before a rotation stay valid until they expire.
+type JWTKeySet struct {
+	mu     sync.RWMutex
+	active *JWTKey
+	keys   map[string]*JWTKey
+}
+
+// LoadJWTKeySet builds the key set described by cfg.
+func LoadJWTKeySet(cfg JWTKeyConfig) (*JWTKeySet, error) {
+	if cfg.Alg == "" {
+		cfg.Alg = AlgRS256
+	}
+	ks := &JWTKeySet{keys: map[string]*JWTKey{}}
+
+	var active *JWTKey
+	var err error
+	switch {
+	case cfg.Alg == AlgHS256:
+		if cfg.Secret == "" {
+			return nil, errors.New("HS256 needs JWT_SECRET")
+		}
+		active = &JWTKey{Alg: AlgHS256, signer: []byte(cfg.Secret), public: []byte(cfg.Secret)}
+		active.ID = cfg.KeyID
+		if active.ID == "" {
+			sum := sha256.Sum256([]byte(cfg.Secret))
+			active.ID = "hs-" + base64.RawURLEncoding.EncodeToString(sum[:8])
+		}
+	case cfg.PrivateKeyFile != "":
+		if active, err = readJWTKeyFile(cfg.PrivateKeyFile); err != nil {
+			return nil, err
+		}
+		if active.signer == nil {
+			return nil, fmt.Errorf("%s holds a public key, the active key must be private", cfg.PrivateKeyFile)
+		}
+		if active.Alg != cfg.Alg {
+			return nil, fmt.Errorf("%s is an %s key but JWT_ALG is %s", cfg.PrivateKeyFile, active.Alg, cfg.Alg)
+		}
+		if cfg.KeyID != "" {
+			active.ID = cfg.KeyID
+		}
+	case cfg.AllowEphemeral:
+		if active, err = GenerateJWTKey(cfg.Alg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("no JWT signing key configured (JWT_PRIVATE_KEY_FILE)")
+	}
+	ks.active = active
+	ks.keys[active.ID] = active
+
+	for _, file := range cfg.PreviousKeyFiles {
+		k, err := readJWTKeyFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := ks.keys[k.ID]; !dup {
+			ks.keys[k.ID] = k
+		}
+	}
+	return ks, nil
+}
+
+// GenerateJWTKey creates a new RS256 (2048 bit) or EdDSA (Ed25519) signing key.
+func GenerateJWTKey(alg string) (*JWTKey, error) {
+	k := &JWTKey{Alg: alg}
+	switch alg {
+	case AlgRS256:
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		k.signer, k.public = priv, &priv.PublicKey
+	case AlgEdDSA:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		k.signer, k.public = priv, pub
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", alg)
+	}
+	var err error
+	k.ID, err = jwkThumbprint(k.public)
+	return k, err
+}
+
+// PrivatePEM encodes the signing key as PKCS#8 PEM.
+func (k *JWTKey) PrivatePEM() ([]byte, error) {
+	if k.signer == nil || k.Alg == AlgHS256 {
+		return nil, errors.New("key has no private part")
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(k.signer)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// ActiveKeyID returns the kid new tokens are signed with.
+func (ks *JWTKeySet) ActiveKeyID() string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.active.ID
+}
+
+// Sign signs claims with the active key and sets the kid header.
+func (ks *JWTKeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	k := ks.active
+	ks.mu.RUnlock()
+
+	token := jwt.NewWithClaims(signingMethod(k.Alg), claims)
+	token.Header["kid"] = k.ID
+	return token.SignedString(k.signer)
+}
+
+// Parse verifies tokenString against the key named by its kid header (tokens without a kid are
+// tried against the active key) and fills claims.
+func (ks *JWTKeySet) Parse(tokenString string, claims jwt.Claims, opts ...jwt.ParserOption) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		ks.mu.RLock()
+		defer ks.mu.RUnlock()
+		k := ks.active
+		if kid, ok := t.Header["kid"].(string); ok {
+			if k, ok = ks.keys[kid]; !ok {
+				return nil, fmt.Errorf("unknown key id %q", kid)
+			}
+		}
+		if t.Method.Alg() != k.Alg {
+			return nil, ErrTokenBadMethod
+		}
+		return k.public, nil
+	}, opts...)
+}
+
+// JWK is one entry of a JSON Web Key Set (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS returns the public keys (active and previous) for /.well-known/jwks.json.
+// HS256 secrets are never included.
+func (ks *JWTKeySet) JWKS() map[string][]JWK {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	ids := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		if id != ks.active.ID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	out := []JWK{}
+	for _, id := range append([]string{ks.active.ID}, ids...) {
+		k := ks.keys[id]
+		if jwk, ok := toJWK(k.public); ok {
+			jwk.Kid, jwk.Use, jwk.Alg = k.ID, "sig", k.Alg
+			out = append(out, jwk)
+		}
+	}
+	return map[string][]JWK{"keys": out}
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	switch alg {
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	case AlgHS256:
+		return jwt.SigningMethodHS256
+	}
+	return jwt.SigningMethodRS256
+}
+
+func readJWTKeyFile(path string) (*JWTKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	k := &JWTKey{}
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		k.Alg, k.signer, k.public = AlgRS256, key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.Alg, k.public = AlgRS256, key
+	case ed25519.PrivateKey:
+		k.Alg, k.signer, k.public = AlgEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.Alg, k.public = AlgEdDSA, key
+	default:
+		return nil, fmt.Errorf("%s: only RSA and Ed25519 keys are supported", path)
+	}
+	if k.ID, err = jwkThumbprint(k.public); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func toJWK(pub interface{}) (JWK, bool) {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(key)}, true
+	}
+	return JWK{}, false
+}
+
+// jwkThumbprint is the RFC 7638 SHA-256 thumbprint, used as the default kid.
+func jwkThumbprint(pub interface{}) (string, error) {
+	jwk, ok := toJWK(pub)
+	if !ok {
+		return "", errors.New("unsupported public key")
+	}
+	var members interface{}
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// MailMessage is a plain text email.
+type MailMessage struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer sends email. SMTPMailer delivers it, LogMailer only writes it to the log (development).
+type Mailer interface {
+	Send(ctx context.Context, msg MailMessage) error
+}
+
+// LogMailer writes every message, links included, to the log instead of sending it.
+type LogMailer struct {
+	Logger *log.Logger // nil uses the standard logger
+}
+
+func (m LogMailer) Send(ctx context.Context, msg MailMessage) error {
+	logf := log.Printf
+	if m.Logger != nil {
+		logf = m.Logger.Printf
+	}
+	logf("mail (not sent) to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+// SMTPMailer sends through an SMTP server. Port 465 style servers need ImplicitTLS; otherwise
+// STARTTLS is used whenever the server offers it.
+type SMTPMailer struct {
+	Host        string
+	Port        string
+	Username    string // empty disables AUTH
+	Password    string
+	From        string // "Name <address>" or a bare address
+	ImplicitTLS bool
+	Timeout     time.Duration // dial plus whole conversation, default 30s
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg MailMessage) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("mail from: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("mail to: %w", err)
+	}
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return errors.New("mail subject contains a line break")
+	}
+
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	addr := net.JoinHostPort(m.Host, m.Port)
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	if m.ImplicitTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: m.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if !m.ImplicitTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if m.Username != "" {
+		// PlainAuth refuses to send the password over an unencrypted connection (except to localhost)
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildMail(from, to, msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func buildMail(from, to *mail.Address, msg MailMessage) []byte {
+	var buf bytes.Buffer
+	id := make([]byte, 12)
+	_, _ = rand.Read(id)
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&buf)
+	_, _ = qp.Write([]byte(strings.ReplaceAll(msg.Text, "\n", "\r\n")))
+	_ = qp.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpSink is an in-process SMTP server that speaks just enough of the protocol for SMTPMailer.
+type smtpSink struct {
+	ln        net.Listener
+	startTLS  string // "" not offered, "refuse" answers 454, "handshake" starts TLS with an untrusted certificate
+	auth      bool   // offer AUTH PLAIN
+	authReply string // reply to AUTH, default 235
+
+	mu   sync.Mutex
+	cmds []string
+	data string // DATA payload; textproto turns its CRLF line endings into LF
+	done chan struct{}
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpSink{ln: ln, done: make(chan struct{})}
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+// serve accepts one connection; call it after the sink is configured.
+func (s *smtpSink) serve() {
+	go func() {
+		defer close(s.done)
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		s.converse(conn)
+	}()
+}
+
+func (s *smtpSink) converse(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 sink ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.cmds = append(s.cmds, line)
+		s.mu.Unlock()
+
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO":
+			ext := []string{"sink"}
+			if s.startTLS != "" {
+				ext = append(ext, "STARTTLS")
+			}
+			if s.auth {
+				ext = append(ext, "AUTH PLAIN")
+			}
+			for i, e := range ext {
+				sep := "-"
+				if i == len(ext)-1 {
+					sep = " "
+				}
+				_ = tp.PrintfLine("250%s%s", sep, e)
+			}
+		case "STARTTLS":
+			if s.startTLS == "refuse" {
+				_ = tp.PrintfLine("454 4.7.0 TLS not available")
+				continue
+			}
+			_ = tp.PrintfLine("220 2.0.0 ready")
+			srv := httptest.NewUnstartedServer(nil)
+			srv.StartTLS()
+			cert := srv.TLS.Certificates[0]
+			srv.Close()
+			_ = tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}}).Handshake()
+			return
+		case "AUTH":
+			reply := s.authReply
+			if !s.auth {
+				reply = "503 5.5.1 AUTH not available"
+			} else if reply == "" {
+				reply = "235 2.7.0 accepted"
+			}
+			_ = tp.PrintfLine("%s", reply)
+		case "MAIL", "RCPT":
+			_ = tp.PrintfLine("250 ok")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+			body, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.data = string(body)
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 queued")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *smtpSink) mailer() *SMTPMailer {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return &SMTPMailer{Host: host, Port: port, From: "Prestasi <noreply@example.ac.id>", Timeout: 5 * time.Second}
+}
+
+// result waits for the conversation to end and returns the commands and the DATA payload.
+func (s *smtpSink) result(t *testing.T) ([]string, string) {
+	t.Helper()
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("smtp conversation did not finish")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cmds, s.data
+}
+
+func hasCommand(cmds []string, prefix string) bool {
+	for _, c := range cmds {
+		if strings.HasPrefix(strings.ToUpper(c), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSMTPMailerSendsHeadersAndEncodedBody(t *testing.T) {
+	sink := newSMTPSink(t)
+	sink.serve()
+
+	msg := MailMessage{
+		To:      "Budi Santoso <budi@example.ac.id>",
+		Subject: "Verifikasi email — Prestasi",
+		Text:    "Halo Budi,\nklik tautan ini: https://example.ac.id/verify?token=abc=def\n" + strings.Repeat("x", 100),
+	}
+	if err := sink.mailer().Send(context.Background(), msg); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	cmds, data := sink.result(t)
+
+	if !hasCommand(cmds, "MAIL FROM:<NOREPLY@EXAMPLE.AC.ID>") || !hasCommand(cmds, "RCPT TO:<BUDI@EXAMPLE.AC.ID>") {
+		t.Fatalf("unexpected envelope: %q", cmds)
+	}
+	if hasCommand(cmds, "AUTH") || hasCommand(cmds, "STARTTLS") {
+		t.Fatalf("AUTH/STARTTLS sent although not configured or offered: %q", cmds)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	h := parsed.Header
+	if got := h.Get("From"); got != `"Prestasi" <noreply@example.ac.id>` {
+		t.Errorf("From = %q", got)
+	}
+	if got := h.Get("To"); got != `"Budi Santoso" <budi@example.ac.id>` {
+		t.Errorf("To = %q", got)
+	}
+	if !strings.HasPrefix(h.Get("Subject"), "=?utf-8?q?") {
+		t.Errorf("non-ASCII subject is not Q-encoded: %q", h.Get("Subject"))
+	}
+	if got, err := new(mime.WordDecoder).DecodeHeader(h.Get("Subject")); err != nil || got != msg.Subject {
+		t.Errorf("Subject decodes to %q (%v)", got, err)
+	}
+	if id := h.Get("Message-ID"); !strings.HasSuffix(id, "@example.ac.id>") {
+		t.Errorf("Message-ID = %q", id)
+	}
+	if _, err := h.Date(); err != nil {
+		t.Errorf("Date: %v", err)
+	}
+	if h.Get("MIME-Version") != "1.0" || h.Get("Content-Type") != "text/plain; charset=utf-8" ||
+		h.Get("Content-Transfer-Encoding") != "quoted-printable" {
+		t.Errorf("MIME headers = %v", h)
+	}
+
+	raw, _ := io.ReadAll(parsed.Body)
+	for _, line := range strings.Split(string(raw), "\n") {
+		if len(line) > 76 {
+			t.Errorf("encoded body line longer than 76 characters: %q", line)
+		}
+	}
+	// the line break before the terminating "." belongs to the DATA framing, not to the body
+	encoded := strings.TrimSuffix(string(raw), "\n")
+	body, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(encoded)))
+	if err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if string(body) != msg.Text {
+		t.Errorf("body = %q, want %q", body, msg.Text)
+	}
+}
+
+func TestSMTPMailerAuthPlain(t *testing.T) {
+	sink := newSMTPSink(t)
+	sink.auth = true
+	sink.serve()
+
+	m := sink.mailer()
+	m.Username, m.Password = "mailer", "s3cret"
+	if err := m.Send(context.Background(), MailMessage{To: "budi@example.ac.id", Subject: "x", Text: "y"}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	cmds, _ := sink.result(t)
+
+	var auth string
+	for _, c := range cmds {
+		if strings.HasPrefix(c, "AUTH PLAIN ") {
+			auth = strings.TrimPrefix(c, "AUTH PLAIN ")
+		}
+	}
+	creds, err := base64.StdEncoding.DecodeString(auth)
+	if err != nil || string(creds) != "\x00mailer\x00s3cret" {
+		t.Fatalf("AUTH PLAIN credentials = %q (%v), commands %q", creds, err, cmds)
+	}
+}
+
+func TestSMTPMailerRefusals(t *testing.T) {
+	cases := []struct {
+		name      string
+		startTLS  string
+		auth      bool
+		authReply string
+		username  string
+		sent      string // command that must have reached the server before the failure
+	}{
+		{name: "starttls refused by server", startTLS: "refuse", sent: "STARTTLS"},
+		{name: "untrusted certificate after starttls", startTLS: "handshake", sent: "STARTTLS"},
+		{name: "auth not offered", username: "mailer", sent: "AUTH PLAIN"},
+		{name: "credentials rejected", auth: true, authReply: "535 5.7.8 bad credentials", username: "mailer", sent: "AUTH PLAIN"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := newSMTPSink(t)
+			sink.startTLS, sink.auth, sink.authReply = tc.startTLS, tc.auth, tc.authReply
+			sink.serve()
+
+			m := sink.mailer()
+			m.Username, m.Password = tc.username, "s3cret"
+			err := m.Send(context.Background(), MailMessage{To: "budi@example.ac.id", Subject: "x", Text: "y"})
+			if err == nil {
+				t.Fatal("send succeeded")
+			}
+			cmds, data := sink.result(t)
+			if !hasCommand(cmds, tc.sent) {
+				t.Errorf("%s was not sent: %q", tc.sent, cmds)
+			}
+			if hasCommand(cmds, "MAIL") || data != "" {
+				t.Errorf("message was sent after the failure: %q", cmds)
+			}
+		})
+	}
+}
+
+func TestSMTPMailerRejectsBadInput(t *testing.T) {
+	m := &SMTPMailer{Host: "127.0.0.1", Port: "1", From: "noreply@example.ac.id"}
+	for _, msg := range []MailMessage{
+		{To: "budi@example.ac.id", Subject: "hi\r\nBcc: evil@example.com"},
+		{To: "not an address", Subject: "hi"},
+	} {
+		if err := m.Send(context.Background(), msg); err == nil || strings.Contains(err.Error(), "connect") {
+			t.Errorf("Send(%+v) = %v, want a validation error before dialing", msg, err)
+		}
+	}
+}
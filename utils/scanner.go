@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ScanResult is the verdict of a Scanner; Signature names the malware when Infected.
+type ScanResult struct {
+	Infected  bool
+	Signature string
+}
+
+// Scanner checks uploaded files for malware. An error means "no verdict" (scanner down, file too
+// large for it), never "clean".
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (ScanResult, error)
+}
+
+// NoopScanner reports every file clean; for development or when scanning happens elsewhere.
+type NoopScanner struct{}
+
+func (NoopScanner) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	return ScanResult{}, nil
+}
+
+// ClamdScanner streams files to a ClamAV daemon with the INSTREAM command.
+type ClamdScanner struct {
+	Network   string // "tcp" or "unix"
+	Address   string // host:port or socket path
+	Timeout   time.Duration
+	ChunkSize int
+}
+
+// NewClamdScanner parses address as tcp://host:port, unix:///path/to/clamd.sock or plain host:port.
+func NewClamdScanner(address string, timeout time.Duration) (*ClamdScanner, error) {
+	network, addr := "tcp", address
+	if rest, ok := strings.CutPrefix(address, "tcp://"); ok {
+		addr = rest
+	} else if rest, ok := strings.CutPrefix(address, "unix://"); ok {
+		network, addr = "unix", rest
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("clamd: invalid address %q", address)
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	return &ClamdScanner{Network: network, Address: addr, Timeout: timeout, ChunkSize: 64 << 10}, nil
+}
+
+// Scan sends r as length-prefixed chunks followed by a zero-length chunk and parses the reply
+// ("stream: OK", "stream: <signature> FOUND" or "<message> ERROR").
+func (s *ClamdScanner) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer conn.Close()
+
+	if err := s.stream(conn, r); err != nil {
+		// clamd closes the connection early when the stream exceeds StreamMaxLength;
+		// its reply explains why, so prefer it over the write error
+		if reply, rerr := readClamdReply(conn); rerr == nil && reply != "" {
+			return parseClamdReply(reply)
+		}
+		return ScanResult{}, fmt.Errorf("clamd: %w", err)
+	}
+	reply, err := readClamdReply(conn)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("clamd: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// Ping checks that the daemon answers, for startup diagnostics.
+func (s *ClamdScanner) Ping(ctx context.Context) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return fmt.Errorf("clamd: %w", err)
+	}
+	reply, err := readClamdReply(conn)
+	if err != nil {
+		return fmt.Errorf("clamd: %w", err)
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("clamd: unexpected reply %q", reply)
+	}
+	return nil
+}
+
+func (s *ClamdScanner) dial(ctx context.Context) (net.Conn, error) {
+	d := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := d.DialContext(ctx, s.Network, s.Address)
+	if err != nil {
+		return nil, fmt.Errorf("clamd: %w", err)
+	}
+	deadline := time.Now().Add(s.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	return conn, nil
+}
+
+func (s *ClamdScanner) stream(conn net.Conn, r io.Reader) error {
+	w := bufio.NewWriter(conn)
+	if _, err := w.WriteString("zINSTREAM\x00"); err != nil {
+		return err
+	}
+	chunk := make([]byte, s.ChunkSize)
+	var size [4]byte
+	for {
+		n, err := r.Read(chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, werr := w.Write(size[:]); werr != nil {
+				return werr
+			}
+			if _, werr := w.Write(chunk[:n]); werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// readClamdReply reads one NUL-terminated reply (z-prefixed commands).
+func readClamdReply(conn net.Conn) (string, error) {
+	b, err := bufio.NewReader(io.LimitReader(conn, 4096)).ReadBytes(0)
+	if err != nil && !(errors.Is(err, io.EOF) && len(b) > 0) {
+		return "", err
+	}
+	return string(bytes.TrimSpace(bytes.TrimRight(b, "\x00"))), nil
+}
+
+func parseClamdReply(reply string) (ScanResult, error) {
+	msg := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case msg == "OK":
+		return ScanResult{}, nil
+	case strings.HasSuffix(msg, " FOUND"):
+		return ScanResult{Infected: true, Signature: strings.TrimSuffix(msg, " FOUND")}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("clamd: %s", strings.TrimSuffix(msg, " ERROR"))
+	}
+}
@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd accepts one connection, reads a zINSTREAM command and answers with reply(data).
+type fakeClamd struct {
+	ln     net.Listener
+	chunks []int  // sizes of the received chunks, the terminating zero included
+	data   []byte // reassembled stream
+	done   chan struct{}
+}
+
+func newFakeClamd(t *testing.T, reply func(cmd string, data []byte) string) *fakeClamd {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeClamd{ln: ln, done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		r := bufio.NewReader(conn)
+		cmd, err := r.ReadString(0)
+		if err != nil {
+			return
+		}
+		cmd = strings.TrimSuffix(cmd, "\x00")
+		if cmd == "zINSTREAM" {
+			for {
+				var size [4]byte
+				if _, err := io.ReadFull(r, size[:]); err != nil {
+					return
+				}
+				n := binary.BigEndian.Uint32(size[:])
+				f.chunks = append(f.chunks, int(n))
+				if n == 0 {
+					break
+				}
+				chunk := make([]byte, n)
+				if _, err := io.ReadFull(r, chunk); err != nil {
+					return
+				}
+				f.data = append(f.data, chunk...)
+			}
+		}
+		_, _ = conn.Write([]byte(reply(cmd, f.data) + "\x00"))
+	}()
+	return f
+}
+
+func (f *fakeClamd) scanner(t *testing.T) *ClamdScanner {
+	t.Helper()
+	s, err := NewClamdScanner("tcp://"+f.ln.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (f *fakeClamd) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-f.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fake clamd did not finish")
+	}
+}
+
+func TestClamdScannerStreamsChunks(t *testing.T) {
+	f := newFakeClamd(t, func(string, []byte) string { return "stream: OK" })
+	s := f.scanner(t)
+	s.ChunkSize = 4
+
+	content := []byte("0123456789")
+	res, err := s.Scan(context.Background(), bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	f.wait(t)
+	if res.Infected {
+		t.Errorf("clean stream reported infected: %+v", res)
+	}
+	if want := []int{4, 4, 2, 0}; !slices.Equal(f.chunks, want) {
+		t.Errorf("chunk sizes = %v, want %v", f.chunks, want)
+	}
+	if !bytes.Equal(f.data, content) {
+		t.Errorf("clamd received %q, want %q", f.data, content)
+	}
+}
+
+func TestClamdScannerEmptyStream(t *testing.T) {
+	f := newFakeClamd(t, func(string, []byte) string { return "stream: OK" })
+	if _, err := f.scanner(t).Scan(context.Background(), bytes.NewReader(nil)); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	f.wait(t)
+	if len(f.chunks) != 1 || f.chunks[0] != 0 {
+		t.Errorf("chunk sizes = %v, want only the terminating zero", f.chunks)
+	}
+}
+
+func TestClamdScannerReplies(t *testing.T) {
+	cases := []struct {
+		name      string
+		reply     string
+		infected  bool
+		signature string
+		err       string
+	}{
+		{name: "clean", reply: "stream: OK"},
+		{name: "found", reply: "stream: Win.Test.EICAR_HDB-1 FOUND", infected: true, signature: "Win.Test.EICAR_HDB-1"},
+		{name: "size limit", reply: "INSTREAM size limit exceeded. ERROR", err: "clamd: INSTREAM size limit exceeded."},
+		{name: "unknown command", reply: "UNKNOWN COMMAND", err: "clamd: UNKNOWN COMMAND"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeClamd(t, func(string, []byte) string { return tc.reply })
+			res, err := f.scanner(t).Scan(context.Background(), strings.NewReader("X5O!P%@AP"))
+			f.wait(t)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("scan error = %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+			if res.Infected != tc.infected || res.Signature != tc.signature {
+				t.Errorf("result = %+v, want infected=%v signature=%q", res, tc.infected, tc.signature)
+			}
+		})
+	}
+}
+
+func TestClamdScannerPing(t *testing.T) {
+	f := newFakeClamd(t, func(cmd string, _ []byte) string {
+		if cmd == "zPING" {
+			return "PONG"
+		}
+		return "UNKNOWN COMMAND"
+	})
+	if err := f.scanner(t).Ping(context.Background()); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	f.wait(t)
+}
+
+func TestClamdScannerUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	s, err := NewClamdScanner(addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Scan(context.Background(), strings.NewReader("x")); err == nil {
+		t.Fatal("scan without a daemon returned a verdict")
+	}
+}